@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	docs "github.com/fatihesergg/go_social/docs"
+	"github.com/fatihesergg/go_social/internal/blob"
 	"github.com/fatihesergg/go_social/internal/controller"
 	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/mailer"
 	"github.com/fatihesergg/go_social/internal/middleware"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/oidc"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/fatihesergg/go_social/internal/worker"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -54,8 +64,97 @@ func main() {
 
 	DSN := fmt.Sprintf("postgres://%s:%s@db:5432/%s?sslmode=disable", pgUser, pgPassword, pgDB)
 
-	if os.Getenv("JWT_SECRET") == "" {
-		panic("JWT_SECRET is not set")
+	jwtKeysDir := os.Getenv("JWT_KEYS_DIR")
+	jwtSigningKeyID := os.Getenv("JWT_SIGNING_KEY_ID")
+	if jwtKeysDir == "" || jwtSigningKeyID == "" {
+		panic("JWT_KEYS_DIR and JWT_SIGNING_KEY_ID are not set")
+	}
+	keyRing, err := util.LoadKeyRing(jwtKeysDir, jwtSigningKeyID)
+	if err != nil {
+		panic("Error loading JWT keys: " + err.Error())
+	}
+
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:3000"
+	}
+
+	blobDir := os.Getenv("BLOB_DIR")
+	if blobDir == "" {
+		blobDir = "./uploads"
+	}
+	mediaURL := os.Getenv("MEDIA_URL")
+	if mediaURL == "" {
+		mediaURL = appURL + "/media"
+	}
+	blobStore := blob.NewLocalStore(blobDir, mediaURL)
+
+	// Data exports are kept apart from the public media so they can only be
+	// downloaded with a signed link.
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = "./exports"
+	}
+	exportStore := blob.NewLocalStore(exportDir, "")
+
+	// Deleted accounts can be restored for ACCOUNT_GRACE_PERIOD_DAYS, then
+	// they are purged according to ACCOUNT_PURGE_POLICY.
+	accountGracePeriodDays := 30
+	if days := os.Getenv("ACCOUNT_GRACE_PERIOD_DAYS"); days != "" {
+		accountGracePeriodDays, err = strconv.Atoi(days)
+		if err != nil || accountGracePeriodDays < 0 {
+			panic("ACCOUNT_GRACE_PERIOD_DAYS must be a non-negative number")
+		}
+	}
+	accountGracePeriod := time.Duration(accountGracePeriodDays) * 24 * time.Hour
+	accountPurgePolicy := os.Getenv("ACCOUNT_PURGE_POLICY")
+	if accountPurgePolicy == "" {
+		accountPurgePolicy = worker.PurgePolicyAnonymize
+	}
+	if accountPurgePolicy != worker.PurgePolicyAnonymize && accountPurgePolicy != worker.PurgePolicyDelete {
+		panic("ACCOUNT_PURGE_POLICY must be anonymize or delete")
+	}
+
+	var mail mailer.Mailer
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		smtpHost := os.Getenv("SMTP_HOST")
+		smtpPort := os.Getenv("SMTP_PORT")
+		mailFrom := os.Getenv("MAIL_FROM")
+		if smtpHost == "" || smtpPort == "" || mailFrom == "" {
+			panic("SMTP_HOST, SMTP_PORT and MAIL_FROM must be set when MAILER is smtp")
+		}
+		mail = mailer.NewSMTPMailer(smtpHost, smtpPort, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom)
+	default:
+		mail = mailer.NewLogMailer(os.Stdout)
+	}
+
+	// OIDC_PROVIDERS is a comma separated list of provider names. Each one is
+	// configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+	// OIDC_<NAME>_CLIENT_SECRET and optionally OIDC_<NAME>_REDIRECT_URL.
+	oidcProviders := make(map[string]*oidc.Provider)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		issuer := os.Getenv(prefix + "ISSUER")
+		clientID := os.Getenv(prefix + "CLIENT_ID")
+		if issuer == "" || clientID == "" {
+			panic(prefix + "ISSUER and " + prefix + "CLIENT_ID must be set")
+		}
+		redirectURL := os.Getenv(prefix + "REDIRECT_URL")
+		if redirectURL == "" {
+			redirectURL = appURL + "/api/v1/auth/" + name + "/callback"
+		}
+		oidcProviders[name] = oidc.NewProvider(oidc.Config{
+			Name:         name,
+			Issuer:       issuer,
+			ClientID:     clientID,
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  redirectURL,
+		})
 	}
 
 	db, err := sql.Open("postgres", DSN)
@@ -70,8 +169,23 @@ func main() {
 	feedStore := database.NewFeedStore(db)
 	likeStore := database.NewLikeStore(db)
 	replyStore := database.NewReplyStore(db)
+	tokenStore := database.NewTokenStore(db)
+	sessionStore := database.NewSessionStore(db)
+	userTokenStore := database.NewUserTokenStore(db)
+	recoveryCodeStore := database.NewRecoveryCodeStore(db)
+	loginAttemptStore := database.NewLoginAttemptStore(db)
+	identityStore := database.NewIdentityStore(db)
+	personalAccessTokenStore := database.NewPersonalAccessTokenStore(db)
+	moderationStore := database.NewModerationStore(db)
+	usernameChangeStore := database.NewUsernameChangeStore(db)
+	blockStore := database.NewBlockStore(db)
+	muteStore := database.NewMuteStore(db)
+	dataExportStore := database.NewDataExportStore(db)
+	mediaStore := database.NewMediaStore(db)
+	repostStore := database.NewRepostStore(db)
+	tagStore := database.NewTagStore(db)
 
-	storage := database.NewPostgresStorage(userStore, postStore, commentStore, followStore, feedStore, likeStore, replyStore)
+	storage := database.NewPostgresStorage(userStore, postStore, commentStore, followStore, feedStore, likeStore, replyStore, tokenStore, sessionStore, userTokenStore, recoveryCodeStore, loginAttemptStore, identityStore, personalAccessTokenStore, moderationStore, usernameChangeStore, blockStore, muteStore, dataExportStore, mediaStore, repostStore, tagStore)
 
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 	rateLimiter := middleware.NewRateLimiter(1, 10)
@@ -82,55 +196,165 @@ func main() {
 	}
 	base := app.Router.Group("/api/v1")
 
-	userController := controller.NewUserController(storage)
-	postController := controller.NewPostController(storage)
-	commentController := controller.NewCommentController(storage)
+	accountPurger := worker.NewAccountPurger(storage, blobStore, accountGracePeriod, accountPurgePolicy)
+	go accountPurger.Run(context.Background())
+	dataExporter := worker.NewDataExporter(storage, exportStore)
+	go dataExporter.Run(context.Background())
+	mediaCleaner := worker.NewMediaCleaner(storage, blobStore)
+	go mediaCleaner.Run(context.Background())
+	postScheduler := worker.NewPostScheduler(storage)
+	go postScheduler.Run(context.Background())
+	passwordResetMailer := worker.NewPasswordResetMailer(storage, mail)
+	go passwordResetMailer.Run(context.Background())
+
+	userController := controller.NewUserController(storage, keyRing, mail, passwordResetMailer, appURL, accountGracePeriod)
+	postController := controller.NewPostController(storage, blobStore)
+	commentController := controller.NewCommentController(storage, blobStore)
 	feedController := controller.NewFeedController(storage)
 	likeController := controller.NewLikeController(storage)
+	repostController := controller.NewRepostController(storage)
+	tagController := controller.NewTagController(storage)
 	replyController := controller.NewReplyController(storage)
+	sessionController := controller.NewSessionController(storage)
+	keyController := controller.NewKeyController(keyRing)
+	oidcController := controller.NewOIDCController(storage, userController, oidcProviders)
+	personalAccessTokenController := controller.NewPersonalAccessTokenController(storage)
+	moderationController := controller.NewModerationController(storage)
+	blockController := controller.NewBlockController(storage)
+	muteController := controller.NewMuteController(storage)
+	profileController := controller.NewProfileController(storage, blobStore)
+	mediaController := controller.NewMediaController(storage, blobStore)
+	dataExportController := controller.NewDataExportController(storage, keyRing, exportStore, appURL)
+
+	engine.GET("/.well-known/jwks.json", keyController.GetJWKS)
+	engine.GET("/media/*key", mediaController.GetMedia)
 
 	base.POST("/signup", userController.Signup)
 	base.POST("/login", userController.Login)
+	base.POST("/login/2fa", userController.LoginTwoFactor)
+	base.POST("/refresh", userController.RefreshToken)
+	base.GET("/verify_email", userController.VerifyEmail)
+	base.POST("/password_reset/request", userController.ForgotPassword)
+	base.POST("/password_reset/confirm", userController.ConfirmPasswordReset)
+	base.GET("/auth/:provider/login", oidcController.Login)
+	base.GET("/auth/:provider/callback", oidcController.Callback)
+	base.GET("/exports/download", dataExportController.DownloadDataExport)
+	base.POST("/logout", middleware.AuthMiddleware(storage, keyRing), middleware.SessionOnlyMiddleware(), userController.Logout)
 
 	userRouter := base.Group("/users")
-	userRouter.Use(middleware.AuthMiddleware())
+	userRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("users"))
 	userRouter.GET("/:id", userController.GetUserByID)
 	userRouter.GET("/:id/posts", userController.GetUsersPosts)
 	userRouter.GET("/getMe", userController.GetMe)
-	userRouter.POST("/:id/follow", userController.FollowUser)
-	userRouter.DELETE("/:id/unfollow", userController.UnfollowUser)
-	userRouter.GET("/:id/followers", userController.GetFollowerByUserID)
-	userRouter.GET("/:id/following", userController.GetFollowingByUserID)
-	userRouter.POST("/reset_password", userController.ResetPassword)
 	userRouter.GET("/search/:username", userController.SearchUserByUsername)
+	userRouter.GET("/username/:username", userController.GetUserByUsername)
+	userRouter.PATCH("/me", profileController.UpdateProfile)
+	userRouter.POST("/me/avatar", profileController.UploadAvatar)
+	userRouter.DELETE("/me/avatar", profileController.DeleteAvatar)
+
+	followRouter := base.Group("/users")
+	followRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("follows"))
+	followRouter.POST("/:id/follow", userController.FollowUser)
+	followRouter.DELETE("/:id/unfollow", userController.UnfollowUser)
+	followRouter.GET("/:id/followers", userController.GetFollowerByUserID)
+	followRouter.GET("/:id/following", userController.GetFollowingByUserID)
+	followRouter.GET("/suggestions", userController.GetSuggestions)
+	followRouter.GET("/:id/mutual_followers", userController.GetMutualFollowers)
+	followRouter.GET("/:id/followers_you_know", userController.GetFollowersYouKnow)
+	followRouter.GET("/:id/relationship", userController.GetRelationship)
+	followRouter.GET("/:id/path", userController.GetFollowPath)
+	followRouter.GET("/follow_requests", userController.GetFollowRequests)
+	followRouter.POST("/follow_requests/:id/approve", userController.ApproveFollowRequest)
+	followRouter.POST("/follow_requests/:id/reject", userController.RejectFollowRequest)
+	followRouter.POST("/:id/block", blockController.BlockUser)
+	followRouter.DELETE("/:id/block", blockController.UnblockUser)
+	followRouter.GET("/blocks", blockController.GetBlocks)
+	followRouter.POST("/:id/mute", muteController.MuteUser)
+	followRouter.DELETE("/:id/mute", muteController.UnmuteUser)
+	followRouter.GET("/mutes", muteController.GetMutes)
+	followRouter.POST("/mutes/keywords", muteController.MuteKeyword)
+	followRouter.DELETE("/mutes/keywords/:id", muteController.UnmuteKeyword)
+
+	accountRouter := base.Group("/users")
+	accountRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.SessionOnlyMiddleware())
+	accountRouter.POST("/reset_password", userController.ResetPassword)
+	accountRouter.POST("/verify_email/resend", userController.ResendVerificationEmail)
+	accountRouter.POST("/2fa/enroll", userController.EnrollTwoFactor)
+	accountRouter.POST("/2fa/confirm", userController.ConfirmTwoFactor)
+	accountRouter.POST("/2fa/disable", userController.DisableTwoFactor)
+	accountRouter.GET("/identities", oidcController.GetIdentities)
+	accountRouter.PUT("/me/username", userController.ChangeUsername)
+	accountRouter.GET("/me/username_history", userController.GetUsernameChanges)
+	accountRouter.DELETE("/me", userController.DeleteAccount)
+	accountRouter.POST("/me/exports", dataExportController.RequestDataExport)
+	accountRouter.GET("/me/exports/:id", dataExportController.GetDataExport)
+
+	sessionRouter := base.Group("/sessions")
+	sessionRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.SessionOnlyMiddleware())
+	sessionRouter.GET("/", sessionController.GetSessions)
+	sessionRouter.DELETE("/", sessionController.RevokeOtherSessions)
+	sessionRouter.DELETE("/:id", sessionController.RevokeSession)
+
+	tokenRouter := base.Group("/tokens")
+	tokenRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.SessionOnlyMiddleware())
+	tokenRouter.GET("/", personalAccessTokenController.GetPersonalAccessTokens)
+	tokenRouter.POST("/", personalAccessTokenController.CreatePersonalAccessToken)
+	tokenRouter.DELETE("/:id", personalAccessTokenController.RevokePersonalAccessToken)
+
+	moderationRouter := base.Group("/moderation")
+	moderationRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.SessionOnlyMiddleware(), middleware.RequireRole(storage, model.RoleModerator))
+	moderationRouter.GET("/actions", moderationController.GetModerationActions)
+
+	adminRouter := base.Group("/admin")
+	adminRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.SessionOnlyMiddleware(), middleware.RequireRole(storage, model.RoleAdmin))
+	adminRouter.PUT("/users/:id/role", moderationController.UpdateUserRole)
 
 	postRouter := base.Group("/posts")
-	postRouter.Use(middleware.AuthMiddleware())
+	postRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("posts"))
 
+	postRouter.GET("/drafts", postController.GetDrafts)
 	postRouter.GET("/:id", postController.GetPostByID)
 	postRouter.GET("/", postController.GetPosts)
-	postRouter.POST("/", postController.CreatePost)
+	postRouter.POST("/", middleware.VerifiedEmailMiddleware(storage), postController.CreatePost)
 	postRouter.PUT("/:id", postController.UpdatePost)
+	postRouter.DELETE("/:id", postController.DeletePost)
 	postRouter.POST("/:id/like", likeController.LikePost)
 	postRouter.DELETE("/:id/unlike", likeController.UnlikePost)
+	postRouter.POST("/:id/repost", repostController.Repost)
+	postRouter.DELETE("/:id/repost", repostController.Unrepost)
+	postRouter.GET("/:id/revisions", postController.GetPostRevisions)
+	postRouter.POST("/:id/schedule", postController.SchedulePost)
+	postRouter.DELETE("/:id/schedule", postController.UnschedulePost)
+	postRouter.POST("/:id/publish", middleware.VerifiedEmailMiddleware(storage), postController.PublishPost)
+
+	tagRouter := base.Group("/tags")
+	tagRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("posts"))
+	tagRouter.GET("/", tagController.GetTrendingTags)
+	tagRouter.GET("/:tag", tagController.GetPostsByTag)
+
+	mediaRouter := base.Group("/media")
+	mediaRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("media"))
+	mediaRouter.POST("/", middleware.VerifiedEmailMiddleware(storage), mediaController.UploadMedia)
 
 	feedRouter := base.Group("/feed")
-	feedRouter.Use(middleware.AuthMiddleware())
+	feedRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("feed"))
 	feedRouter.GET("/", feedController.GetFeed)
 
 	commentRouter := base.Group("/comments")
-	commentRouter.Use(middleware.AuthMiddleware())
-	commentRouter.POST("/", commentController.CreateComment)
-	commentRouter.GET("/:post_id", commentController.GetCommentsByPostID)
+	commentRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("comments"))
+	commentRouter.POST("/", middleware.VerifiedEmailMiddleware(storage), commentController.CreateComment)
+	commentRouter.GET("/:id", commentController.GetCommentsByPostID)
+	commentRouter.GET("/:id/revisions", commentController.GetCommentRevisions)
 	commentRouter.PUT("/:id", commentController.UpdateComment)
 	commentRouter.DELETE("/:id", commentController.DeleteComment)
-	commentRouter.POST("/:id/reply", replyController.ReplyComment)
+	commentRouter.POST("/:id/reply", middleware.VerifiedEmailMiddleware(storage), replyController.ReplyComment)
 	commentRouter.POST("/:id/like", likeController.LikeComment)
 	commentRouter.DELETE("/:id/unlike", likeController.UnlikeComment)
 
 	replyRouter := base.Group("/replies")
-	replyRouter.Use(middleware.AuthMiddleware())
+	replyRouter.Use(middleware.AuthMiddleware(storage, keyRing), middleware.ScopeMiddleware("comments"))
 	replyRouter.GET("/:id", replyController.GetCommentReplies)
+	replyRouter.GET("/:id/revisions", replyController.GetReplyRevisions)
 	replyRouter.PUT("/:id", replyController.UpdateReply)
 	replyRouter.DELETE("/:id", replyController.DeleteReply)
 
@@ -0,0 +1,27 @@
+// Package blob stores user uploaded files like avatars. Store is the
+// interface the rest of the app uses, LocalStore keeps files on the local
+// filesystem and other backends like S3 can be added behind it.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob: not found")
+var ErrInvalidKey = errors.New("blob: invalid key")
+
+type Store interface {
+	// Put stores the content of r under key, replacing an existing blob.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the content of a blob. It returns ErrNotFound if there is
+	// no blob with the key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of a blob.
+	URL(key string) string
+	// Key returns the key of a URL returned by URL, or false if the URL
+	// doesn't belong to the store.
+	Key(url string) (string, bool)
+}
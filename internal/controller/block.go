@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BlockController struct {
+	Storage *database.Storage
+}
+
+func NewBlockController(storage *database.Storage) *BlockController {
+	return &BlockController{
+		Storage: storage,
+	}
+}
+
+// BlockUser godoc
+//
+//	@Summary		Block a user
+//	@Description	Block a user by their ID. The follows between the two users are removed and they can't follow, like, comment on or reply to each other or see each other's content until the block is removed.
+//	@Tags			Blocks
+//	@Produce		json
+//	@Param			id	path		string	true	"User ID to block"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/block [post]
+func (bc BlockController) BlockUser(c *gin.Context) {
+	blockedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+	if blockedID == userID {
+		c.JSON(400, util.ErrorResponse{Error: util.BlockYourselfError})
+		return
+	}
+
+	user, err := bc.Storage.UserStore.GetUserByID(blockedID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	if err := bc.Storage.BlockStore.BlockUser(userID, blockedID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "User blocked successfully"})
+}
+
+// UnblockUser godoc
+//
+//	@Summary		Unblock a user
+//	@Description	Remove the block of a user. Follows removed by the block are not restored.
+//	@Tags			Blocks
+//	@Produce		json
+//	@Param			id	path		string	true	"User ID to unblock"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/block [delete]
+func (bc BlockController) UnblockUser(c *gin.Context) {
+	blockedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	unblocked, err := bc.Storage.BlockStore.UnblockUser(userID, blockedID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !unblocked {
+		c.JSON(404, util.ErrorResponse{Error: util.BlockNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "User unblocked successfully"})
+}
+
+// GetBlocks godoc
+//
+//	@Summary		Get blocked users
+//	@Description	List the users blocked by the authenticated user, newest first
+//	@Tags			Blocks
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"		default(20)
+//	@Param			offset	query		int	false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.Block}
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/blocks [get]
+func (bc BlockController) GetBlocks(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	pagination := database.NewPagination(c)
+
+	blocks, err := bc.Storage.BlockStore.GetBlocksByUserID(userID, pagination)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Blocked users fetched successfully", Result: blocks})
+}
+
+// authorizeInteraction lets the authenticated user interact with the content
+// of otherID unless one of them blocked the other. It responds with an error
+// if the interaction isn't allowed.
+func authorizeInteraction(c *gin.Context, storage *database.Storage, otherID uuid.UUID) bool {
+	userID := c.MustGet("userID").(uuid.UUID)
+	if userID == otherID {
+		return true
+	}
+
+	blocked, err := storage.BlockStore.IsBlocked(userID, otherID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return false
+	}
+	if blocked {
+		c.JSON(403, util.ErrorResponse{Error: util.BlockedUserError})
+		return false
+	}
+	return true
+}
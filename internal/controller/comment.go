@@ -1,6 +1,9 @@
 package controller
 
 import (
+	"errors"
+
+	"github.com/fatihesergg/go_social/internal/blob"
 	"github.com/fatihesergg/go_social/internal/database"
 	"github.com/fatihesergg/go_social/internal/dto"
 	"github.com/fatihesergg/go_social/internal/model"
@@ -11,18 +14,20 @@ import (
 
 type CommentController struct {
 	Storage *database.Storage
+	Blob    blob.Store
 }
 
-func NewCommentController(storage *database.Storage) *CommentController {
+func NewCommentController(storage *database.Storage, blobStore blob.Store) *CommentController {
 	return &CommentController{
 		Storage: storage,
+		Blob:    blobStore,
 	}
 }
 
 // CreateComment godoc
 //
 //	@Summary		Create a new comment
-//	@Description	Create a new comment on a post
+//	@Description	Create a new comment on a post with an optional image uploaded with POST /media
 //	@Tags			Comments
 //	@Accept			json
 //	@Produce		json
@@ -30,7 +35,9 @@ func NewCommentController(storage *database.Storage) *CommentController {
 //	@Success		201		{object}	util.SuccessMessageResponse
 //	@Failure		400		{object}	util.ErrorResponse
 //	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
 //	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		409		{object}	util.ErrorResponse
 //	@Failure		500		{object}	util.ErrorResponse
 //	@Security		Bearer
 //	@Router			/comments [post]
@@ -40,6 +47,22 @@ func (cc *CommentController) CreateComment(c *gin.Context) {
 		util.HandleBindError(c, err)
 		return
 	}
+	post, err := cc.Storage.PostStore.GetPostByID(params.PostID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if post == nil || !post.IsPublished() {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return
+	}
+	if !authorizeInteraction(c, cc.Storage, post.UserID) {
+		return
+	}
+	if !canSeeContent(c, cc.Storage, post.ID, post.UserID, util.PostNotFoundError) {
+		return
+	}
+
 	userID := c.MustGet("userID").(uuid.UUID)
 	comment := &model.Comment{
 		ID:      uuid.New(),
@@ -47,10 +70,21 @@ func (cc *CommentController) CreateComment(c *gin.Context) {
 		UserID:  userID,
 		Content: params.Content,
 	}
+	if params.MediaID != nil {
+		media, ok := attachableMedia(c, cc.Storage, *params.MediaID)
+		if !ok {
+			return
+		}
+		image, thumbnail := cc.Blob.URL(media.BlobKey), cc.Blob.URL(media.ThumbnailKey)
+		comment.MediaID, comment.Image, comment.Thumbnail = &media.ID, &image, &thumbnail
+	}
 
-	err := cc.Storage.CommentStore.CreateComment(comment)
+	err = cc.Storage.CommentStore.CreateComment(comment)
 	if err != nil {
-
+		if errors.Is(err, database.ErrMediaAttached) {
+			c.JSON(409, util.ErrorResponse{Error: util.MediaAlreadyAttachedError})
+			return
+		}
 		c.JSON(500, util.ErrorResponse{Error: "Error creating comment"})
 		return
 	}
@@ -74,7 +108,7 @@ func (cc *CommentController) CreateComment(c *gin.Context) {
 //	@Security		Bearer
 //	@Router			/comments/post/{post_id} [get]
 func (cc *CommentController) GetCommentsByPostID(c *gin.Context) {
-	id := c.Param("post_id")
+	id := c.Param("id")
 	if id == "" {
 		c.JSON(400, util.ErrorResponse{Error: util.IDRequiredError})
 		return
@@ -84,6 +118,19 @@ func (cc *CommentController) GetCommentsByPostID(c *gin.Context) {
 		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
 		return
 	}
+	post, err := cc.Storage.PostStore.GetPostByID(postID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if post == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return
+	}
+	if !canSeeContent(c, cc.Storage, post.ID, post.UserID, util.PostNotFoundError) {
+		return
+	}
+
 	userID := c.MustGet("userID").(uuid.UUID)
 	comments, err := cc.Storage.CommentStore.GetCommentsByPostID(postID, userID)
 
@@ -104,7 +151,7 @@ func (cc *CommentController) GetCommentsByPostID(c *gin.Context) {
 // UpdateComment godoc
 //
 //	@Summary		Update a comment
-//	@Description	Update an existing comment by its ID
+//	@Description	Update an existing comment by its ID. Moderators can update any comment.
 //	@Tags			Comments
 //	@Accept			json
 //	@Produce		json
@@ -143,9 +190,16 @@ func (cc *CommentController) UpdateComment(c *gin.Context) {
 		c.JSON(404, util.ErrorResponse{Error: util.NoCommentsFoundError})
 		return
 	}
+	moderated, ok := authorizeContentChange(c, cc.Storage, comment.UserID)
+	if !ok {
+		return
+	}
+	if moderated && !recordModerationAction(c, cc.Storage, model.ModerationActionUpdate, model.ModerationTargetComment, comment.ID, comment.UserID, comment.Content) {
+		return
+	}
 	comment.Content = params.Content
 
-	err = cc.Storage.CommentStore.UpdateComment(comment)
+	err = cc.Storage.CommentStore.UpdateComment(comment, moderated)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: "Error updating comment"})
 		return
@@ -156,7 +210,7 @@ func (cc *CommentController) UpdateComment(c *gin.Context) {
 // DeleteComment godoc
 //
 //	@Summary		Delete a comment
-//	@Description	Delete an existing comment by its ID
+//	@Description	Delete an existing comment by its ID. Moderators can delete any comment.
 //	@Tags			Comments
 //	@Accept			json
 //	@Produce		json
@@ -179,7 +233,22 @@ func (cc *CommentController) DeleteComment(c *gin.Context) {
 		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
 		return
 	}
-	// TODO: CHECK USERID
+	comment, err := cc.Storage.CommentStore.GetCommentByID(commentID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Error fetching comment"})
+		return
+	}
+	if comment == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.CommentNotFoundError})
+		return
+	}
+	moderated, ok := authorizeContentChange(c, cc.Storage, comment.UserID)
+	if !ok {
+		return
+	}
+	if moderated && !recordModerationAction(c, cc.Storage, model.ModerationActionDelete, model.ModerationTargetComment, comment.ID, comment.UserID, comment.Content) {
+		return
+	}
 	err = cc.Storage.CommentStore.DeleteComment(commentID)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: "Error deleting comment"})
@@ -187,3 +256,44 @@ func (cc *CommentController) DeleteComment(c *gin.Context) {
 	}
 	c.JSON(200, util.SuccessMessageResponse{Message: "Comment deleted successfully"})
 }
+
+// GetCommentRevisions godoc
+//
+//	@Summary		Get comment revisions
+//	@Description	List the earlier versions of an edited comment, newest first. The current version is the comment itself. Versions replaced by a moderator are left out.
+//	@Tags			Comments
+//	@Produce		json
+//	@Param			id	path		string	true	"Comment ID"
+//	@Success		200	{object}	util.SuccessResultResponse{result=[]model.Revision}
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/comments/{id}/revisions [get]
+func (cc *CommentController) GetCommentRevisions(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+	comment, err := cc.Storage.CommentStore.GetCommentByID(commentID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if comment == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.CommentNotFoundError})
+		return
+	}
+	if !canSeeContent(c, cc.Storage, comment.PostID, comment.UserID, util.CommentNotFoundError) {
+		return
+	}
+
+	revisions, err := cc.Storage.CommentStore.GetCommentRevisions(comment.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Comment revisions fetched successfully", Result: revisions})
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/blob"
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DataExportController lets users download a copy of their data. Archives are
+// built by worker.DataExporter and kept in Blob, which is not served under
+// /media.
+type DataExportController struct {
+	Storage *database.Storage
+	KeyRing *util.KeyRing
+	Blob    blob.Store
+	AppURL  string
+}
+
+func NewDataExportController(storage *database.Storage, keyRing *util.KeyRing, blobStore blob.Store, appURL string) *DataExportController {
+	return &DataExportController{
+		Storage: storage,
+		KeyRing: keyRing,
+		Blob:    blobStore,
+		AppURL:  appURL,
+	}
+}
+
+// RequestDataExport godoc
+//
+//	@Summary		Request a data export
+//	@Description	Start building an archive with the profile, posts, comments, replies, likes, follows and sessions of the authenticated user. The archive is built in the background, poll the returned export until it is completed. If an export is already in progress it is returned instead.
+//	@Tags			Users
+//	@Produce		json
+//	@Success		202	{object}	util.SuccessResultResponse{result=model.DataExport}
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me/exports [post]
+func (dc DataExportController) RequestDataExport(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	export, err := dc.Storage.DataExportStore.GetUnfinishedDataExport(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if export == nil {
+		export = &model.DataExport{UserID: userID}
+		if err := dc.Storage.DataExportStore.CreateDataExport(export); err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+	}
+
+	c.JSON(202, util.SuccessResultResponse{Message: "Data export requested", Result: export})
+}
+
+// GetDataExport godoc
+//
+//	@Summary		Get a data export
+//	@Description	Get the status of a data export. Completed exports include a download link that is valid for an hour, request the export again for a new link.
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id	path		string	true	"Export ID"
+//	@Success		200	{object}	util.SuccessResultResponse{result=model.DataExport}
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me/exports/{id} [get]
+func (dc DataExportController) GetDataExport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid export ID"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	export, err := dc.Storage.DataExportStore.GetDataExportByID(id)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if export == nil || export.UserID != userID {
+		c.JSON(404, util.ErrorResponse{Error: util.DataExportNotFoundError})
+		return
+	}
+
+	if export.Status == model.DataExportStatusCompleted && export.ExpiresAt.After(time.Now()) {
+		token, err := dc.KeyRing.CreateDataExportToken(export.ID)
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		export.DownloadURL = dc.AppURL + "/api/v1/exports/download?token=" + url.QueryEscape(token)
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Data export fetched successfully", Result: export})
+}
+
+// DownloadDataExport godoc
+//
+//	@Summary		Download a data export
+//	@Description	Download the zip archive of a data export with the token of a download link
+//	@Tags			Users
+//	@Produce		application/zip
+//	@Param			token	query		string	true	"Download token"
+//	@Success		200		{file}		binary
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Router			/exports/download [get]
+func (dc DataExportController) DownloadDataExport(c *gin.Context) {
+	claims, err := dc.KeyRing.ParseDataExportToken(c.Query("token"))
+	if err != nil {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidDownloadTokenError})
+		return
+	}
+	id, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidDownloadTokenError})
+		return
+	}
+
+	export, err := dc.Storage.DataExportStore.GetDataExportByID(id)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if export == nil || export.UserID == uuid.Nil || export.Status != model.DataExportStatusCompleted || export.BlobKey == nil || export.ExpiresAt.Before(time.Now()) {
+		c.JSON(404, util.ErrorResponse{Error: util.DataExportNotFoundError})
+		return
+	}
+
+	file, err := dc.Blob.Open(c.Request.Context(), *export.BlobKey)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			c.JSON(404, util.ErrorResponse{Error: util.DataExportNotFoundError})
+			return
+		}
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="go_social_export_`+export.CompletedAt.Format("2006-01-02")+`.zip"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(200)
+	if _, err := io.Copy(c.Writer, file); err != nil {
+		log.Printf("error serving data export %s: %v", export.ID, err)
+	}
+}
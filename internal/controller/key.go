@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type KeyController struct {
+	KeyRing *util.KeyRing
+}
+
+func NewKeyController(keyRing *util.KeyRing) *KeyController {
+	return &KeyController{
+		KeyRing: keyRing,
+	}
+}
+
+// GetJWKS godoc
+//
+//	@Summary		JSON Web Key Set
+//	@Description	Public keys that can be used to verify tokens issued by go_social
+//	@Tags			Keys
+//	@Produce		json
+//	@Success		200	{object}	util.JWKSet
+//	@Router			/.well-known/jwks.json [get]
+func (kc KeyController) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(200, kc.KeyRing.JWKS())
+}
@@ -29,6 +29,7 @@ func NewLikeController(storage *database.Storage) *LikeController {
 //	@Success		200	{object}	util.SuccessMessageResponse
 //	@Failure		400	{object}	util.ErrorResponse
 //	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		403	{object}	util.ErrorResponse
 //	@Failure		404	{object}	util.ErrorResponse
 //	@Failure		500	{object}	util.ErrorResponse
 //	@Security		Bearer
@@ -43,6 +44,22 @@ func (lc LikeController) LikePost(c *gin.Context) {
 		return
 	}
 
+	post, err := lc.Storage.PostStore.GetPostByID(postID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if post == nil || !post.IsPublished() {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return
+	}
+	if !authorizeInteraction(c, lc.Storage, post.UserID) {
+		return
+	}
+	if !canSeeContent(c, lc.Storage, post.ID, post.UserID, util.PostNotFoundError) {
+		return
+	}
+
 	userID := c.MustGet("userID").(uuid.UUID)
 
 	liked, err := lc.Storage.LikeStore.IsPostLiked(postID, userID)
@@ -123,6 +140,7 @@ func (lc LikeController) UnlikePost(c *gin.Context) {
 // @Success		200	{object}	util.SuccessMessageResponse
 // @Failure		400	{object}	util.ErrorResponse
 // @Failure		401	{object}	util.ErrorResponse
+// @Failure		403	{object}	util.ErrorResponse
 // @Failure		404	{object}	util.ErrorResponse
 // @Failure		500	{object}	util.ErrorResponse
 // @Security		Bearer
@@ -136,6 +154,22 @@ func (lc *LikeController) LikeComment(c *gin.Context) {
 		return
 	}
 
+	comment, err := lc.Storage.CommentStore.GetCommentByID(commentID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if comment == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.CommentNotFoundError})
+		return
+	}
+	if !authorizeInteraction(c, lc.Storage, comment.UserID) {
+		return
+	}
+	if !canSeeContent(c, lc.Storage, comment.PostID, comment.UserID, util.CommentNotFoundError) {
+		return
+	}
+
 	userID := c.MustGet("userID").(uuid.UUID)
 
 	existLike, err := lc.Storage.LikeStore.IsCommentLiked(commentID, userID)
@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/fatihesergg/go_social/internal/blob"
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/imaging"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxMediaSize = 8 << 20
+
+type MediaController struct {
+	Storage *database.Storage
+	Blob    blob.Store
+}
+
+func NewMediaController(storage *database.Storage, blobStore blob.Store) *MediaController {
+	return &MediaController{
+		Storage: storage,
+		Blob:    blobStore,
+	}
+}
+
+// UploadMedia godoc
+//
+//	@Summary		Upload media
+//	@Description	Upload a JPEG, PNG or GIF image to attach to a post or a comment with its media_id. The image is stored without its metadata and a thumbnail is generated. Uploads that aren't attached within a day are deleted.
+//	@Tags			Media
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image"
+//	@Success		201		{object}	util.SuccessResultResponse{result=model.Media}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		413		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/media [post]
+func (mc MediaController) UploadMedia(c *gin.Context) {
+	// Leave room for the multipart headers around the file.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMediaSize+1<<20)
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			c.JSON(413, util.ErrorResponse{Error: util.FileTooLargeError})
+			return
+		}
+		c.JSON(400, util.ErrorResponse{Error: util.FileRequiredError})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxMediaSize+1))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.FileRequiredError})
+		return
+	}
+	if len(data) > maxMediaSize {
+		c.JSON(413, util.ErrorResponse{Error: util.FileTooLargeError})
+		return
+	}
+
+	processed, err := imaging.Process(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrInvalidImage):
+			c.JSON(400, util.ErrorResponse{Error: util.InvalidImageError})
+		case errors.Is(err, imaging.ErrImageTooLarge):
+			c.JSON(400, util.ErrorResponse{Error: util.ImageDimensionsTooLargeError})
+		default:
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		}
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	name, err := util.GenerateRandomToken()
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	media := &model.Media{
+		UserID:       &userID,
+		BlobKey:      fmt.Sprintf("media/%s/%s%s", userID, name, processed.Extension),
+		ThumbnailKey: fmt.Sprintf("media/%s/%s_thumb%s", userID, name, processed.ThumbnailExtension),
+		ContentType:  processed.ContentType,
+		Width:        processed.Width,
+		Height:       processed.Height,
+		Size:         len(processed.Data),
+	}
+	if err := mc.Blob.Put(c.Request.Context(), media.BlobKey, bytes.NewReader(processed.Data)); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if err := mc.Blob.Put(c.Request.Context(), media.ThumbnailKey, bytes.NewReader(processed.Thumbnail)); err != nil {
+		mc.deleteBlobs(c, media)
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if err := mc.Storage.MediaStore.CreateMedia(media); err != nil {
+		mc.deleteBlobs(c, media)
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	media.URL = mc.Blob.URL(media.BlobKey)
+	media.ThumbnailURL = mc.Blob.URL(media.ThumbnailKey)
+	c.JSON(201, util.SuccessResultResponse{Message: "Media uploaded successfully", Result: media})
+}
+
+func (mc MediaController) deleteBlobs(c *gin.Context, media *model.Media) {
+	for _, key := range []string{media.BlobKey, media.ThumbnailKey} {
+		if err := mc.Blob.Delete(c.Request.Context(), key); err != nil {
+			log.Printf("error deleting media %s: %v", key, err)
+		}
+	}
+}
+
+// attachableMedia returns the media a post or a comment is created with,
+// which must be an upload of the authenticated user that isn't attached to
+// anything yet. It writes the error response and returns false otherwise.
+func attachableMedia(c *gin.Context, storage *database.Storage, mediaID uuid.UUID) (*model.Media, bool) {
+	media, err := storage.MediaStore.GetMediaByID(mediaID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return nil, false
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+	if media == nil || media.UserID == nil || *media.UserID != userID {
+		c.JSON(404, util.ErrorResponse{Error: util.MediaNotFoundError})
+		return nil, false
+	}
+	if media.AttachedAt != nil {
+		c.JSON(409, util.ErrorResponse{Error: util.MediaAlreadyAttachedError})
+		return nil, false
+	}
+	return media, true
+}
+
+// GetMedia godoc
+//
+//	@Summary		Get media
+//	@Description	Serve an uploaded file like an avatar
+//	@Tags			Media
+//	@Produce		octet-stream
+//	@Param			key	path		string	true	"Media key"
+//	@Success		200	{file}		binary
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Router			/media/{key} [get]
+func (mc MediaController) GetMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	file, err := mc.Blob.Open(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+			c.JSON(404, util.ErrorResponse{Error: util.MediaNotFoundError})
+			return
+		}
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// Keys are random and never reused, so the content can be cached forever.
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(200)
+	if _, err := io.Copy(c.Writer, file); err != nil {
+		log.Printf("error serving media %s: %v", key, err)
+	}
+}
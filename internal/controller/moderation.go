@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/dto"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ModerationController struct {
+	Storage *database.Storage
+}
+
+func NewModerationController(storage *database.Storage) *ModerationController {
+	return &ModerationController{
+		Storage: storage,
+	}
+}
+
+// GetModerationActions godoc
+//
+//	@Summary		List moderation actions
+//	@Description	List the changes moderators made to content of other users, newest first
+//	@Tags			Moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.ModerationAction}
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/moderation/actions [get]
+func (mc ModerationController) GetModerationActions(c *gin.Context) {
+	pagination := database.NewPagination(c)
+
+	actions, err := mc.Storage.ModerationStore.GetModerationActions(pagination)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Moderation actions fetched successfully", Result: actions})
+}
+
+// UpdateUserRole godoc
+//
+//	@Summary		Change the role of a user
+//	@Description	Make a user a moderator or an admin, or take the role away
+//	@Tags			Moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string					true	"User ID"
+//	@Param			role	body		dto.UpdateUserRoleDTO	true	"New role"
+//	@Success		200		{object}	util.SuccessMessageResponse
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/admin/users/{id}/role [put]
+func (mc ModerationController) UpdateUserRole(c *gin.Context) {
+	var params dto.UpdateUserRoleDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+	// Admins can't demote themselves, so there is always an admin left.
+	if targetID == c.MustGet("userID").(uuid.UUID) {
+		c.JSON(400, util.ErrorResponse{Error: util.ChangeOwnRoleError})
+		return
+	}
+
+	user, err := mc.Storage.UserStore.GetUserByID(targetID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	if err := mc.Storage.UserStore.UpdateUserRole(user.ID, params.Role); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "User role updated successfully"})
+}
+
+// authorizeContentChange lets the owner of a post, comment or reply change it,
+// and moderators change anyone's. It returns whether the change is a
+// moderation, and responds with an error if it isn't allowed.
+func authorizeContentChange(c *gin.Context, storage *database.Storage, ownerID uuid.UUID) (bool, bool) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	if userID == ownerID {
+		return false, true
+	}
+
+	user, err := storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return false, false
+	}
+	if user == nil || !user.HasRole(model.RoleModerator) {
+		c.JSON(403, util.ErrorResponse{Error: util.InvalidPermissionError})
+		return false, false
+	}
+	return true, true
+}
+
+// recordModerationAction stores the audit record of a moderator changing
+// content of another user. It runs before the change, so no moderation
+// happens without a record, and responds with an error if it fails.
+func recordModerationAction(c *gin.Context, storage *database.Storage, action, targetType string, targetID, targetUserID uuid.UUID, previousContent string) bool {
+	moderatorID := c.MustGet("userID").(uuid.UUID)
+	err := storage.ModerationStore.CreateModerationAction(&model.ModerationAction{
+		ModeratorID:     &moderatorID,
+		Action:          action,
+		TargetType:      targetType,
+		TargetID:        targetID,
+		TargetUserID:    &targetUserID,
+		PreviousContent: previousContent,
+	})
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/dto"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MuteController struct {
+	Storage *database.Storage
+}
+
+func NewMuteController(storage *database.Storage) *MuteController {
+	return &MuteController{
+		Storage: storage,
+	}
+}
+
+// MuteUser godoc
+//
+//	@Summary		Mute a user
+//	@Description	Hide the posts of a user from the feed and post listings without unfollowing or blocking them. The mute lasts forever unless expires_in_days is set. Muting a muted user changes the expiry.
+//	@Tags			Mutes
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string				true	"User ID to mute"
+//	@Param			mute	body		dto.MuteUserDTO		false	"Mute expiry"
+//	@Success		200		{object}	util.SuccessResultResponse{result=model.Mute}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/mute [post]
+func (mc MuteController) MuteUser(c *gin.Context) {
+	mutedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	var params dto.MuteUserDTO
+	if err := c.ShouldBindJSON(&params); err != nil && !errors.Is(err, io.EOF) {
+		util.HandleBindError(c, err)
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+	if mutedID == userID {
+		c.JSON(400, util.ErrorResponse{Error: util.MuteYourselfError})
+		return
+	}
+
+	user, err := mc.Storage.UserStore.GetUserByID(mutedID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	mute := model.Mute{
+		UserID:  userID,
+		MutedID: mutedID,
+	}
+	if params.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, *params.ExpiresInDays)
+		mute.ExpiresAt = &expiresAt
+	}
+	if err := mc.Storage.MuteStore.MuteUser(&mute); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "User muted successfully", Result: mute})
+}
+
+// UnmuteUser godoc
+//
+//	@Summary		Unmute a user
+//	@Description	Remove the mute of a user
+//	@Tags			Mutes
+//	@Produce		json
+//	@Param			id	path		string	true	"User ID to unmute"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/mute [delete]
+func (mc MuteController) UnmuteUser(c *gin.Context) {
+	mutedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	unmuted, err := mc.Storage.MuteStore.UnmuteUser(userID, mutedID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !unmuted {
+		c.JSON(404, util.ErrorResponse{Error: util.MuteNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "User unmuted successfully"})
+}
+
+// MuteKeyword godoc
+//
+//	@Summary		Mute a keyword
+//	@Description	Hide posts of other users containing a keyword or phrase, ignoring case, from the feed and post listings. The mute lasts forever unless expires_in_days is set. Muting a muted keyword changes the expiry.
+//	@Tags			Mutes
+//	@Accept			json
+//	@Produce		json
+//	@Param			keyword	body		dto.MuteKeywordDTO	true	"Keyword and expiry"
+//	@Success		200		{object}	util.SuccessResultResponse{result=model.MutedKeyword}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/mutes/keywords [post]
+func (mc MuteController) MuteKeyword(c *gin.Context) {
+	var params dto.MuteKeywordDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+	keyword := strings.ToLower(strings.TrimSpace(params.Keyword))
+	if keyword == "" {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidKeywordError})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	mutedKeyword := model.MutedKeyword{
+		UserID:  userID,
+		Keyword: keyword,
+	}
+	if params.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, *params.ExpiresInDays)
+		mutedKeyword.ExpiresAt = &expiresAt
+	}
+	if err := mc.Storage.MuteStore.MuteKeyword(&mutedKeyword); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Keyword muted successfully", Result: mutedKeyword})
+}
+
+// UnmuteKeyword godoc
+//
+//	@Summary		Unmute a keyword
+//	@Description	Remove a muted keyword by its ID
+//	@Tags			Mutes
+//	@Produce		json
+//	@Param			id	path		string	true	"Muted keyword ID"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/mutes/keywords/{id} [delete]
+func (mc MuteController) UnmuteKeyword(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid keyword ID"})
+		return
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	unmuted, err := mc.Storage.MuteStore.UnmuteKeyword(id, userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !unmuted {
+		c.JSON(404, util.ErrorResponse{Error: util.MutedKeywordNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Keyword unmuted successfully"})
+}
+
+// GetMutes godoc
+//
+//	@Summary		Get mutes
+//	@Description	List the users and keywords muted by the authenticated user, newest first. Expired mutes are left out.
+//	@Tags			Mutes
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessResultResponse{result=dto.MuteListResponse}
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/mutes [get]
+func (mc MuteController) GetMutes(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	mutes, err := mc.Storage.MuteStore.GetMutesByUserID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	keywords, err := mc.Storage.MuteStore.GetMutedKeywordsByUserID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Mutes fetched successfully", Result: dto.MuteListResponse{Users: mutes, Keywords: keywords}})
+}
@@ -0,0 +1,347 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/oidc"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const oidcStateTTL = time.Minute * 10
+
+type OIDCController struct {
+	Storage   *database.Storage
+	Users     *UserController
+	Providers map[string]*oidc.Provider
+}
+
+func NewOIDCController(storage *database.Storage, users *UserController, providers map[string]*oidc.Provider) *OIDCController {
+	return &OIDCController{
+		Storage:   storage,
+		Users:     users,
+		Providers: providers,
+	}
+}
+
+// Login godoc
+//
+//	@Summary		Log in with an external provider
+//	@Description	Redirect to the OpenID Connect provider to sign in. The provider redirects back to the callback endpoint.
+//	@Tags			Auth
+//	@Param			provider	path	string	true	"Provider name"
+//	@Success		302
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Failure		502	{object}	util.ErrorResponse
+//	@Router			/auth/{provider}/login [get]
+func (oc OIDCController) Login(c *gin.Context) {
+	providerName := c.Param("provider")
+	provider, ok := oc.Providers[providerName]
+	if !ok {
+		c.JSON(404, util.ErrorResponse{Error: util.UnknownLoginProviderError})
+		return
+	}
+
+	state, err := util.GenerateRandomToken()
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	nonce, err := util.GenerateRandomToken()
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	codeVerifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		log.Printf("error discovering oidc provider %s: %v", providerName, err)
+		c.JSON(502, util.ErrorResponse{Error: util.ExternalLoginFailedError})
+		return
+	}
+
+	err = oc.Storage.IdentityStore.CreateOIDCState(&model.OIDCState{
+		StateHash:    util.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	})
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.Redirect(302, authURL)
+}
+
+// Callback godoc
+//
+//	@Summary		External provider callback
+//	@Description	Complete a login with an OpenID Connect provider. The external account is linked to the go_social account with the same verified email, or a new account is created on first login.
+//	@Tags			Auth
+//	@Produce		json
+//	@Param			provider	path		string	true	"Provider name"
+//	@Param			code		query		string	true	"Authorization code"
+//	@Param			state		query		string	true	"State"
+//	@Success		200			{object}	util.SuccessResultResponse{result=dto.TokenResponse}
+//	@Success		202			{object}	util.SuccessResultResponse{result=dto.TwoFactorChallengeResponse}
+//	@Failure		400			{object}	util.ErrorResponse
+//	@Failure		401			{object}	util.ErrorResponse
+//	@Failure		404			{object}	util.ErrorResponse
+//	@Failure		409			{object}	util.ErrorResponse
+//	@Failure		500			{object}	util.ErrorResponse
+//	@Failure		502			{object}	util.ErrorResponse
+//	@Router			/auth/{provider}/callback [get]
+func (oc OIDCController) Callback(c *gin.Context) {
+	providerName := c.Param("provider")
+	provider, ok := oc.Providers[providerName]
+	if !ok {
+		c.JSON(404, util.ErrorResponse{Error: util.UnknownLoginProviderError})
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		log.Printf("oidc provider %s returned an error: %s %s", providerName, providerError, c.Query("error_description"))
+		c.JSON(401, util.ErrorResponse{Error: util.ExternalLoginFailedError})
+		return
+	}
+
+	code := c.Query("code")
+	stateParam := c.Query("state")
+	if code == "" || stateParam == "" {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidLoginStateError})
+		return
+	}
+
+	state, err := oc.Storage.IdentityStore.ConsumeOIDCState(util.HashToken(stateParam))
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if state == nil || state.Provider != providerName || state.ExpiresAt.Before(time.Now()) {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidLoginStateError})
+		return
+	}
+
+	token, err := provider.Exchange(c.Request.Context(), code, state.CodeVerifier)
+	if err != nil {
+		log.Printf("error exchanging oidc code with %s: %v", providerName, err)
+		c.JSON(502, util.ErrorResponse{Error: util.ExternalLoginFailedError})
+		return
+	}
+	claims, err := provider.VerifyIDToken(c.Request.Context(), token.IDToken, state.Nonce)
+	if err != nil {
+		log.Printf("error verifying oidc id token from %s: %v", providerName, err)
+		c.JSON(401, util.ErrorResponse{Error: util.ExternalLoginFailedError})
+		return
+	}
+
+	identity, err := oc.Storage.IdentityStore.GetIdentity(providerName, claims.Subject)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	var user *model.User
+	if identity != nil {
+		user, err = oc.Storage.UserStore.GetUserByID(identity.UserID)
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		if user == nil {
+			c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+			return
+		}
+	} else {
+		if claims.Email == "" {
+			c.JSON(400, util.ErrorResponse{Error: util.ExternalEmailRequiredError})
+			return
+		}
+
+		user, err = oc.Storage.UserStore.GetUserByEmail(util.NormalizeEmail(claims.Email))
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		if user != nil {
+			// Only link to an existing account if both sides have verified the
+			// email, otherwise anyone could take over an account through a
+			// provider that lets them use an arbitrary email.
+			if !claims.EmailVerified || user.EmailVerifiedAt == nil {
+				c.JSON(409, util.ErrorResponse{Error: util.ExternalAccountExistsError})
+				return
+			}
+			err = oc.Storage.IdentityStore.CreateIdentity(&model.UserIdentity{
+				UserID:   user.ID,
+				Provider: providerName,
+				Subject:  claims.Subject,
+				Email:    claims.Email,
+			})
+			if err != nil {
+				c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+				return
+			}
+		} else {
+			user, err = oc.createUser(c.Request.Context(), providerName, claims)
+			if err != nil {
+				c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+				return
+			}
+		}
+	}
+
+	if user.TOTPEnabledAt != nil {
+		oc.Users.sendTwoFactorChallenge(c, user)
+		return
+	}
+	oc.Users.startSession(c, user)
+}
+
+// GetIdentities godoc
+//
+//	@Summary		Get linked accounts
+//	@Description	List the external accounts linked to the authenticated user
+//	@Tags			Auth
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessResultResponse{result=[]model.UserIdentity}
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/identities [get]
+func (oc OIDCController) GetIdentities(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	identities, err := oc.Storage.IdentityStore.GetIdentitiesByUserID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Linked accounts fetched successfully", Result: identities})
+}
+
+// createUser creates the go_social account for the first login with an
+// external account. The password is random, so the account can only be used
+// with a password after a password reset.
+func (oc OIDCController) createUser(ctx context.Context, providerName string, claims *oidc.IDTokenClaims) (*model.User, error) {
+	username, err := oc.generateUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := util.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	name, lastName := claims.GivenName, claims.FamilyName
+	if name == "" && lastName == "" {
+		name, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+	if name == "" {
+		name = username
+	}
+
+	user := &model.User{
+		Name:     truncate(name, 50),
+		LastName: truncate(lastName, 50),
+		Username: username,
+		Email:    util.NormalizeEmail(claims.Email),
+		Password: string(hashedPass),
+	}
+	if claims.Picture != "" && len(claims.Picture) <= 255 {
+		user.Avatar = &claims.Picture
+	}
+	if claims.EmailVerified {
+		now := time.Now().UTC()
+		user.EmailVerifiedAt = &now
+	}
+
+	identity := &model.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := oc.Storage.IdentityStore.CreateUserWithIdentity(user, identity); err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := oc.Users.sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("error sending verification email to user %s: %v", user.ID, err)
+		}
+	}
+	return user, nil
+}
+
+// generateUsername derives a free username from the profile of an external
+// account, adding random digits if the name is taken.
+func (oc OIDCController) generateUsername(claims *oidc.IDTokenClaims) (string, error) {
+	base := ""
+	for _, candidate := range []string{claims.PreferredUsername, strings.Split(claims.Email, "@")[0], claims.GivenName} {
+		if base = alphanumeric(candidate); base != "" {
+			break
+		}
+	}
+	if base == "" {
+		base = "user"
+	}
+	base = truncate(base, 40)
+
+	username := base
+	for i := 0; i < 10; i++ {
+		taken, err := oc.Users.isUsernameTaken(username)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return username, nil
+		}
+		suffix, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return "", err
+		}
+		username = base + suffix.String()
+	}
+	return base + strings.ReplaceAll(uuid.NewString(), "-", "")[:8], nil
+}
+
+func alphanumeric(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
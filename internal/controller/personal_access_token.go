@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"slices"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/dto"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PersonalAccessTokenController struct {
+	Storage *database.Storage
+}
+
+func NewPersonalAccessTokenController(storage *database.Storage) *PersonalAccessTokenController {
+	return &PersonalAccessTokenController{
+		Storage: storage,
+	}
+}
+
+// CreatePersonalAccessToken godoc
+//
+//	@Summary		Create a personal access token
+//	@Description	Create a named token with scopes for scripts and bots. The token is only shown in this response.
+//	@Tags			Tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	body		dto.CreatePersonalAccessTokenDTO	true	"Token name, scopes and expiry"
+//	@Success		201		{object}	util.SuccessResultResponse{result=dto.PersonalAccessTokenResponse}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/tokens [post]
+func (pc PersonalAccessTokenController) CreatePersonalAccessToken(c *gin.Context) {
+	var params dto.CreatePersonalAccessTokenDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	scopes := []string{}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(model.PersonalAccessTokenScopes, scope) {
+			c.JSON(400, util.ErrorResponse{Error: util.InvalidScopeError + ": " + scope})
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	secret, err := util.GenerateRandomToken()
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	token := model.PersonalAccessTokenPrefix + secret
+
+	accessToken := model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      params.Name,
+		TokenHash: util.HashToken(token),
+		Scopes:    scopes,
+	}
+	if params.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, *params.ExpiresInDays)
+		accessToken.ExpiresAt = &expiresAt
+	}
+	if err := pc.Storage.PersonalAccessTokenStore.CreatePersonalAccessToken(&accessToken); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	result := dto.PersonalAccessTokenResponse{Token: token, PersonalAccessToken: accessToken}
+	c.JSON(201, util.SuccessResultResponse{Message: "Personal access token created. Copy it now, it won't be shown again", Result: result})
+}
+
+// GetPersonalAccessTokens godoc
+//
+//	@Summary		List personal access tokens
+//	@Description	List the active personal access tokens of the authenticated user
+//	@Tags			Tokens
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessResultResponse{result=[]model.PersonalAccessToken}
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/tokens [get]
+func (pc PersonalAccessTokenController) GetPersonalAccessTokens(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	tokens, err := pc.Storage.PersonalAccessTokenStore.GetPersonalAccessTokensByUserID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Personal access tokens fetched successfully", Result: tokens})
+}
+
+// RevokePersonalAccessToken godoc
+//
+//	@Summary		Revoke a personal access token
+//	@Description	Revoke one of the authenticated user's personal access tokens
+//	@Tags			Tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"Token ID"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/tokens/{id} [delete]
+func (pc PersonalAccessTokenController) RevokePersonalAccessToken(c *gin.Context) {
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	revoked, err := pc.Storage.PersonalAccessTokenStore.RevokePersonalAccessToken(tokenID, userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !revoked {
+		c.JSON(404, util.ErrorResponse{Error: util.PersonalAccessTokenNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Personal access token revoked successfully"})
+}
@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/fatihesergg/go_social/internal/blob"
 	"github.com/fatihesergg/go_social/internal/database"
 	"github.com/fatihesergg/go_social/internal/dto"
 	"github.com/fatihesergg/go_social/internal/model"
@@ -13,11 +16,13 @@ import (
 
 type PostController struct {
 	Storage *database.Storage
+	Blob    blob.Store
 }
 
-func NewPostController(storage *database.Storage) *PostController {
+func NewPostController(storage *database.Storage, blobStore blob.Store) *PostController {
 	return &PostController{
 		Storage: storage,
+		Blob:    blobStore,
 	}
 }
 
@@ -102,13 +107,17 @@ func (pc PostController) GetPostByID(c *gin.Context) {
 // CreatePost godoc
 //
 //	@Summary		Create a new post
-//	@Description	Create a new post with content and optional image
+//	@Description	Create a new post with content and an optional image uploaded with POST /media. With quote_id set the post quotes another post, which is embedded in it. With draft set the post is saved as a private draft that can be edited, scheduled and published later.
 //	@Tags			Posts
 //	@Accept			json
 //	@Produce		json
 //	@Param			post	body		dto.CreatePostDTO	true	"Post data"
 //	@Success		201		{object}	util.SuccessMessageResponse
+//	@Success		201		{object}	util.SuccessResultResponse{result=dto.DraftResponse}
 //	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		409		{object}	util.ErrorResponse
 //	@Failure		500		{object}	util.ErrorResponse
 //	@Router			/posts [post]
 //	@Security		Bearer
@@ -121,18 +130,45 @@ func (pc PostController) CreatePost(c *gin.Context) {
 
 	post := &model.Post{
 		Content: params.Content,
+		Status:  model.PostStatusPublished,
+	}
+	if params.Draft {
+		post.Status = model.PostStatusDraft
 	}
 
 	userID := c.MustGet("userID").(uuid.UUID)
 	post.UserID = userID
 
+	if params.QuoteID != nil {
+		if _, ok := shareablePost(c, pc.Storage, *params.QuoteID); !ok {
+			return
+		}
+		post.QuoteID = params.QuoteID
+	}
+
+	if params.MediaID != nil {
+		media, ok := attachableMedia(c, pc.Storage, *params.MediaID)
+		if !ok {
+			return
+		}
+		image, thumbnail := pc.Blob.URL(media.BlobKey), pc.Blob.URL(media.ThumbnailKey)
+		post.MediaID, post.Image, post.Thumbnail = &media.ID, &image, &thumbnail
+	}
+
 	err := pc.Storage.PostStore.CreatePost(post)
 	if err != nil {
-
+		if errors.Is(err, database.ErrMediaAttached) {
+			c.JSON(409, util.ErrorResponse{Error: util.MediaAlreadyAttachedError})
+			return
+		}
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
 
+	if !post.IsPublished() {
+		c.JSON(201, util.SuccessResultResponse{Message: "Draft saved successfully", Result: dto.NewDraftResponse(post)})
+		return
+	}
 	c.JSON(201, util.SuccessMessageResponse{Message: "Post created succesfully"})
 
 }
@@ -140,7 +176,7 @@ func (pc PostController) CreatePost(c *gin.Context) {
 // UpdatePost godoc
 //
 //	@Summary		Update an existing post
-//	@Description	Update the content and/or image of an existing post by its ID
+//	@Description	Update the content and/or image of an existing post by its ID. Moderators can update any post.
 //	@Tags			Posts
 //	@Accept			json
 //	@Produce		json
@@ -175,12 +211,15 @@ func (pc PostController) UpdatePost(c *gin.Context) {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-	if existPost == nil {
+	if existPost == nil || isOthersDraft(c, existPost) {
 		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
 		return
 	}
-	if existPost.UserID != c.MustGet("userID").(uuid.UUID) {
-		c.JSON(403, util.ErrorResponse{Error: util.InvalidPermissionError})
+	moderated, ok := authorizeContentChange(c, pc.Storage, existPost.UserID)
+	if !ok {
+		return
+	}
+	if moderated && !recordModerationAction(c, pc.Storage, model.ModerationActionUpdate, model.ModerationTargetPost, existPost.ID, existPost.UserID, existPost.Content) {
 		return
 	}
 	post := &model.Post{
@@ -188,7 +227,7 @@ func (pc PostController) UpdatePost(c *gin.Context) {
 		Content: params.Content,
 	}
 
-	err = pc.Storage.PostStore.UpdatePost(post)
+	err = pc.Storage.PostStore.UpdatePost(post, moderated)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: "Error updating post"})
 		return
@@ -200,7 +239,7 @@ func (pc PostController) UpdatePost(c *gin.Context) {
 // DeletePost godoc
 //
 //	@Summary		Delete a post
-//	@Description	Delete an existing post by its ID
+//	@Description	Delete an existing post by its ID. Moderators can delete any post.
 //	@Tags			Posts
 //	@Accept			json
 //	@Produce		json
@@ -229,12 +268,15 @@ func (pc PostController) DeletePost(c *gin.Context) {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-	if post == nil {
+	if post == nil || isOthersDraft(c, post) {
 		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
 		return
 	}
-	if post.UserID != c.MustGet("userID").(uuid.UUID) {
-		c.JSON(403, util.ErrorResponse{Error: util.InvalidPermissionError})
+	moderated, ok := authorizeContentChange(c, pc.Storage, post.UserID)
+	if !ok {
+		return
+	}
+	if moderated && !recordModerationAction(c, pc.Storage, model.ModerationActionDelete, model.ModerationTargetPost, post.ID, post.UserID, post.Content) {
 		return
 	}
 
@@ -245,3 +287,225 @@ func (pc PostController) DeletePost(c *gin.Context) {
 	}
 	c.JSON(200, util.SuccessMessageResponse{Message: "Post deleted successfully"})
 }
+
+// GetPostRevisions godoc
+//
+//	@Summary		Get post revisions
+//	@Description	List the earlier versions of an edited post, newest first. The current version is the post itself. Text a moderator edited out is not listed.
+//	@Tags			Posts
+//	@Produce		json
+//	@Param			id	path		string	true	"Post ID"
+//	@Success		200	{object}	util.SuccessResultResponse{result=[]model.Revision}
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/posts/{id}/revisions [get]
+func (pc PostController) GetPostRevisions(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+	post, err := pc.Storage.PostStore.GetPostByID(postID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if post == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return
+	}
+	if !canSeeContent(c, pc.Storage, post.ID, post.UserID, util.PostNotFoundError) {
+		return
+	}
+
+	revisions, err := pc.Storage.PostStore.GetPostRevisions(post.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Post revisions fetched successfully", Result: revisions})
+}
+
+// canSeeContent checks that the authenticated user can see content authorID
+// wrote on a post. Hidden content responds with notFoundError as if it didn't
+// exist. It writes the error response and returns false otherwise.
+func canSeeContent(c *gin.Context, storage *database.Storage, postID, authorID uuid.UUID, notFoundError string) bool {
+	userID := c.MustGet("userID").(uuid.UUID)
+	visible, err := storage.PostStore.IsContentVisible(postID, authorID, userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return false
+	}
+	if !visible {
+		c.JSON(404, util.ErrorResponse{Error: notFoundError})
+		return false
+	}
+	return true
+}
+
+// GetDrafts godoc
+//
+//	@Summary		Get drafts
+//	@Description	List the drafts and scheduled posts of the authenticated user, most recently saved first
+//	@Tags			Posts
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"		default(20)
+//	@Param			offset	query		int	false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]dto.DraftResponse}
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/posts/drafts [get]
+func (pc PostController) GetDrafts(c *gin.Context) {
+	pagination := database.NewPagination(c)
+	userID := c.MustGet("userID").(uuid.UUID)
+	posts, err := pc.Storage.PostStore.GetDraftsByUserID(userID, pagination)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Drafts fetched successfully", Result: dto.NewDraftListResponse(posts)})
+}
+
+// SchedulePost godoc
+//
+//	@Summary		Schedule a draft
+//	@Description	Schedule a draft to be published at publish_at, or change the publish time of a scheduled post
+//	@Tags			Posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string				true	"Post ID"
+//	@Param			body	body		dto.SchedulePostDTO	true	"Publish time"
+//	@Success		200		{object}	util.SuccessResultResponse{result=dto.DraftResponse}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		409		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/posts/{id}/schedule [post]
+func (pc PostController) SchedulePost(c *gin.Context) {
+	var params dto.SchedulePostDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+	if !params.PublishAt.After(time.Now()) {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidPublishTimeError})
+		return
+	}
+	post, ok := pc.draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := pc.Storage.PostStore.SchedulePost(post.ID, params.PublishAt); err != nil {
+		pc.handleDraftError(c, err)
+		return
+	}
+	post.Status = model.PostStatusScheduled
+	post.PublishAt = &params.PublishAt
+	c.JSON(200, util.SuccessResultResponse{Message: "Post scheduled successfully", Result: dto.NewDraftResponse(post)})
+}
+
+// UnschedulePost godoc
+//
+//	@Summary		Unschedule a post
+//	@Description	Turn a scheduled post back into a draft
+//	@Tags			Posts
+//	@Produce		json
+//	@Param			id	path		string	true	"Post ID"
+//	@Success		200	{object}	util.SuccessResultResponse{result=dto.DraftResponse}
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		409	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/posts/{id}/schedule [delete]
+func (pc PostController) UnschedulePost(c *gin.Context) {
+	post, ok := pc.draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := pc.Storage.PostStore.UnschedulePost(post.ID); err != nil {
+		pc.handleDraftError(c, err)
+		return
+	}
+	post.Status = model.PostStatusDraft
+	post.PublishAt = nil
+	c.JSON(200, util.SuccessResultResponse{Message: "Post unscheduled successfully", Result: dto.NewDraftResponse(post)})
+}
+
+// PublishPost godoc
+//
+//	@Summary		Publish a draft
+//	@Description	Publish a draft or scheduled post right away
+//	@Tags			Posts
+//	@Produce		json
+//	@Param			id	path		string	true	"Post ID"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		409	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/posts/{id}/publish [post]
+func (pc PostController) PublishPost(c *gin.Context) {
+	post, ok := pc.draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := pc.Storage.PostStore.PublishPost(post.ID); err != nil {
+		pc.handleDraftError(c, err)
+		return
+	}
+	c.JSON(200, util.SuccessMessageResponse{Message: "Post published successfully"})
+}
+
+// draftFromParam returns the unpublished post of the authenticated user in the
+// id path parameter. It writes the error response and returns false if there
+// is none.
+func (pc PostController) draftFromParam(c *gin.Context) (*model.Post, bool) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return nil, false
+	}
+	post, err := pc.Storage.PostStore.GetPostByID(postID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return nil, false
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+	if post == nil || post.UserID != userID {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return nil, false
+	}
+	if post.IsPublished() {
+		c.JSON(409, util.ErrorResponse{Error: util.PostAlreadyPublishedError})
+		return nil, false
+	}
+	return post, true
+}
+
+func (pc PostController) handleDraftError(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrPostPublished) {
+		c.JSON(409, util.ErrorResponse{Error: util.PostAlreadyPublishedError})
+		return
+	}
+	c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+}
+
+// isOthersDraft reports whether post is a draft or scheduled post of someone
+// other than the authenticated user. Those are treated as if they didn't
+// exist, even for moderators.
+func isOthersDraft(c *gin.Context, post *model.Post) bool {
+	return !post.IsPublished() && post.UserID != c.MustGet("userID").(uuid.UUID)
+}
@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatihesergg/go_social/internal/blob"
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/dto"
+	"github.com/fatihesergg/go_social/internal/imaging"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxAvatarSize = 2 << 20
+const maxAvatarDimension = 4096
+
+type ProfileController struct {
+	Storage *database.Storage
+	Blob    blob.Store
+}
+
+func NewProfileController(storage *database.Storage, blobStore blob.Store) *ProfileController {
+	return &ProfileController{
+		Storage: storage,
+		Blob:    blobStore,
+	}
+}
+
+// UpdateProfile godoc
+//
+//	@Summary		Update profile
+//	@Description	Update the display name, bio, website, location and privacy of the authenticated user. Only the fields in the body are changed, an empty string clears a field.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			profile	body		dto.UpdateProfileDTO	true	"Profile fields"
+//	@Success		200		{object}	util.SuccessResultResponse{result=model.User}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me [patch]
+func (pc ProfileController) UpdateProfile(c *gin.Context) {
+	var params dto.UpdateProfileDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	user, err := pc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	if params.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*params.DisplayName)
+	}
+	if params.Bio != nil {
+		user.Bio = strings.TrimSpace(*params.Bio)
+	}
+	if params.Location != nil {
+		user.Location = strings.TrimSpace(*params.Location)
+	}
+	wasPrivate := user.IsPrivate
+	if params.IsPrivate != nil {
+		user.IsPrivate = *params.IsPrivate
+	}
+	if params.Website != nil {
+		website := strings.TrimSpace(*params.Website)
+		if website != "" && !isWebsite(website) {
+			c.JSON(400, util.ErrorResponse{Error: util.InvalidWebsiteError})
+			return
+		}
+		user.Website = website
+	}
+	for _, field := range []string{user.DisplayName, user.Bio, user.Location} {
+		if strings.ContainsFunc(field, isControl) || !utf8.ValidString(field) {
+			c.JSON(400, util.ErrorResponse{Error: util.BadRequestError})
+			return
+		}
+	}
+
+	if err := pc.Storage.UserStore.UpdateProfile(user); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	// Pending requests are approved once the account is public again.
+	if wasPrivate && !user.IsPrivate {
+		if err := pc.Storage.FollowStore.ApproveAllFollowRequests(user.ID); err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Profile updated successfully", Result: user})
+}
+
+// UploadAvatar godoc
+//
+//	@Summary		Upload avatar
+//	@Description	Replace the avatar of the authenticated user with a JPEG, PNG or GIF image of at most 2 MB. The image is encoded again, so EXIF data like the GPS position is removed.
+//	@Tags			Users
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			avatar	formData	file	true	"Avatar image"
+//	@Success		200		{object}	util.SuccessResultResponse{result=model.User}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		413		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me/avatar [post]
+func (pc ProfileController) UploadAvatar(c *gin.Context) {
+	// Leave room for the multipart headers around the file.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAvatarSize+1<<20)
+	file, _, err := c.Request.FormFile("avatar")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			c.JSON(413, util.ErrorResponse{Error: util.FileTooLargeError})
+			return
+		}
+		c.JSON(400, util.ErrorResponse{Error: util.FileRequiredError})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarSize+1))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.FileRequiredError})
+		return
+	}
+	if len(data) > maxAvatarSize {
+		c.JSON(413, util.ErrorResponse{Error: util.FileTooLargeError})
+		return
+	}
+
+	// The image is encoded again so only pixels are stored, not the EXIF
+	// data or anything else that came along with the upload.
+	processed, err := imaging.Process(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrInvalidImage):
+			c.JSON(400, util.ErrorResponse{Error: util.InvalidImageError})
+		case errors.Is(err, imaging.ErrImageTooLarge):
+			c.JSON(400, util.ErrorResponse{Error: util.ImageDimensionsTooLargeError})
+		default:
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		}
+		return
+	}
+	if processed.Width > maxAvatarDimension || processed.Height > maxAvatarDimension {
+		c.JSON(400, util.ErrorResponse{Error: util.ImageDimensionsTooLargeError})
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	user, err := pc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	name, err := util.GenerateRandomToken()
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	key := fmt.Sprintf("avatars/%s/%s%s", user.ID, name, processed.Extension)
+	if err := pc.Blob.Put(c.Request.Context(), key, bytes.NewReader(processed.Data)); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	avatar := pc.Blob.URL(key)
+	if err := pc.Storage.UserStore.UpdateAvatar(user.ID, &avatar); err != nil {
+		_ = pc.Blob.Delete(c.Request.Context(), key)
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	pc.deleteAvatar(c, user.Avatar)
+	user.Avatar = &avatar
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Avatar updated successfully", Result: user})
+}
+
+// DeleteAvatar godoc
+//
+//	@Summary		Remove avatar
+//	@Description	Remove the avatar of the authenticated user
+//	@Tags			Users
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me/avatar [delete]
+func (pc ProfileController) DeleteAvatar(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	user, err := pc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	if err := pc.Storage.UserStore.UpdateAvatar(user.ID, nil); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	pc.deleteAvatar(c, user.Avatar)
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Avatar removed successfully"})
+}
+
+// deleteAvatar removes an old avatar from the blob store. Avatars set to an
+// external URL at signup are left alone.
+func (pc ProfileController) deleteAvatar(c *gin.Context, avatar *string) {
+	if avatar == nil {
+		return
+	}
+	key, ok := pc.Blob.Key(*avatar)
+	if !ok {
+		return
+	}
+	if err := pc.Blob.Delete(c.Request.Context(), key); err != nil {
+		log.Printf("error deleting avatar %s: %v", key, err)
+	}
+}
+
+func isWebsite(website string) bool {
+	u, err := url.Parse(website)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
@@ -52,8 +52,12 @@ func (rc *ReplyController) GetCommentReplies(c *gin.Context) {
 		c.JSON(404, util.SuccessMessageResponse{Message: "Comment not found"})
 		return
 	}
+	if !canSeeContent(c, &rc.Storage, existComment.PostID, existComment.UserID, util.CommentNotFoundError) {
+		return
+	}
 
-	replies, err := rc.Storage.ReplyStore.GetRepliesByCommentID(existComment.ID)
+	userID := c.MustGet("userID").(uuid.UUID)
+	replies, err := rc.Storage.ReplyStore.GetRepliesByCommentID(existComment.ID, userID)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
@@ -79,6 +83,8 @@ func (rc *ReplyController) GetCommentReplies(c *gin.Context) {
 //	@Param			id	path		string	true	"Comment ID"
 //	@Success		201	{object}	util.SuccessMessageResponse
 //	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		403	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
 //	@Failure		500	{object}	util.ErrorResponse
 //	@Router			/comments/{id}/reply [POST]
 //	@Security		Bearer
@@ -112,6 +118,12 @@ func (rc *ReplyController) ReplyComment(c *gin.Context) {
 		c.JSON(400, util.ErrorResponse{Error: util.CommentNotFoundError})
 		return
 	}
+	if !authorizeInteraction(c, &rc.Storage, comment.UserID) {
+		return
+	}
+	if !canSeeContent(c, &rc.Storage, comment.PostID, comment.UserID, util.CommentNotFoundError) {
+		return
+	}
 
 	userID := c.MustGet("userID").(uuid.UUID)
 
@@ -173,15 +185,17 @@ func (rc *ReplyController) UpdateReply(c *gin.Context) {
 		return
 	}
 
-	userID := c.MustGet("userID").(uuid.UUID)
-	if existReply.UserID != userID {
-		c.JSON(403, util.ErrorResponse{Error: util.InvalidPermissionError})
+	moderated, ok := authorizeContentChange(c, &rc.Storage, existReply.UserID)
+	if !ok {
+		return
+	}
+	if moderated && !recordModerationAction(c, &rc.Storage, model.ModerationActionUpdate, model.ModerationTargetReply, existReply.ID, existReply.UserID, existReply.Message) {
 		return
 	}
 
 	existReply.Message = params.Message
 
-	err = rc.Storage.ReplyStore.UpdateReply(existReply)
+	err = rc.Storage.ReplyStore.UpdateReply(existReply, moderated)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
@@ -227,10 +241,11 @@ func (rc *ReplyController) DeleteReply(c *gin.Context) {
 		return
 	}
 
-	userID := c.MustGet("userID").(uuid.UUID)
-
-	if existReply.UserID != userID {
-		c.JSON(403, util.ErrorResponse{Error: util.InvalidPermissionError})
+	moderated, ok := authorizeContentChange(c, &rc.Storage, existReply.UserID)
+	if !ok {
+		return
+	}
+	if moderated && !recordModerationAction(c, &rc.Storage, model.ModerationActionDelete, model.ModerationTargetReply, existReply.ID, existReply.UserID, existReply.Message) {
 		return
 	}
 
@@ -242,3 +257,54 @@ func (rc *ReplyController) DeleteReply(c *gin.Context) {
 
 	c.JSON(200, util.SuccessMessageResponse{Message: "Reply deleted successfully"})
 }
+
+// GetReplyRevisions godoc
+//
+//	@Summary		Get reply revisions
+//	@Description	List the earlier versions of an edited reply, newest first. The current version is the reply itself. A version a moderator changed is hidden.
+//	@Tags			Reply
+//	@Produce		json
+//	@Param			id	path		string	true	"Reply ID"
+//	@Success		200	{object}	util.SuccessResultResponse{result=[]model.Revision}
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/replies/{id}/revisions [get]
+func (rc *ReplyController) GetReplyRevisions(c *gin.Context) {
+	replyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+	reply, err := rc.Storage.ReplyStore.GetReplyByID(replyID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if reply == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.ReplyNotFoundError})
+		return
+	}
+	comment, err := rc.Storage.CommentStore.GetCommentByID(reply.CommentID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if comment == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.ReplyNotFoundError})
+		return
+	}
+	// Replies are hidden together with the comment they reply to.
+	if !canSeeContent(c, &rc.Storage, comment.PostID, comment.UserID, util.ReplyNotFoundError) || !canSeeContent(c, &rc.Storage, comment.PostID, reply.UserID, util.ReplyNotFoundError) {
+		return
+	}
+
+	revisions, err := rc.Storage.ReplyStore.GetReplyRevisions(reply.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Reply revisions fetched successfully", Result: revisions})
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RepostController struct {
+	Storage *database.Storage
+}
+
+func NewRepostController(storage *database.Storage) *RepostController {
+	return &RepostController{
+		Storage: storage,
+	}
+}
+
+// Repost godoc
+//
+//	@Summary		Repost a post
+//	@Description	Share a post with your followers. The post shows up in their feed attributed to you. A post can be reposted once.
+//	@Tags			Reposts
+//	@Produce		json
+//	@Param			id	path		string	true	"Post ID"
+//	@Success		201	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		403	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/posts/{id}/repost [post]
+func (rc RepostController) Repost(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+	if _, ok := shareablePost(c, rc.Storage, postID); !ok {
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	err = rc.Storage.RepostStore.Repost(&model.Repost{PostID: postID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, database.ErrAlreadyReposted) {
+			c.JSON(400, util.ErrorResponse{Error: util.PostAlreadyRepostedError})
+			return
+		}
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(201, util.SuccessMessageResponse{Message: "Post reposted successfully"})
+}
+
+// Unrepost godoc
+//
+//	@Summary		Undo a repost
+//	@Description	Remove your repost of a post
+//	@Tags			Reposts
+//	@Produce		json
+//	@Param			id	path		string	true	"Post ID"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/posts/{id}/repost [delete]
+func (rc RepostController) Unrepost(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	err = rc.Storage.RepostStore.Unrepost(postID, userID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotReposted) {
+			c.JSON(400, util.ErrorResponse{Error: util.PostNotRepostedError})
+			return
+		}
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(200, util.SuccessMessageResponse{Message: "Repost removed successfully"})
+}
+
+// shareablePost returns the post with the given ID if the authenticated user
+// can repost or quote it. Only published posts the user can see can be
+// shared, and posts of private accounts only by the account itself, since
+// sharing them would show them to people who don't follow it. Otherwise the
+// error response is written and false is returned.
+func shareablePost(c *gin.Context, storage *database.Storage, postID uuid.UUID) (*model.Post, bool) {
+	post, err := storage.PostStore.GetPostByID(postID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return nil, false
+	}
+	if post == nil || !post.IsPublished() {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return nil, false
+	}
+	if !authorizeInteraction(c, storage, post.UserID) {
+		return nil, false
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	visible, err := storage.PostStore.IsContentVisible(post.ID, post.UserID, userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return nil, false
+	}
+	if !visible {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return nil, false
+	}
+	if post.UserID == userID {
+		return post, true
+	}
+
+	author, err := storage.UserStore.GetUserByID(post.UserID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return nil, false
+	}
+	if author == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.PostNotFoundError})
+		return nil, false
+	}
+	if author.IsPrivate {
+		c.JSON(403, util.ErrorResponse{Error: util.PrivatePostShareError})
+		return nil, false
+	}
+	return post, true
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/dto"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionController struct {
+	Storage *database.Storage
+}
+
+func NewSessionController(storage *database.Storage) *SessionController {
+	return &SessionController{
+		Storage: storage,
+	}
+}
+
+// GetSessions godoc
+//
+//	@Summary		List active sessions
+//	@Description	List the devices the authenticated user is signed in on
+//	@Tags			Sessions
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessResultResponse{result=[]dto.SessionResponse}
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/sessions [get]
+func (sc SessionController) GetSessions(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	sessionID := c.MustGet("sessionID").(uuid.UUID)
+
+	sessions, err := sc.Storage.SessionStore.GetActiveSessionsByUserID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	result := dto.NewSessionResponse(sessions, sessionID)
+	c.JSON(200, util.SuccessResultResponse{Message: "Sessions fetched successfully", Result: result})
+}
+
+// RevokeSession godoc
+//
+//	@Summary		Revoke a session
+//	@Description	Sign out one of the authenticated user's sessions
+//	@Tags			Sessions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"Session ID"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/sessions/{id} [delete]
+func (sc SessionController) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidIDFormatError})
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	session, err := sc.Storage.SessionStore.GetSessionByID(sessionID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		c.JSON(404, util.ErrorResponse{Error: util.SessionNotFoundError})
+		return
+	}
+
+	if err := sc.Storage.SessionStore.RevokeSession(session.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Session revoked successfully"})
+}
+
+// RevokeOtherSessions godoc
+//
+//	@Summary		Revoke other sessions
+//	@Description	Sign out every session of the authenticated user except the current one
+//	@Tags			Sessions
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/sessions [delete]
+func (sc SessionController) RevokeOtherSessions(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	sessionID := c.MustGet("sessionID").(uuid.UUID)
+
+	if err := sc.Storage.SessionStore.RevokeOtherSessions(userID, sessionID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Other sessions revoked successfully"})
+}
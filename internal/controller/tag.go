@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/dto"
+	"github.com/fatihesergg/go_social/internal/hashtag"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TagController struct {
+	Storage *database.Storage
+}
+
+func NewTagController(storage *database.Storage) *TagController {
+	return &TagController{
+		Storage: storage,
+	}
+}
+
+// GetPostsByTag godoc
+//
+//	@Summary		Get posts by hashtag
+//	@Description	List the posts with a hashtag, newest first. The tag is matched case insensitively, with or without the leading #.
+//	@Tags			Tags
+//	@Produce		json
+//	@Param			tag		path		string	true	"Hashtag"
+//	@Param			limit	query		int		false	"Limit"		default(20)
+//	@Param			offset	query		int		false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]dto.AllPostResponse}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/tags/{tag} [get]
+func (tc TagController) GetPostsByTag(c *gin.Context) {
+	tag, ok := hashtag.Normalize(c.Param("tag"))
+	if !ok {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidTagError})
+		return
+	}
+
+	pagination := database.NewPagination(c)
+	userID := c.MustGet("userID").(uuid.UUID)
+	posts, err := tc.Storage.PostStore.GetPosts(pagination, database.Search{Tag: tag}, userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Posts fetched successfully", Result: dto.NewAllPostResponse(posts)})
+}
+
+// GetTrendingTags godoc
+//
+//	@Summary		Get trending hashtags
+//	@Description	List the hashtags used by the most people in public posts and comments during the last hours
+//	@Tags			Tags
+//	@Produce		json
+//	@Param			hours	query		int	false	"Time window in hours"	default(24)	minimum(1)	maximum(168)
+//	@Param			limit	query		int	false	"Limit"					default(20)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.TrendingTag}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/tags [get]
+func (tc TagController) GetTrendingTags(c *gin.Context) {
+	window := util.DefaultTrendingWindow
+	if value := c.Query("hours"); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil || hours < 1 || time.Duration(hours)*time.Hour > util.MaxTrendingWindow {
+			c.JSON(400, util.ErrorResponse{Error: util.InvalidTrendingWindowError})
+			return
+		}
+		window = time.Duration(hours) * time.Hour
+	}
+
+	pagination := database.NewPagination(c)
+	tags, err := tc.Storage.TagStore.GetTrendingTags(time.Now().Add(-window), pagination.Limit)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Trending tags fetched successfully", Result: tags})
+}
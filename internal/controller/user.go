@@ -1,25 +1,66 @@
 package controller
 
 import (
+	"context"
+	"database/sql"
+	"log"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/fatihesergg/go_social/internal/database"
 	"github.com/fatihesergg/go_social/internal/dto"
+	"github.com/fatihesergg/go_social/internal/mailer"
 	"github.com/fatihesergg/go_social/internal/model"
 	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/fatihesergg/go_social/internal/worker"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// dummyPasswordHash is compared against when a login uses an unknown email.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("go_social"), bcrypt.DefaultCost)
+
 type UserController struct {
 	Storage *database.Storage
+	KeyRing *util.KeyRing
+	Mailer  mailer.Mailer
+	// PasswordResets sends the password reset emails.
+	PasswordResets *worker.PasswordResetMailer
+	AppURL         string
+	// AccountGracePeriod is how long a deleted account can be restored by
+	// logging in before it is purged.
+	AccountGracePeriod time.Duration
 }
 
-func NewUserController(storage *database.Storage) *UserController {
+func NewUserController(storage *database.Storage, keyRing *util.KeyRing, mailer mailer.Mailer, passwordResets *worker.PasswordResetMailer, appURL string, accountGracePeriod time.Duration) *UserController {
 	return &UserController{
-		Storage: storage,
+		Storage:            storage,
+		KeyRing:            keyRing,
+		Mailer:             mailer,
+		PasswordResets:     passwordResets,
+		AppURL:             appURL,
+		AccountGracePeriod: accountGracePeriod,
 	}
 }
 
+// GetUserByID godoc
+//
+//	@Summary		Get user by ID
+//	@Description	Get the profile of a user with their follower, following and post counts and their relationship to the authenticated user
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id	path		string	true	"User ID"
+//	@Success		200	{object}	util.SuccessResultResponse{result=model.Profile}
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id} [get]
 func (uc UserController) GetUserByID(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -42,8 +83,21 @@ func (uc UserController) GetUserByID(c *gin.Context) {
 		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
 		return
 	}
+	if !uc.canSeeUser(c, user) {
+		return
+	}
+
+	profile, err := uc.Storage.UserStore.GetProfile(user.ID, c.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if profile == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
 
-	c.JSON(200, gin.H{"result": user})
+	c.JSON(200, util.SuccessResultResponse{Message: "User fetched successfully", Result: profile})
 }
 
 // Signup godoc
@@ -69,7 +123,7 @@ func (uc UserController) Signup(c *gin.Context) {
 	user := &model.User{
 		Name:     params.Name,
 		LastName: params.LastName,
-		Email:    params.Email,
+		Email:    util.NormalizeEmail(params.Email),
 		Avatar:   params.Avatar,
 		Username: params.Username,
 		Password: params.Password,
@@ -85,14 +139,14 @@ func (uc UserController) Signup(c *gin.Context) {
 
 		return
 	}
-	existUsername, err := uc.Storage.UserStore.GetUserByUsername(user.Username)
+	usernameTaken, err := uc.isUsernameTaken(user.Username)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 
 		return
 	}
-	if existUsername != nil {
-		c.JSON(400, util.ErrorResponse{Error: "Username already exists"})
+	if usernameTaken {
+		c.JSON(400, util.ErrorResponse{Error: util.UsernameTakenError})
 		return
 	}
 
@@ -109,358 +163,605 @@ func (uc UserController) Signup(c *gin.Context) {
 		return
 	}
 
-	c.JSON(201, util.SuccessResultResponse{Message: "User registered successfully", Result: user})
+	// The account is created either way, the user can ask for a new email.
+	if err := uc.sendVerificationEmail(c.Request.Context(), user); err != nil {
+		log.Printf("error sending verification email to user %s: %v", user.ID, err)
+	}
+
+	c.JSON(201, util.SuccessResultResponse{Message: "User registered successfully. Please check your email to verify your account", Result: user})
 }
 
-// Login godoc
+// VerifyEmail godoc
 //
-//	@Summary		User login
-//	@Description	Authenticate a user and return a JWT token
+//	@Summary		Verify email address
+//	@Description	Confirm the email address of an account with the token sent by email
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
-//	@Param			credentials	body		dto.LoginUserDTO				true	"User login credentials"
-//	@Success		200			{object}	util.SuccessMessageResponse{result=string}
-//	@Failure		400			{object}	util.ErrorResponse{error=string}
-//	@Failure		401			{object}	util.ErrorResponse{error=string}
-//	@Failure		404			{object}	util.ErrorResponse{error=string}
-//	@Failure		500			{object}	util.ErrorResponse{error=string}
-//	@Router			/login [post]
-func (uc UserController) Login(c *gin.Context) {
-	var params dto.LoginUserDTO
-
-	if err := c.ShouldBindJSON(&params); err != nil {
-		util.HandleBindError(c, err)
+//	@Param			token	query		string	true	"Verification token"
+//	@Success		200		{object}	util.SuccessMessageResponse
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Router			/verify_email [get]
+func (uc UserController) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidVerificationTokenError})
 		return
 	}
 
-	user, err := uc.Storage.UserStore.GetUserByEmail(params.Email)
+	existToken, err := uc.Storage.UserTokenStore.GetUserTokenByHash(util.HashToken(token), model.UserTokenPurposeEmailVerification)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-	if user == nil {
-		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+	if existToken == nil || existToken.UsedAt != nil || existToken.ExpiresAt.Before(time.Now()) {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidVerificationTokenError})
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.Password))
+	used, err := uc.Storage.UserTokenStore.UseUserToken(existToken.ID)
 	if err != nil {
-		c.JSON(401, util.ErrorResponse{Error: "Invalid credentials"})
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !used {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidVerificationTokenError})
 		return
 	}
 
-	token, err := util.CreateJsonWebToken(user.ID)
-	if err != nil {
+	if err := uc.Storage.UserStore.VerifyEmail(existToken.UserID); err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
 
-	c.JSON(200, util.SuccessResultResponse{Message: "Login successful", Result: token})
+	c.JSON(200, util.SuccessMessageResponse{Message: "Email verified successfully"})
 }
 
-// GetMe godoc
+// ResendVerificationEmail godoc
 //
-//	@Summary		Get current user
-//	@Description	Retrieve the authenticated user's details
+//	@Summary		Resend verification email
+//	@Description	Send a new verification email to the authenticated user. Previously sent links stop working.
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	util.SuccessResultResponse{result=model.User}
-//	@Failure		401	{object}	util.ErrorResponse	"Unauthorized: Invalid or missing token"
-//	@Failure		404	{object}	util.ErrorResponse	"Not Found: User not found"
-//	@Failure		500	{object}	util.ErrorResponse	"Internal Server Error"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
 //	@Security		Bearer
-//	@Router			/me [get]
-func (uc UserController) GetMe(c *gin.Context) {
-	id := c.MustGet("userID").(uuid.UUID)
-	user, err := uc.Storage.UserStore.GetUserByID(id)
-	if err != nil {
+//	@Router			/users/verify_email/resend [post]
+func (uc UserController) ResendVerificationEmail(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
 
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-
 	if user == nil {
 		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
 		return
 	}
-
-	c.JSON(200, util.SuccessResultResponse{Message: "User fetched successfully", Result: user})
-}
-
-// GetFollowerByUserID godoc
-//
-//	@Summary		Get followers of a user by user ID
-//	@Description	Retrieve a list of followers for a specific user
-//	@Tags			Users
-//	@Accept			json
-//	@Produce		json
-//	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}		util.SuccessResultResponse{result=[]model.Follow}
-//	@Failure		400	{object}	util.ErrorResponse
-//	@Failure		404	{object}	util.ErrorResponse
-//	@Failure		500	{object}	util.ErrorResponse
-//	@Security		Bearer
-//	@Router			/users/{id}/followers [get]
-func (uc UserController) GetFollowerByUserID(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+	if user.EmailVerifiedAt != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Email is already verified"})
 		return
 	}
-	userID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+
+	if err := uc.sendVerificationEmail(c.Request.Context(), user); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Error sending verification email"})
 		return
 	}
 
-	followers, err := uc.Storage.FollowStore.GetFollowerByUserID(userID)
+	c.JSON(200, util.SuccessMessageResponse{Message: "Verification email sent"})
+}
+
+// sendVerificationEmail replaces any pending verification token of the user
+// with a new one and emails it.
+func (uc UserController) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	token, err := util.GenerateRandomToken()
 	if err != nil {
-		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
-		return
+		return err
 	}
 
-	if len(followers) == 0 {
-		c.JSON(404, util.ErrorResponse{Error: "No followers found"})
-		return
+	if err := uc.Storage.UserTokenStore.DeleteUserTokens(user.ID, model.UserTokenPurposeEmailVerification); err != nil {
+		return err
+	}
+	err = uc.Storage.UserTokenStore.CreateUserToken(&model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.UserTokenPurposeEmailVerification,
+		TokenHash: util.HashToken(token),
+		ExpiresAt: time.Now().Add(util.EmailVerificationTokenTTL),
+	})
+	if err != nil {
+		return err
 	}
 
-	c.JSON(200, util.SuccessResultResponse{Message: "Followers fetched successfully", Result: followers})
+	link := uc.AppURL + "/api/v1/verify_email?token=" + url.QueryEscape(token)
+	return uc.Mailer.Send(ctx, mailer.NewVerificationMessage(user.Email, user.Name, link))
 }
 
-// GetFollowingByUserID godoc
+// Login godoc
 //
-//	@Summary		Get followings of a user by user ID
-//	@Description	Retrieve a list of users that a specific user is following
+//	@Summary		User login
+//	@Description	Authenticate a user and return an access token and a refresh token. If two factor authentication is enabled a challenge token is returned instead, which has to be exchanged at /login/2fa. Repeated failures slow down and eventually lock logins for the account and the IP address.
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}		util.SuccessResultResponse{result=[]model.Follow}
-//	@Failure		400	{object}	util.ErrorResponse
-//	@Failure		404	{object}	util.ErrorResponse
-//	@Failure		500	{object}	util.ErrorResponse
-//	@Security		Bearer
-//	@Router			/users/{id}/following [get]
-func (uc UserController) GetFollowingByUserID(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+//	@Param			credentials	body		dto.LoginUserDTO				true	"User login credentials"
+//	@Success		200			{object}	util.SuccessResultResponse{result=dto.TokenResponse}
+//	@Success		202			{object}	util.SuccessResultResponse{result=dto.TwoFactorChallengeResponse}
+//	@Failure		400			{object}	util.ErrorResponse{error=string}
+//	@Failure		401			{object}	util.ErrorResponse{error=string}
+//	@Failure		429			{object}	util.ErrorResponse{error=string}
+//	@Failure		500			{object}	util.ErrorResponse{error=string}
+//	@Router			/login [post]
+func (uc UserController) Login(c *gin.Context) {
+	var params dto.LoginUserDTO
+
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
 		return
 	}
 
-	userID, err := uuid.Parse(id)
+	email := util.NormalizeEmail(params.Email)
+	if uc.rejectLockedLogin(c, email) {
+		return
+	}
 
+	user, err := uc.Storage.UserStore.GetUserByEmail(email)
 	if err != nil {
-		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		// Unknown emails take as long and are counted the same as wrong
+		// passwords, so the response doesn't tell whether an account exists.
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(params.Password))
+		uc.failLogin(c, email, nil)
 		return
 	}
 
-	followings, err := uc.Storage.FollowStore.GetFollowingByUserID(userID)
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.Password))
 	if err != nil {
-		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		uc.failLogin(c, email, &user.ID)
 		return
 	}
 
-	if len(followings) == 0 {
-		c.JSON(404, util.ErrorResponse{Error: "No followings found"})
+	if user.TOTPEnabledAt != nil {
+		uc.sendTwoFactorChallenge(c, user)
 		return
 	}
 
-	c.JSON(200, util.SuccessResultResponse{Message: "User following fetched successfully", Result: followings})
+	if err := uc.Storage.LoginAttemptStore.ResetLoginAttempts(model.LoginAttemptScopeAccount, email); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	uc.startSession(c, user)
 }
 
-// FollowUser godoc
+// RefreshToken godoc
 //
-//	@Summary		Follow a user
-//	@Description	Follow a user by their ID
+//	@Summary		Refresh access token
+//	@Description	Exchange a refresh token for a new access token. The refresh token is rotated and the old one can not be used again.
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int															true	"User ID to follow"
-//	@Success		200	{object}	util.SuccessMessageResponse
-//	@Failure		400	{object}	util.ErrorResponse
-//	@Failure		500	{object}	util.ErrorResponse
-//	@Security		Bearer
-//	@Router			/users/{id}/follow [post]
-func (uc UserController) FollowUser(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+//	@Param			token	body		dto.RefreshTokenDTO	true	"Refresh token"
+//	@Success		200		{object}	util.SuccessResultResponse{result=dto.TokenResponse}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Router			/refresh [post]
+func (uc UserController) RefreshToken(c *gin.Context) {
+	var params dto.RefreshTokenDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
 		return
 	}
-	followUser, err := uuid.Parse(id)
+
+	existToken, err := uc.Storage.TokenStore.GetRefreshTokenByHash(util.HashToken(params.RefreshToken))
 	if err != nil {
-		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if existToken == nil || existToken.ExpiresAt.Before(time.Now()) {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidRefreshTokenError})
 		return
 	}
 
-	me := c.MustGet("userID").(uuid.UUID)
-
-	followings, err := uc.Storage.FollowStore.GetFollowingByUserID(me)
+	session, err := uc.Storage.SessionStore.GetSessionByID(existToken.SessionID)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-	isFollowing := false
-	for _, follow := range followings {
-		if follow.FollowID == followUser {
-			isFollowing = true
-		}
-	}
-
-	if isFollowing {
-		c.JSON(400, util.ErrorResponse{Error: "You are already following this user"})
+	if session == nil || session.RevokedAt != nil {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidRefreshTokenError})
 		return
 	}
 
-	err = uc.Storage.FollowStore.FollowUser(me, followUser)
-	if err != nil {
-		c.JSON(500, util.ErrorResponse{Error: "Error following user"})
+	if existToken.RevokedAt != nil {
+		// A rotated token is being replayed, the whole session is considered stolen.
+		if err := uc.Storage.SessionStore.RevokeSession(session.ID); err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidRefreshTokenError})
 		return
 	}
-	c.JSON(200, util.SuccessMessageResponse{Message: "Followed successfully"})
-}
 
-// UnfollowUser godoc
-//
-//	@Summary		Unfollow a user
-//	@Description	Unfollow a user by their ID
-//	@Tags			Users
-//	@Accept			json
-//	@Produce		json
-//	@Param			id	path		int															true	"User ID to unfollow"
-//	@Success		200	{object}	util.SuccessMessageResponse
-//	@Failure		400	{object}	util.ErrorResponse
-//	@Failure		500	{object}	util.ErrorResponse
-//	@Security		Bearer
-//	@Router			/users/{id}/unfollow [post]
-func (uc UserController) UnfollowUser(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+	accessToken, err := uc.KeyRing.CreateJsonWebToken(existToken.UserID, session.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-	unfUser, err := uuid.Parse(id)
+	refreshToken, err := util.GenerateRandomToken()
 	if err != nil {
-		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-	me := c.MustGet("userID").(uuid.UUID)
 
-	followings, err := uc.Storage.FollowStore.GetFollowingByUserID(me)
+	err = uc.Storage.TokenStore.RotateRefreshToken(existToken.ID, &model.RefreshToken{
+		UserID:    existToken.UserID,
+		SessionID: session.ID,
+		TokenHash: util.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(util.RefreshTokenTTL),
+	})
 	if err != nil {
+		if err == database.ErrRefreshTokenReused {
+			_ = uc.Storage.SessionStore.RevokeSession(session.ID)
+			c.JSON(401, util.ErrorResponse{Error: util.InvalidRefreshTokenError})
+			return
+		}
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
 
-	isFollowing := false
-	for _, follow := range followings {
-		if follow.FollowID == unfUser {
-			isFollowing = true
-		}
-	}
-	if !isFollowing {
-		c.JSON(400, util.ErrorResponse{Error: "You are not following this user"})
+	if err := uc.Storage.SessionStore.TouchSession(session.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
 
-	err = uc.Storage.FollowStore.UnFollowUser(me, unfUser)
-	if err != nil {
-		c.JSON(500, util.ErrorResponse{Error: "Error unfollowing user"})
-		return
-	} else {
-		c.JSON(200, util.SuccessMessageResponse{Message: "Unfollowed successfully"})
+	result := dto.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(util.AccessTokenTTL.Seconds()),
 	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Token refreshed successfully", Result: result})
 }
 
-// GetUsersPosts godoc
+// Logout godoc
 //
-//	@Summary		Get posts of a user by user ID
-//	@Description	Retrieve posts made by a specific user, only if the requester is following that user
+//	@Summary		User logout
+//	@Description	Revoke the current access token and the session it belongs to
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		int		true	"User ID"
-//	@Param			limit	query		int		false	"Limit"		default(10)
-//	@Param			offset	query		int		false	"Offset"	default(0)
-//	@Param			search	query		string	false	"Search query"
-//	@Success		200		{object}		util.SuccessResultResponse{result=[]dto.AllPostResponse}
-//	@Failure		400		{object}	util.ErrorResponse
-//	@Failure		403		{object}	util.ErrorResponse
-//	@Failure		404		{object}	util.ErrorResponse
-//	@Failure		500		{object}	util.ErrorResponse
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
 //	@Security		Bearer
-//	@Router			/users/{id}/posts [get]
-func (uc UserController) GetUsersPosts(c *gin.Context) {
-	id := c.Param("id")
-
-	userID, err := uuid.Parse(id)
+//	@Router			/logout [post]
+func (uc UserController) Logout(c *gin.Context) {
+	sessionID := c.MustGet("sessionID").(uuid.UUID)
+	tokenID := c.MustGet("tokenID").(uuid.UUID)
+	expiresAt := c.MustGet("tokenExpiresAt").(time.Time)
 
-	if err != nil {
-		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+	if err := uc.Storage.TokenStore.RevokeAccessToken(tokenID, expiresAt); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
 
-	user, err := uc.Storage.UserStore.GetUserByID(userID)
-	if err != nil {
+	if err := uc.Storage.SessionStore.RevokeSession(sessionID); err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
 
-	if user == nil {
-		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
-		return
+	c.JSON(200, util.SuccessMessageResponse{Message: "Logged out successfully"})
+}
+
+// rejectLockedLogin responds with 429 if logins for the email or the client IP
+// are currently blocked.
+func (uc UserController) rejectLockedLogin(c *gin.Context, email string) bool {
+	var retryAfter time.Duration
+	for scope, subject := range map[string]string{model.LoginAttemptScopeAccount: email, model.LoginAttemptScopeIP: c.ClientIP()} {
+		attempt, err := uc.Storage.LoginAttemptStore.GetLoginAttempt(scope, subject)
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return true
+		}
+		if attempt != nil && attempt.LockedUntil != nil {
+			if wait := time.Until(*attempt.LockedUntil); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+	if retryAfter <= 0 {
+		return false
 	}
 
-	followers, err := uc.Storage.FollowStore.GetFollowerByUserID(user.ID)
-	if err != nil {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.JSON(429, util.ErrorResponse{Error: util.TooManyLoginAttemptsError})
+	return true
+}
+
+// failLogin counts a failed login for the email and the client IP and responds
+// with the same error whether the account exists or not.
+func (uc UserController) failLogin(c *gin.Context, email string, userID *uuid.UUID) {
+	if err := uc.recordFailedLogin(model.LoginAttemptScopeAccount, email, util.AccountLockoutPolicy, userID, c.ClientIP()); err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
-	pagination := database.NewPagination(c)
-	search := database.NewSearch(c)
-	for i := range followers {
-		//TODO:  Check if user owner the
-		followerID := followers[i].ID
-		if followerID == userID {
-			posts, err := uc.Storage.PostStore.GetPostsByUserID(user.ID, pagination, search)
-			if err != nil {
-				c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
-				return
-			}
-			if posts == nil {
-				c.JSON(404, util.ErrorResponse{Error: util.NoPostsFoundError})
-				return
-			}
-			result := dto.NewAllPostResponse(posts)
+	if err := uc.recordFailedLogin(model.LoginAttemptScopeIP, c.ClientIP(), util.IPLockoutPolicy, nil, c.ClientIP()); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	c.JSON(401, util.ErrorResponse{Error: util.InvalidCredentialsError})
+}
 
-			c.JSON(200, util.SuccessResultResponse{Message: "User posts fetched successfully", Result: result})
-			return
-		}
+func (uc UserController) recordFailedLogin(scope, subject string, policy util.LockoutPolicy, userID *uuid.UUID, ipAddress string) error {
+	now := time.Now()
+	attempt, err := uc.Storage.LoginAttemptStore.RecordFailedLogin(scope, subject, now, now.Add(-policy.Window))
+	if err != nil {
+		return err
 	}
 
-	c.JSON(403, util.ErrorResponse{Error: util.NotFollowingError})
+	backoff, lockout := policy.Backoff(attempt.FailedCount)
+	if backoff == 0 {
+		return nil
+	}
+	lockedUntil := now.Add(backoff)
+	if err := uc.Storage.LoginAttemptStore.LockLogin(scope, subject, lockedUntil); err != nil {
+		return err
+	}
+	if !lockout {
+		return nil
+	}
+
+	log.Printf("login locked for %s %s until %s after %d failed attempts", scope, subject, lockedUntil.Format(time.RFC3339), attempt.FailedCount)
+	return uc.Storage.LoginAttemptStore.CreateLoginLockout(&model.LoginLockout{
+		Scope:       scope,
+		Subject:     subject,
+		UserID:      userID,
+		IPAddress:   ipAddress,
+		FailedCount: attempt.FailedCount,
+		LockedUntil: lockedUntil,
+	})
 }
 
-// ResetPassword godoc
-//
-//	@Summary		Reset user password
-//	@Description	Allow authenticated users to reset their password by providing the old and new passwords
+// sendTwoFactorChallenge responds with a challenge token that has to be
+// exchanged at /login/2fa together with a second factor.
+func (uc UserController) sendTwoFactorChallenge(c *gin.Context, user *model.User) {
+	challengeToken, err := uc.KeyRing.CreateChallengeToken(user.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	result := dto.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+		ExpiresIn:         int(util.ChallengeTokenTTL.Seconds()),
+	}
+	c.JSON(202, util.SuccessResultResponse{Message: "Two factor authentication required", Result: result})
+}
+
+// startSession creates a session for a user that has been authenticated and
+// responds with its tokens.
+func (uc UserController) startSession(c *gin.Context, user *model.User) {
+	if user.DeactivatedAt != nil {
+		// Logging in to a deleted account restores it until it is purged.
+		if time.Since(*user.DeactivatedAt) > uc.AccountGracePeriod {
+			c.JSON(401, util.ErrorResponse{Error: util.InvalidCredentialsError})
+			return
+		}
+		if err := uc.Storage.UserStore.ReactivateUser(user.ID); err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+	}
+
+	session := &model.Session{
+		UserID:    user.ID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+	if err := uc.Storage.SessionStore.CreateSession(session); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	tokens, err := uc.issueTokens(user.ID, session.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Login successful", Result: tokens})
+}
+
+// issueTokens creates a new access token and a refresh token for the given
+// session.
+func (uc UserController) issueTokens(userID, sessionID uuid.UUID) (*dto.TokenResponse, error) {
+	accessToken, err := uc.KeyRing.CreateJsonWebToken(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := util.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.Storage.TokenStore.CreateRefreshToken(&model.RefreshToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: util.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(util.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(util.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// LoginTwoFactor godoc
+//
+//	@Summary		Complete a two factor login
+//	@Description	Exchange the challenge token returned by /login and a TOTP or recovery code for an access token and a refresh token
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
-//	@Param			passwords	body		dto.ResetUserPasswordDTO	true	"Old and new passwords"
-//	@Success		200			{object}	util.SuccessMessageResponse
+//	@Param			challenge	body		dto.LoginTwoFactorDTO	true	"Challenge token and code"
+//	@Success		200			{object}	util.SuccessResultResponse{result=dto.TokenResponse}
 //	@Failure		400			{object}	util.ErrorResponse
-//	@Failure		404			{object}	util.ErrorResponse
+//	@Failure		401			{object}	util.ErrorResponse
+//	@Failure		429			{object}	util.ErrorResponse
 //	@Failure		500			{object}	util.ErrorResponse
+//	@Router			/login/2fa [post]
+func (uc UserController) LoginTwoFactor(c *gin.Context) {
+	var params dto.LoginTwoFactorDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	claims, err := uc.KeyRing.ParseChallengeToken(params.ChallengeToken)
+	if err != nil {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidChallengeTokenError})
+		return
+	}
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidChallengeTokenError})
+		return
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidChallengeTokenError})
+		return
+	}
+
+	revoked, err := uc.Storage.TokenStore.IsAccessTokenRevoked(challengeID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if revoked {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidChallengeTokenError})
+		return
+	}
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil || user.TOTPEnabledAt == nil {
+		c.JSON(401, util.ErrorResponse{Error: util.InvalidChallengeTokenError})
+		return
+	}
+
+	email := util.NormalizeEmail(user.Email)
+	if uc.rejectLockedLogin(c, email) {
+		return
+	}
+
+	ok, err := uc.verifySecondFactor(user, params.Code)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !ok {
+		uc.failLogin(c, email, &user.ID)
+		return
+	}
+	if err := uc.Storage.LoginAttemptStore.ResetLoginAttempts(model.LoginAttemptScopeAccount, email); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	// The challenge is single use, a leaked one can't be exchanged again.
+	if err := uc.Storage.TokenStore.RevokeAccessToken(challengeID, claims.ExpiresAt.Time); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	uc.startSession(c, user)
+}
+
+// EnrollTwoFactor godoc
+//
+//	@Summary		Start two factor enrollment
+//	@Description	Generate a new TOTP secret for the authenticated user. Two factor authentication is enabled once a code is confirmed.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessResultResponse{result=dto.TwoFactorEnrollmentResponse}
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		409	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
 //	@Security		Bearer
-//	@Router			/users/reset_password [post]
-func (uc UserController) ResetPassword(c *gin.Context) {
+//	@Router			/users/2fa/enroll [post]
+func (uc UserController) EnrollTwoFactor(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
 
-	var params dto.ResetUserPasswordDTO
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(409, util.ErrorResponse{Error: util.TwoFactorAlreadyEnabledError})
+		return
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if err := uc.Storage.UserStore.SetTOTPSecret(user.ID, secret); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	result := dto.TwoFactorEnrollmentResponse{
+		Secret: secret,
+		URI:    util.TOTPURI(util.TOTPIssuer, user.Email, secret),
+	}
+	c.JSON(200, util.SuccessResultResponse{Message: "Scan the code with your authenticator app and confirm it with a code", Result: result})
+}
+
+// ConfirmTwoFactor godoc
+//
+//	@Summary		Confirm two factor enrollment
+//	@Description	Enable two factor authentication with a first code from the authenticator app. The recovery codes are only shown once.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		dto.TwoFactorCodeDTO	true	"TOTP code"
+//	@Success		200		{object}	util.SuccessResultResponse{result=dto.RecoveryCodesResponse}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		409		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/2fa/confirm [post]
+func (uc UserController) ConfirmTwoFactor(c *gin.Context) {
+	var params dto.TwoFactorCodeDTO
 	if err := c.ShouldBindJSON(&params); err != nil {
 		util.HandleBindError(c, err)
 		return
@@ -477,40 +778,1213 @@ func (uc UserController) ResetPassword(c *gin.Context) {
 		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
 		return
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.OldPassword)); err != nil {
-		c.JSON(400, util.ErrorResponse{Error: "Invalid Credentials"})
+	if user.TOTPEnabledAt != nil {
+		c.JSON(409, util.ErrorResponse{Error: util.TwoFactorAlreadyEnabledError})
 		return
 	}
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(params.NewPassword), bcrypt.DefaultCost)
+	if user.TOTPSecret == nil {
+		c.JSON(400, util.ErrorResponse{Error: util.TwoFactorNotEnrolledError})
+		return
+	}
+
+	step, ok := util.ValidateTOTP(*user.TOTPSecret, params.Code, time.Now())
+	if !ok {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidTwoFactorCodeError})
+		return
+	}
+	used, err := uc.Storage.UserStore.UseTOTPStep(user.ID, step)
 	if err != nil {
-		c.JSON(500, util.ErrorResponse{Error: "Something went wrong"})
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !used {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidTwoFactorCodeError})
 		return
 	}
-	user.Password = string(hashedPass)
 
-	err = uc.Storage.UserStore.UpdateUser(user)
+	recoveryCodes, err := util.GenerateRecoveryCodes(util.RecoveryCodeCount)
 	if err != nil {
-		c.JSON(500, util.ErrorResponse{Error: "Error updating password"})
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	codeHashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		codeHashes = append(codeHashes, util.HashToken(util.NormalizeRecoveryCode(code)))
+	}
+	if err := uc.Storage.RecoveryCodeStore.ReplaceRecoveryCodes(user.ID, codeHashes); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if err := uc.Storage.UserStore.EnableTOTP(user.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
 
-	c.JSON(200, util.SuccessMessageResponse{Message: "Password updated successfully"})
+	c.JSON(200, util.SuccessResultResponse{Message: "Two factor authentication enabled. Store the recovery codes in a safe place", Result: dto.RecoveryCodesResponse{RecoveryCodes: recoveryCodes}})
 }
 
-func (uc UserController) SearchUserByUsername(c *gin.Context) {
-	username := c.Param("username")
+// DisableTwoFactor godoc
+//
+//	@Summary		Disable two factor authentication
+//	@Description	Disable two factor authentication with the password and a TOTP or recovery code
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			credentials	body		dto.DisableTwoFactorDTO	true	"Password and code"
+//	@Success		200			{object}	util.SuccessMessageResponse
+//	@Failure		400			{object}	util.ErrorResponse
+//	@Failure		401			{object}	util.ErrorResponse
+//	@Failure		404			{object}	util.ErrorResponse
+//	@Failure		500			{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/2fa/disable [post]
+func (uc UserController) DisableTwoFactor(c *gin.Context) {
+	var params dto.DisableTwoFactorDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
 
-	users, err := uc.Storage.UserStore.GetUsersByUsername(username)
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
 	if err != nil {
 		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
 		return
 	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(400, util.ErrorResponse{Error: util.TwoFactorNotEnabledError})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.Password)); err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidCredentialsError})
+		return
+	}
 
-	if users == nil {
-		c.JSON(200, util.SuccessMessageResponse{Message: "No users found"})
+	ok, err := uc.verifySecondFactor(user, params.Code)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !ok {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidTwoFactorCodeError})
 		return
 	}
 
-	c.JSON(200, util.SuccessResultResponse{Message: "Users fetched successfully", Result: users})
+	if err := uc.Storage.UserStore.DisableTOTP(user.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if err := uc.Storage.RecoveryCodeStore.DeleteRecoveryCodes(user.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Two factor authentication disabled"})
+}
+
+// verifySecondFactor checks a TOTP code or, if the code doesn't look like one,
+// a recovery code. Both are single use.
+func (uc UserController) verifySecondFactor(user *model.User, code string) (bool, error) {
+	if user.TOTPSecret == nil {
+		return false, nil
+	}
+
+	if step, ok := util.ValidateTOTP(*user.TOTPSecret, code, time.Now()); ok {
+		return uc.Storage.UserStore.UseTOTPStep(user.ID, step)
+	}
+
+	return uc.Storage.RecoveryCodeStore.UseRecoveryCode(user.ID, util.HashToken(util.NormalizeRecoveryCode(code)))
+}
+
+// GetMe godoc
+//
+//	@Summary		Get current user
+//	@Description	Retrieve the authenticated user's details
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessResultResponse{result=model.User}
+//	@Failure		401	{object}	util.ErrorResponse	"Unauthorized: Invalid or missing token"
+//	@Failure		404	{object}	util.ErrorResponse	"Not Found: User not found"
+//	@Failure		500	{object}	util.ErrorResponse	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/me [get]
+func (uc UserController) GetMe(c *gin.Context) {
+	id := c.MustGet("userID").(uuid.UUID)
+	user, err := uc.Storage.UserStore.GetUserByID(id)
+	if err != nil {
+
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "User fetched successfully", Result: user})
+}
+
+// GetFollowerByUserID godoc
+//
+//	@Summary		Get followers of a user by user ID
+//	@Description	Retrieve the followers of a specific user, newest first, with a summary of each follower and whether the authenticated user follows them
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"User ID"
+//	@Param			limit	query		int		false	"Limit"		default(20)
+//	@Param			offset	query		int		false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.Follow}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/followers [get]
+func (uc UserController) GetFollowerByUserID(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+		return
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if !uc.canViewUser(c, user) {
+		return
+	}
+
+	me := c.MustGet("userID").(uuid.UUID)
+	followers, err := uc.Storage.FollowStore.GetFollowerByUserID(userID, me, database.NewPagination(c))
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Followers fetched successfully", Result: followers})
+}
+
+// GetFollowingByUserID godoc
+//
+//	@Summary		Get followings of a user by user ID
+//	@Description	Retrieve the users that a specific user is following, newest first, with a summary of each user and whether the authenticated user follows them
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"User ID"
+//	@Param			limit	query		int		false	"Limit"		default(20)
+//	@Param			offset	query		int		false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.Follow}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/following [get]
+func (uc UserController) GetFollowingByUserID(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+		return
+	}
+
+	userID, err := uuid.Parse(id)
+
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if !uc.canViewUser(c, user) {
+		return
+	}
+
+	me := c.MustGet("userID").(uuid.UUID)
+	followings, err := uc.Storage.FollowStore.GetFollowingByUserID(userID, me, database.NewPagination(c))
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "User following fetched successfully", Result: followings})
+}
+
+// FollowUser godoc
+//
+//	@Summary		Follow a user
+//	@Description	Follow a user by their ID. Following a private account sends a follow request that the account has to approve.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int															true	"User ID to follow"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Success		202	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		403	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/follow [post]
+func (uc UserController) FollowUser(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+		return
+	}
+	followUser, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	me := c.MustGet("userID").(uuid.UUID)
+	if followUser == me {
+		c.JSON(400, util.ErrorResponse{Error: util.FollowYourselfError})
+		return
+	}
+
+	user, err := uc.Storage.UserStore.GetUserByID(followUser)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if !authorizeInteraction(c, uc.Storage, followUser) {
+		return
+	}
+
+	isFollowing, err := uc.Storage.FollowStore.IsFollowing(me, followUser)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	if isFollowing {
+		c.JSON(400, util.ErrorResponse{Error: "You are already following this user"})
+		return
+	}
+
+	if user.IsPrivate {
+		request, err := uc.Storage.FollowStore.GetFollowRequest(me, followUser)
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		if request != nil {
+			c.JSON(400, util.ErrorResponse{Error: util.FollowRequestAlreadySentError})
+			return
+		}
+		if err := uc.Storage.FollowStore.CreateFollowRequest(me, followUser); err != nil {
+			c.JSON(500, util.ErrorResponse{Error: "Error following user"})
+			return
+		}
+		c.JSON(202, util.SuccessMessageResponse{Message: "Follow request sent"})
+		return
+	}
+
+	err = uc.Storage.FollowStore.FollowUser(me, followUser)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Error following user"})
+		return
+	}
+	c.JSON(200, util.SuccessMessageResponse{Message: "Followed successfully"})
+}
+
+// UnfollowUser godoc
+//
+//	@Summary		Unfollow a user
+//	@Description	Unfollow a user by their ID, or cancel a pending follow request
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int															true	"User ID to unfollow"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/unfollow [post]
+func (uc UserController) UnfollowUser(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(400, util.ErrorResponse{Error: "ID is required"})
+		return
+	}
+	unfUser, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+
+	isFollowing, err := uc.Storage.FollowStore.IsFollowing(me, unfUser)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !isFollowing {
+		// Unfollowing a private account that hasn't approved the request yet
+		// cancels the request.
+		cancelled, err := uc.Storage.FollowStore.DeleteFollowRequest(me, unfUser)
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		if cancelled {
+			c.JSON(200, util.SuccessMessageResponse{Message: "Follow request cancelled"})
+			return
+		}
+		c.JSON(400, util.ErrorResponse{Error: "You are not following this user"})
+		return
+	}
+
+	err = uc.Storage.FollowStore.UnFollowUser(me, unfUser)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Error unfollowing user"})
+		return
+	} else {
+		c.JSON(200, util.SuccessMessageResponse{Message: "Unfollowed successfully"})
+	}
+}
+
+// GetUsersPosts godoc
+//
+//	@Summary		Get posts of a user by user ID
+//	@Description	Retrieve posts made by a specific user. The posts of a private account can only be seen by its followers.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Limit"		default(10)
+//	@Param			offset	query		int		false	"Offset"	default(0)
+//	@Param			search	query		string	false	"Search query"
+//	@Success		200		{object}		util.SuccessResultResponse{result=[]dto.AllPostResponse}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/posts [get]
+func (uc UserController) GetUsersPosts(c *gin.Context) {
+	id := c.Param("id")
+
+	userID, err := uuid.Parse(id)
+
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	if !uc.canViewUser(c, user) {
+		return
+	}
+
+	pagination := database.NewPagination(c)
+	search := database.NewSearch(c)
+	posts, err := uc.Storage.PostStore.GetPostsByUserID(user.ID, pagination, search)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(404, util.ErrorResponse{Error: util.NoPostsFoundError})
+			return
+		}
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	result := dto.NewAllPostResponse(posts)
+
+	c.JSON(200, util.SuccessResultResponse{Message: "User posts fetched successfully", Result: result})
+}
+
+// canSeeUser hides deactivated users and users that blocked the authenticated
+// user or were blocked by them, as if they didn't exist. It writes the response and returns false
+// if the user is hidden.
+func (uc UserController) canSeeUser(c *gin.Context, user *model.User) bool {
+	me := c.MustGet("userID").(uuid.UUID)
+	if user.ID == me {
+		return true
+	}
+	if user.DeactivatedAt != nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return false
+	}
+
+	blocked, err := uc.Storage.BlockStore.IsBlocked(me, user.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return false
+	}
+	if blocked {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return false
+	}
+	return true
+}
+
+// canViewUser checks whether the authenticated user can see the posts and
+// connections of user. Private accounts are only visible to their followers.
+// It writes the response and returns false if they can't.
+func (uc UserController) canViewUser(c *gin.Context, user *model.User) bool {
+	if !uc.canSeeUser(c, user) {
+		return false
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+	if !user.IsPrivate || user.ID == me {
+		return true
+	}
+
+	isFollowing, err := uc.Storage.FollowStore.IsFollowing(me, user.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return false
+	}
+	if !isFollowing {
+		c.JSON(403, util.ErrorResponse{Error: util.PrivateAccountError})
+		return false
+	}
+	return true
+}
+
+// ResetPassword godoc
+//
+//	@Summary		Reset user password
+//	@Description	Allow authenticated users to reset their password by providing the old and new passwords
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			passwords	body		dto.ResetUserPasswordDTO	true	"Old and new passwords"
+//	@Success		200			{object}	util.SuccessMessageResponse
+//	@Failure		400			{object}	util.ErrorResponse
+//	@Failure		404			{object}	util.ErrorResponse
+//	@Failure		500			{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/reset_password [post]
+func (uc UserController) ResetPassword(c *gin.Context) {
+
+	var params dto.ResetUserPasswordDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.OldPassword)); err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid Credentials"})
+		return
+	}
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(params.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Something went wrong"})
+		return
+	}
+	user.Password = string(hashedPass)
+
+	err = uc.Storage.UserStore.UpdateUser(user)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Error updating password"})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Password updated successfully"})
+}
+
+// ForgotPassword godoc
+//
+//	@Summary		Request a password reset
+//	@Description	Email a single use password reset token. The response is the same whether the email belongs to an account or not.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			email	body		dto.ForgotPasswordDTO	true	"Account email"
+//	@Success		200		{object}	util.SuccessMessageResponse
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Router			/password_reset/request [post]
+func (uc UserController) ForgotPassword(c *gin.Context) {
+	var params dto.ForgotPasswordDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	// Sending happens in the background so the response time doesn't tell
+	// whether the email exists.
+	if !uc.PasswordResets.Enqueue(util.NormalizeEmail(params.Email)) {
+		log.Printf("password reset queue is full, dropping a request")
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "If an account with this email exists, a password reset email has been sent"})
+}
+
+// ConfirmPasswordReset godoc
+//
+//	@Summary		Reset password with a token
+//	@Description	Set a new password with a password reset token. Every session of the user is signed out.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			reset	body		dto.ConfirmPasswordResetDTO	true	"Reset token and new password"
+//	@Success		200		{object}	util.SuccessMessageResponse
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Router			/password_reset/confirm [post]
+func (uc UserController) ConfirmPasswordReset(c *gin.Context) {
+	var params dto.ConfirmPasswordResetDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	existToken, err := uc.Storage.UserTokenStore.GetUserTokenByHash(util.HashToken(params.Token), model.UserTokenPurposePasswordReset)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if existToken == nil || existToken.UsedAt != nil || existToken.ExpiresAt.Before(time.Now()) {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidPasswordResetTokenError})
+		return
+	}
+
+	user, err := uc.Storage.UserStore.GetUserByID(existToken.UserID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidPasswordResetTokenError})
+		return
+	}
+
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(params.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Something went wrong"})
+		return
+	}
+
+	used, err := uc.Storage.UserTokenStore.ResetPassword(existToken.ID, user.ID, string(hashedPass))
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: "Error updating password"})
+		return
+	}
+	if !used {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidPasswordResetTokenError})
+		return
+	}
+	if err := uc.Storage.SessionStore.RevokeAllSessions(user.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if err := uc.Storage.UserTokenStore.DeleteUserTokens(user.ID, model.UserTokenPurposePasswordReset); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	// The reset email reached the user, so the address is verified too.
+	if err := uc.Storage.UserStore.VerifyEmail(user.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Password reset successfully. Please log in with your new password"})
+}
+
+func (uc UserController) SearchUserByUsername(c *gin.Context) {
+	username := c.Param("username")
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	users, err := uc.Storage.UserStore.GetUsersByUsername(username, userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	if users == nil {
+		c.JSON(200, util.SuccessMessageResponse{Message: "No users found"})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Users fetched successfully", Result: users})
+
+}
+
+// GetUserByUsername godoc
+//
+//	@Summary		Get user by username
+//	@Description	Get the profile of a user by their exact username. A username that was changed recently redirects to the current username of the account.
+//	@Tags			Users
+//	@Produce		json
+//	@Param			username	path		string	true	"Username"
+//	@Success		200			{object}	util.SuccessResultResponse{result=model.Profile}
+//	@Success		307
+//	@Failure		401			{object}	util.ErrorResponse
+//	@Failure		404			{object}	util.ErrorResponse
+//	@Failure		500			{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/username/{username} [get]
+func (uc UserController) GetUserByUsername(c *gin.Context) {
+	username := c.Param("username")
+
+	user, err := uc.Storage.UserStore.GetUserByUsername(username)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user != nil {
+		if !uc.canSeeUser(c, user) {
+			return
+		}
+		profile, err := uc.Storage.UserStore.GetProfile(user.ID, c.MustGet("userID").(uuid.UUID))
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		c.JSON(200, util.SuccessResultResponse{Message: "User fetched successfully", Result: profile})
+		return
+	}
+
+	change, err := uc.Storage.UsernameChangeStore.GetReservedUsername(username)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if change == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+
+	// Look the account up again, it may have been renamed more than once.
+	user, err = uc.Storage.UserStore.GetUserByID(change.UserID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if !uc.canSeeUser(c, user) {
+		return
+	}
+
+	// The old username is only reserved for a while and can then be taken
+	// by someone else, so the redirect must not be cached permanently.
+	c.Redirect(307, strings.Replace(c.FullPath(), ":username", url.PathEscape(user.Username), 1))
+}
+
+// ChangeUsername godoc
+//
+//	@Summary		Change username
+//	@Description	Change the username of the authenticated user. Usernames can be changed once every 14 days and the old username keeps pointing to the account for 30 days, during which nobody else can take it.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	body		dto.ChangeUsernameDTO	true	"New username"
+//	@Success		200			{object}	util.SuccessResultResponse{result=model.UsernameChange}
+//	@Failure		400			{object}	util.ErrorResponse
+//	@Failure		401			{object}	util.ErrorResponse
+//	@Failure		404			{object}	util.ErrorResponse
+//	@Failure		429			{object}	util.ErrorResponse
+//	@Failure		500			{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me/username [put]
+func (uc UserController) ChangeUsername(c *gin.Context) {
+	var params dto.ChangeUsernameDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if params.Username == user.Username {
+		c.JSON(400, util.ErrorResponse{Error: util.SameUsernameError})
+		return
+	}
+
+	now := time.Now()
+	latest, err := uc.Storage.UsernameChangeStore.GetLatestUsernameChange(user.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if latest != nil {
+		if retryAfter := latest.ChangedAt.Add(util.UsernameChangeCooldown).Sub(now); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(429, util.ErrorResponse{Error: util.UsernameChangeTooSoonError})
+			return
+		}
+	}
+
+	change := &model.UsernameChange{
+		UserID:        user.ID,
+		NewUsername:   params.Username,
+		ReservedUntil: now.Add(util.UsernameGracePeriod),
+	}
+	err = uc.Storage.UsernameChangeStore.ChangeUsername(change, now.Add(-util.UsernameChangeCooldown))
+	if err != nil {
+		switch err {
+		case database.ErrUsernameTaken:
+			c.JSON(400, util.ErrorResponse{Error: util.UsernameTakenError})
+		case database.ErrUsernameChangeTooSoon:
+			c.JSON(429, util.ErrorResponse{Error: util.UsernameChangeTooSoonError})
+		default:
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		}
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Username changed successfully", Result: change})
+}
+
+// GetUsernameChanges godoc
+//
+//	@Summary		Username history
+//	@Description	List the username changes of the authenticated user
+//	@Tags			Users
+//	@Produce		json
+//	@Success		200	{object}	util.SuccessResultResponse{result=[]model.UsernameChange}
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me/username_history [get]
+func (uc UserController) GetUsernameChanges(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	changes, err := uc.Storage.UsernameChangeStore.GetUsernameChangesByUserID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Username history fetched successfully", Result: changes})
+}
+
+// isUsernameTaken reports whether a username belongs to a user or is still
+// reserved after a username change.
+func (uc UserController) isUsernameTaken(username string) (bool, error) {
+	user, err := uc.Storage.UserStore.GetUserByUsername(username)
+	if err != nil {
+		return false, err
+	}
+	if user != nil {
+		return true, nil
+	}
+	change, err := uc.Storage.UsernameChangeStore.GetReservedUsername(username)
+	if err != nil {
+		return false, err
+	}
+	return change != nil, nil
+}
+
+// GetFollowRequests godoc
+//
+//	@Summary		Get follow requests
+//	@Description	List the pending requests to follow the authenticated user, oldest first
+//	@Tags			Users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"		default(20)
+//	@Param			offset	query		int	false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.FollowRequest}
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/follow_requests [get]
+func (uc UserController) GetFollowRequests(c *gin.Context) {
+	me := c.MustGet("userID").(uuid.UUID)
+	pagination := database.NewPagination(c)
+
+	requests, err := uc.Storage.FollowStore.GetFollowRequestsByFollowID(me, pagination)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Follow requests fetched successfully", Result: requests})
+}
+
+// ApproveFollowRequest godoc
+//
+//	@Summary		Approve a follow request
+//	@Description	Approve the request of a user to follow the authenticated user
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id	path		string	true	"ID of the user who sent the request"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/follow_requests/{id}/approve [post]
+func (uc UserController) ApproveFollowRequest(c *gin.Context) {
+	requester, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+
+	approved, err := uc.Storage.FollowStore.ApproveFollowRequest(requester, me)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !approved {
+		c.JSON(404, util.ErrorResponse{Error: util.FollowRequestNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Follow request approved"})
+}
+
+// RejectFollowRequest godoc
+//
+//	@Summary		Reject a follow request
+//	@Description	Reject the request of a user to follow the authenticated user
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id	path		string	true	"ID of the user who sent the request"
+//	@Success		200	{object}	util.SuccessMessageResponse
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/follow_requests/{id}/reject [post]
+func (uc UserController) RejectFollowRequest(c *gin.Context) {
+	requester, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+
+	rejected, err := uc.Storage.FollowStore.DeleteFollowRequest(requester, me)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if !rejected {
+		c.JSON(404, util.ErrorResponse{Error: util.FollowRequestNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessMessageResponse{Message: "Follow request rejected"})
+}
+
+// GetSuggestions godoc
+//
+//	@Summary		Get follow suggestions
+//	@Description	Suggest accounts for the authenticated user to follow. Accounts followed by more of the accounts they follow come first, followed by popular accounts. Accounts they already follow or asked to follow and blocked or muted accounts are left out.
+//	@Tags			Users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"		default(20)
+//	@Param			offset	query		int	false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.Suggestion}
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/suggestions [get]
+func (uc UserController) GetSuggestions(c *gin.Context) {
+	me := c.MustGet("userID").(uuid.UUID)
+	pagination := database.NewPagination(c)
+
+	suggestions, err := uc.Storage.FollowStore.GetSuggestions(me, pagination)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	for i := range suggestions {
+		suggestions[i].Reason = suggestionReason(suggestions[i])
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Suggestions fetched successfully", Result: suggestions})
+}
+
+func suggestionReason(suggestion model.Suggestion) string {
+	switch {
+	case suggestion.MutualCount == 1:
+		return "followed by 1 person you follow"
+	case suggestion.MutualCount > 1:
+		return "followed by " + strconv.Itoa(suggestion.MutualCount) + " people you follow"
+	case suggestion.FollowerCount == 1:
+		return "popular account with 1 follower"
+	default:
+		return "popular account with " + strconv.Itoa(suggestion.FollowerCount) + " followers"
+	}
+}
+
+// GetMutualFollowers godoc
+//
+//	@Summary		Get mutual followers
+//	@Description	List the users that follow both the authenticated user and another user
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id		path		string	true	"User ID"
+//	@Param			limit	query		int		false	"Limit"		default(20)
+//	@Param			offset	query		int		false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.User}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/mutual_followers [get]
+func (uc UserController) GetMutualFollowers(c *gin.Context) {
+	user, ok := uc.userFromParam(c)
+	if !ok || !uc.canViewUser(c, user) {
+		return
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+
+	users, err := uc.Storage.FollowStore.GetMutualFollowers(me, user.ID, database.NewPagination(c))
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Mutual followers fetched successfully", Result: users})
+}
+
+// GetFollowersYouKnow godoc
+//
+//	@Summary		Get followers you know
+//	@Description	List the followers of a user that the authenticated user follows
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id		path		string	true	"User ID"
+//	@Param			limit	query		int		false	"Limit"		default(20)
+//	@Param			offset	query		int		false	"Offset"	default(0)
+//	@Success		200		{object}	util.SuccessResultResponse{result=[]model.User}
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		403		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/followers_you_know [get]
+func (uc UserController) GetFollowersYouKnow(c *gin.Context) {
+	user, ok := uc.userFromParam(c)
+	if !ok || !uc.canViewUser(c, user) {
+		return
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+
+	users, err := uc.Storage.FollowStore.GetFollowersYouKnow(me, user.ID, database.NewPagination(c))
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Followers you know fetched successfully", Result: users})
+}
+
+// GetRelationship godoc
+//
+//	@Summary		Get relationship
+//	@Description	Tell whether the authenticated user and another user follow each other
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id	path		string	true	"User ID"
+//	@Success		200	{object}	util.SuccessResultResponse{result=model.Relationship}
+//	@Failure		400	{object}	util.ErrorResponse
+//	@Failure		401	{object}	util.ErrorResponse
+//	@Failure		404	{object}	util.ErrorResponse
+//	@Failure		500	{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/relationship [get]
+func (uc UserController) GetRelationship(c *gin.Context) {
+	user, ok := uc.userFromParam(c)
+	if !ok || !uc.canSeeUser(c, user) {
+		return
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+
+	relationship, err := uc.Storage.FollowStore.GetRelationship(me, user.ID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Relationship fetched successfully", Result: relationship})
+}
+
+// GetFollowPath godoc
+//
+//	@Summary		Get follow path
+//	@Description	Find the shortest chain of follows from the authenticated user to another user, including both of them. Only follows the authenticated user can see are searched.
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id			path		string	true	"User ID"
+//	@Param			max_depth	query		int		false	"Maximum number of follows, at most 4"	default(3)
+//	@Success		200			{object}	util.SuccessResultResponse{result=[]model.User}
+//	@Failure		400			{object}	util.ErrorResponse
+//	@Failure		401			{object}	util.ErrorResponse
+//	@Failure		404			{object}	util.ErrorResponse
+//	@Failure		500			{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/{id}/path [get]
+func (uc UserController) GetFollowPath(c *gin.Context) {
+	maxDepth := util.DefaultFollowPathDepth
+	if param := c.Query("max_depth"); param != "" {
+		depth, err := strconv.Atoi(param)
+		if err != nil || depth < 1 || depth > util.MaxFollowPathDepth {
+			c.JSON(400, util.ErrorResponse{Error: util.InvalidFollowPathDepthError})
+			return
+		}
+		maxDepth = depth
+	}
+
+	user, ok := uc.userFromParam(c)
+	if !ok || !uc.canSeeUser(c, user) {
+		return
+	}
+	me := c.MustGet("userID").(uuid.UUID)
+
+	path, err := uc.Storage.FollowStore.GetFollowPath(me, user.ID, maxDepth)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if path == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.FollowPathNotFoundError})
+		return
+	}
+
+	c.JSON(200, util.SuccessResultResponse{Message: "Follow path fetched successfully", Result: path})
+}
+
+// userFromParam looks up the user in the id path parameter. It writes the
+// response and returns false if the ID is invalid or the user doesn't exist.
+func (uc UserController) userFromParam(c *gin.Context) (*model.User, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, util.ErrorResponse{Error: "Invalid user ID"})
+		return nil, false
+	}
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return nil, false
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return nil, false
+	}
+	return user, true
+}
+
+// DeleteAccount godoc
+//
+//	@Summary		Delete account
+//	@Description	Delete the account of the authenticated user. The account is deactivated right away and every session and personal access token is revoked. Logging in again within the grace period restores the account, after that it is purged.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			account	body		dto.DeleteAccountDTO	true	"Password, and a two factor code if it is enabled"
+//	@Success		200		{object}	util.SuccessMessageResponse
+//	@Failure		400		{object}	util.ErrorResponse
+//	@Failure		401		{object}	util.ErrorResponse
+//	@Failure		404		{object}	util.ErrorResponse
+//	@Failure		500		{object}	util.ErrorResponse
+//	@Security		Bearer
+//	@Router			/users/me [delete]
+func (uc UserController) DeleteAccount(c *gin.Context) {
+	var params dto.DeleteAccountDTO
+	if err := c.ShouldBindJSON(&params); err != nil {
+		util.HandleBindError(c, err)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	user, err := uc.Storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
+	if user == nil {
+		c.JSON(404, util.ErrorResponse{Error: util.UserNotFoundError})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.Password)); err != nil {
+		c.JSON(400, util.ErrorResponse{Error: util.InvalidCredentialsError})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		ok, err := uc.verifySecondFactor(user, params.Code)
+		if err != nil {
+			c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+			return
+		}
+		if !ok {
+			c.JSON(400, util.ErrorResponse{Error: util.InvalidTwoFactorCodeError})
+			return
+		}
+	}
+
+	if err := uc.Storage.UserStore.DeactivateUser(user.ID); err != nil {
+		c.JSON(500, util.ErrorResponse{Error: util.InternalServerError})
+		return
+	}
 
+	days := int(uc.AccountGracePeriod.Hours() / 24)
+	c.JSON(200, util.SuccessMessageResponse{Message: "Account deleted. Log in within " + strconv.Itoa(days) + " days to restore it"})
 }
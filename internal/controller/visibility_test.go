@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// visibilityPostStore serves a single published post of a private account
+// that only its author and follower can see.
+type visibilityPostStore struct {
+	database.BasePostStore
+	post     model.Post
+	follower uuid.UUID
+}
+
+func (s *visibilityPostStore) GetPostByID(postID uuid.UUID) (*model.Post, error) {
+	if postID != s.post.ID {
+		return nil, nil
+	}
+	post := s.post
+	return &post, nil
+}
+
+func (s *visibilityPostStore) IsContentVisible(postID, authorID, viewerID uuid.UUID) (bool, error) {
+	return postID == s.post.ID && (viewerID == s.post.UserID || viewerID == s.follower), nil
+}
+
+type visibilityCommentStore struct {
+	database.BaseCommentStore
+	comment model.Comment
+}
+
+func (s *visibilityCommentStore) GetCommentByID(id uuid.UUID) (*model.Comment, error) {
+	if id != s.comment.ID {
+		return nil, nil
+	}
+	comment := s.comment
+	return &comment, nil
+}
+
+func (s *visibilityCommentStore) GetCommentsByPostID(postID, userID uuid.UUID) ([]model.Comment, error) {
+	return []model.Comment{s.comment}, nil
+}
+
+type visibilityBlockStore struct {
+	database.BaseBlockStore
+}
+
+func (s *visibilityBlockStore) IsBlocked(userID, otherID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+type visibilityReplyStore struct {
+	database.BaseReplyStore
+}
+
+func (s *visibilityReplyStore) GetRepliesByCommentID(commentID, userID uuid.UUID) ([]model.Reply, error) {
+	return []model.Reply{{ID: uuid.New(), CommentID: commentID}}, nil
+}
+
+func TestPrivatePostContent_HiddenFromNonFollowers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorID, followerID, strangerID := uuid.New(), uuid.New(), uuid.New()
+	post := model.Post{ID: uuid.New(), UserID: authorID, Status: model.PostStatusPublished}
+	comment := model.Comment{ID: uuid.New(), PostID: post.ID, UserID: authorID}
+	storage := &database.Storage{
+		PostStore:    &visibilityPostStore{post: post, follower: followerID},
+		CommentStore: &visibilityCommentStore{comment: comment},
+		BlockStore:   &visibilityBlockStore{},
+		ReplyStore:   &visibilityReplyStore{},
+	}
+
+	commentController := NewCommentController(storage, nil)
+	likeController := NewLikeController(storage)
+	replyController := NewReplyController(storage)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		handler gin.HandlerFunc
+		route   string
+	}{
+		{
+			name:    "create comment",
+			method:  http.MethodPost,
+			route:   "/comments",
+			path:    "/comments",
+			body:    `{"post_id":"` + post.ID.String() + `","content":"hello"}`,
+			handler: commentController.CreateComment,
+		},
+		{
+			name:    "list comments",
+			method:  http.MethodGet,
+			route:   "/comments/:id",
+			path:    "/comments/" + post.ID.String(),
+			handler: commentController.GetCommentsByPostID,
+		},
+		{
+			name:    "like post",
+			method:  http.MethodPost,
+			route:   "/posts/:id/like",
+			path:    "/posts/" + post.ID.String() + "/like",
+			handler: likeController.LikePost,
+		},
+		{
+			name:    "like comment",
+			method:  http.MethodPost,
+			route:   "/comments/:id/like",
+			path:    "/comments/" + comment.ID.String() + "/like",
+			handler: likeController.LikeComment,
+		},
+		{
+			name:    "reply to comment",
+			method:  http.MethodPost,
+			route:   "/comments/:id/reply",
+			path:    "/comments/" + comment.ID.String() + "/reply",
+			body:    `{"message":"hello"}`,
+			handler: replyController.ReplyComment,
+		},
+		{
+			name:    "list replies",
+			method:  http.MethodGet,
+			route:   "/replies/:id",
+			path:    "/replies/" + comment.ID.String(),
+			handler: replyController.GetCommentReplies,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Handle(tt.method, tt.route, func(c *gin.Context) {
+				c.Set("userID", strangerID)
+			}, tt.handler)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+
+	// Followers still see the thread.
+	router := gin.New()
+	router.GET("/comments/:id", func(c *gin.Context) {
+		c.Set("userID", followerID)
+	}, commentController.GetCommentsByPostID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/comments/"+post.ID.String(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func (s *visibilityPostStore) GetPostRevisions(postID uuid.UUID) ([]model.Revision, error) {
+	return []model.Revision{{ID: uuid.New(), Content: "first version"}}, nil
+}
+
+func TestGetPostRevisions_VisibleToWhoCanSeeThePost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorID, followerID, strangerID := uuid.New(), uuid.New(), uuid.New()
+	post := model.Post{ID: uuid.New(), UserID: authorID, Status: model.PostStatusPublished}
+	storage := &database.Storage{
+		PostStore: &visibilityPostStore{post: post, follower: followerID},
+	}
+	postController := NewPostController(storage, nil)
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		code   int
+	}{
+		{name: "author", userID: authorID, code: http.StatusOK},
+		{name: "follower", userID: followerID, code: http.StatusOK},
+		{name: "stranger", userID: strangerID, code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/posts/:id/revisions", func(c *gin.Context) {
+				c.Set("userID", tt.userID)
+			}, postController.GetPostRevisions)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/"+post.ID.String()+"/revisions", nil))
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, tt.code == http.StatusOK, strings.Contains(w.Body.String(), "first version"))
+		})
+	}
+}
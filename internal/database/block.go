@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+type BaseBlockStore interface {
+	BlockUser(userID, blockedID uuid.UUID) error
+	UnblockUser(userID, blockedID uuid.UUID) (bool, error)
+	IsBlocked(userID, otherID uuid.UUID) (bool, error)
+	GetBlocksByUserID(userID uuid.UUID, pagination Pagination) ([]model.Block, error)
+}
+
+type BlockStore struct {
+	DB *sql.DB
+}
+
+func NewBlockStore(db *sql.DB) BaseBlockStore {
+	return &BlockStore{DB: db}
+}
+
+// BlockUser blocks a user and removes the follows and follow requests between
+// the two users in both directions in a single transaction.
+func (s *BlockStore) BlockUser(userID, blockedID uuid.UUID) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO blocks (user_id, blocked_id) VALUES ($1, $2) ON CONFLICT (user_id, blocked_id) DO NOTHING"
+	if _, err := tx.Exec(query, userID, blockedID); err != nil {
+		return err
+	}
+	query = "DELETE FROM follows WHERE (user_id = $1 AND follow_id = $2) OR (user_id = $2 AND follow_id = $1)"
+	if _, err := tx.Exec(query, userID, blockedID); err != nil {
+		return err
+	}
+	query = "DELETE FROM follow_requests WHERE (user_id = $1 AND follow_id = $2) OR (user_id = $2 AND follow_id = $1)"
+	if _, err := tx.Exec(query, userID, blockedID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *BlockStore) UnblockUser(userID, blockedID uuid.UUID) (bool, error) {
+	result, err := s.DB.Exec("DELETE FROM blocks WHERE user_id = $1 AND blocked_id = $2", userID, blockedID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// IsBlocked reports whether either user blocked the other.
+func (s *BlockStore) IsBlocked(userID, otherID uuid.UUID) (bool, error) {
+	var result bool
+	query := "SELECT EXISTS (SELECT 1 FROM blocks WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1))"
+	err := s.DB.QueryRow(query, userID, otherID).Scan(&result)
+	if err != nil {
+		return false, err
+	}
+	return result, nil
+}
+
+// GetBlocksByUserID returns the users blocked by a user, newest first.
+func (s *BlockStore) GetBlocksByUserID(userID uuid.UUID, pagination Pagination) ([]model.Block, error) {
+	blocks := []model.Block{}
+	query := `SELECT blocks.id, blocks.user_id, blocks.blocked_id, blocks.created_at,
+	users.id, users.name, users.last_name, users.username, users.avatar
+	FROM blocks
+	JOIN users ON users.id = blocks.blocked_id
+	WHERE blocks.user_id = $1
+	ORDER BY blocks.created_at DESC
+	LIMIT $2 OFFSET $3`
+	rows, err := s.DB.Query(query, userID, pagination.Limit, pagination.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		block := model.Block{}
+		err := rows.Scan(&block.ID, &block.UserID, &block.BlockedID, &block.CreatedAt,
+			&block.User.ID, &block.User.Name, &block.User.LastName, &block.User.Username, &block.User.Avatar)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
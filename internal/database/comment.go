@@ -11,8 +11,10 @@ type BaseCommentStore interface {
 	GetCommentsByPostID(postID, userID uuid.UUID) ([]model.Comment, error)
 	GetCommentByID(id uuid.UUID) (*model.Comment, error)
 	CreateComment(comment *model.Comment) error
-	UpdateComment(comment *model.Comment) error
+	UpdateComment(comment *model.Comment, moderated bool) error
 	DeleteComment(id uuid.UUID) error
+	GetCommentsByUserID(userID uuid.UUID) ([]model.Comment, error)
+	GetCommentRevisions(commentID uuid.UUID) ([]model.Revision, error)
 }
 
 type CommentStore struct {
@@ -53,8 +55,11 @@ func (cs CommentStore) GetCommentsByPostID(postID, userID uuid.UUID) ([]model.Co
 	comments.id,
 	comments.post_id,
 	comments.content,
+	comments.image,
+	comments.thumbnail,
 	comments.created_at,
 	comments.updated_at,
+	(comments.updated_at > comments.created_at) AS edited,
 
 	users.id,
 	users.name,
@@ -70,11 +75,13 @@ func (cs CommentStore) GetCommentsByPostID(postID, userID uuid.UUID) ([]model.Co
 
 
 	FROM comments JOIN users ON comments.user_id = users.id
+	JOIN posts ON posts.id = comments.post_id
 	LEFT JOIN comment_likes_count ON comment_likes_count.comment_id = comments.id
 	LEFT JOIN reply_count ON reply_count.comment_id = comments.id
 	LEFT JOIN user_likes ON user_likes.comment_id = comments.id
 	LEFT JOIN user_follows ON user_follows.follow_id = users.id
-	WHERE comments.post_id = $1`
+	WHERE comments.post_id = $1 AND users.deactivated_at IS NULL AND ` + notBlocked("comments.user_id", "$2") + `
+	AND ` + notBlocked("posts.user_id", "$2")
 	rows, err := cs.db.Query(query, postID, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -87,7 +94,7 @@ func (cs CommentStore) GetCommentsByPostID(postID, userID uuid.UUID) ([]model.Co
 	for rows.Next() {
 		var comment model.Comment
 		err := rows.Scan(
-			&comment.ID, &comment.PostID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt,
+			&comment.ID, &comment.PostID, &comment.Content, &comment.Image, &comment.Thumbnail, &comment.CreatedAt, &comment.UpdatedAt, &comment.Edited,
 			&comment.User.ID, &comment.User.Name, &comment.User.LastName, &comment.User.Username,
 			&comment.LikeCount, &comment.ReplyCount,
 			&comment.IsLiked, &comment.IsFollowing)
@@ -130,23 +137,49 @@ func (cs CommentStore) GetCommentByID(id uuid.UUID) (*model.Comment, error) {
 	return &comment, nil
 }
 
+// CreateComment stores a comment with its hashtags and attaches its media in
+// a single transaction. If the media was attached to something else in the meantime,
+// nothing is stored and ErrMediaAttached is returned.
 func (cs CommentStore) CreateComment(comment *model.Comment) error {
-
-	query := "INSERT INTO comments (post_id, user_id, content) VALUES ($1, $2, $3)"
-	_, err := cs.db.Exec(query, comment.PostID, comment.UserID, comment.Content)
+	tx, err := cs.db.Begin()
 	if err != nil {
 		return err
 	}
-	return nil
-}
+	defer tx.Rollback()
 
-func (cs CommentStore) UpdateComment(comment *model.Comment) error {
-	query := "UPDATE comments SET content = $1 WHERE id = $2"
-	_, err := cs.db.Exec(query, comment.Content, comment.ID)
-	if err != nil {
+	query := "INSERT INTO comments (post_id, user_id, content, image, thumbnail) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	if err := tx.QueryRow(query, comment.PostID, comment.UserID, comment.Content, comment.Image, comment.Thumbnail).Scan(&comment.ID); err != nil {
 		return err
 	}
-	return nil
+	if comment.MediaID != nil {
+		if err := attachMedia(tx, *comment.MediaID, nil, &comment.ID); err != nil {
+			return err
+		}
+	}
+	if err := setCommentTags(tx, comment.ID, comment.Content); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateComment replaces the content of a comment and its hashtags and keeps
+// the previous version as a revision, marked as moderated if a moderator made
+// the edit.
+func (cs CommentStore) UpdateComment(comment *model.Comment, moderated bool) error {
+	return revise(cs.db,
+		"SELECT content FROM comments WHERE id = $1 FOR UPDATE",
+		"INSERT INTO comment_revisions (comment_id, content, created_at, moderated) SELECT id, content, updated_at, $2 FROM comments WHERE id = $1",
+		"UPDATE comments SET content = $1, updated_at = NOW() WHERE id = $2",
+		comment.ID, comment.Content, moderated,
+		func(tx *sql.Tx) error { return setCommentTags(tx, comment.ID, comment.Content) })
+}
+
+// GetCommentRevisions returns the earlier versions of a comment, newest first,
+// except the ones replaced by a moderator.
+func (cs CommentStore) GetCommentRevisions(commentID uuid.UUID) ([]model.Revision, error) {
+	query := "SELECT id, content, created_at FROM comment_revisions WHERE comment_id = $1 AND NOT moderated ORDER BY created_at DESC"
+	return getRevisions(cs.db, query, commentID)
 }
 
 func (cs CommentStore) DeleteComment(id uuid.UUID) error {
@@ -157,3 +190,25 @@ func (cs CommentStore) DeleteComment(id uuid.UUID) error {
 	}
 	return nil
 }
+
+// GetCommentsByUserID returns every comment of a user, oldest first.
+func (cs CommentStore) GetCommentsByUserID(userID uuid.UUID) ([]model.Comment, error) {
+	comments := []model.Comment{}
+	query := "SELECT id, post_id, user_id, content, image, created_at, updated_at FROM comments WHERE user_id = $1 ORDER BY created_at ASC"
+	rows, err := cs.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		comment := model.Comment{}
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.Image, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+type BaseDataExportStore interface {
+	CreateDataExport(export *model.DataExport) error
+	GetDataExportByID(id uuid.UUID) (*model.DataExport, error)
+	GetUnfinishedDataExport(userID uuid.UUID) (*model.DataExport, error)
+	ClaimDataExport(staleBefore time.Time) (*model.DataExport, error)
+	CompleteDataExport(id uuid.UUID, blobKey string, expiresAt time.Time) error
+	FailDataExport(id uuid.UUID) error
+	GetExpiredDataExports(limit int) ([]model.DataExport, error)
+	ExpireDataExport(id uuid.UUID) error
+}
+
+type DataExportStore struct {
+	DB *sql.DB
+}
+
+func NewDataExportStore(db *sql.DB) BaseDataExportStore {
+	return &DataExportStore{DB: db}
+}
+
+const dataExportColumns = "id, user_id, status, blob_key, started_at, completed_at, expires_at, created_at"
+
+func scanDataExport(row interface{ Scan(...any) error }, export *model.DataExport) error {
+	return row.Scan(&export.ID, &export.UserID, &export.Status, &export.BlobKey, &export.StartedAt, &export.CompletedAt, &export.ExpiresAt, &export.CreatedAt)
+}
+
+func (s *DataExportStore) CreateDataExport(export *model.DataExport) error {
+	query := "INSERT INTO data_exports (user_id) VALUES ($1) RETURNING " + dataExportColumns
+	return scanDataExport(s.DB.QueryRow(query, export.UserID), export)
+}
+
+func (s *DataExportStore) GetDataExportByID(id uuid.UUID) (*model.DataExport, error) {
+	export := &model.DataExport{}
+	query := "SELECT " + dataExportColumns + " FROM data_exports WHERE id = $1"
+	if err := scanDataExport(s.DB.QueryRow(query, id), export); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// GetUnfinishedDataExport returns the pending or running export of a user.
+func (s *DataExportStore) GetUnfinishedDataExport(userID uuid.UUID) (*model.DataExport, error) {
+	export := &model.DataExport{}
+	query := "SELECT " + dataExportColumns + " FROM data_exports WHERE user_id = $1 AND status IN ('pending', 'running') ORDER BY created_at DESC LIMIT 1"
+	if err := scanDataExport(s.DB.QueryRow(query, userID), export); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// ClaimDataExport marks the oldest pending export as running and returns it.
+// Exports that have been running since before staleBefore are claimed again,
+// their worker is assumed to have died. Concurrent workers never claim the
+// same export. Exports of deleted users are never claimed. It returns nil if
+// there is nothing to do.
+func (s *DataExportStore) ClaimDataExport(staleBefore time.Time) (*model.DataExport, error) {
+	export := &model.DataExport{}
+	query := `UPDATE data_exports SET status = 'running', started_at = NOW()
+	WHERE id = (
+		SELECT id FROM data_exports
+		WHERE (status = 'pending' OR (status = 'running' AND started_at < $1))
+		AND user_id IS NOT NULL
+		ORDER BY created_at ASC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + dataExportColumns
+	if err := scanDataExport(s.DB.QueryRow(query, staleBefore.UTC()), export); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *DataExportStore) CompleteDataExport(id uuid.UUID, blobKey string, expiresAt time.Time) error {
+	query := "UPDATE data_exports SET status = 'completed', blob_key = $1, completed_at = NOW(), expires_at = $2 WHERE id = $3"
+	_, err := s.DB.Exec(query, blobKey, expiresAt.UTC(), id)
+	return err
+}
+
+func (s *DataExportStore) FailDataExport(id uuid.UUID) error {
+	query := "UPDATE data_exports SET status = 'failed', completed_at = NOW() WHERE id = $1"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+// GetExpiredDataExports returns up to limit completed exports whose archive
+// has expired, and exports whose user was deleted or anonymized. Those lose
+// their user_id, and their archives are deleted right away.
+func (s *DataExportStore) GetExpiredDataExports(limit int) ([]model.DataExport, error) {
+	exports := []model.DataExport{}
+	query := "SELECT " + dataExportColumns + ` FROM data_exports
+	WHERE (status = 'completed' AND expires_at < NOW())
+	OR (user_id IS NULL AND status IN ('pending', 'completed', 'failed'))
+	ORDER BY expires_at ASC NULLS FIRST LIMIT $1`
+	rows, err := s.DB.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		export := model.DataExport{}
+		if err := scanDataExport(rows, &export); err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// ExpireDataExport marks an export as expired once its archive is deleted.
+func (s *DataExportStore) ExpireDataExport(id uuid.UUID) error {
+	query := "UPDATE data_exports SET status = 'expired', blob_key = NULL WHERE id = $1"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
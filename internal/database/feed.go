@@ -21,14 +21,66 @@ func NewFeedStore(db *sql.DB) BaseFeedStore {
 	}
 }
 
+// feedPostFilters keeps the posts the viewer, $1, can see in their feed and
+// that match the search, $4.
+var feedPostFilters = `posts.content ILIKE '%' || $4 || '%'
+	AND posts.status = 'published'
+	AND ` + visibleTo("posts.user_id", "$1") + `
+	AND ` + isActive("posts.user_id") + `
+	AND ` + notBlocked("posts.user_id", "$1") + `
+	AND ` + notMuted("posts.user_id", "posts.content", "$1")
+
+// feedReposterFilters keeps the reposts of posts by reposterColumn that the
+// viewer, $1, can see in their feed.
+func feedReposterFilters(reposterColumn string) string {
+	return isActive(reposterColumn) + `
+	AND ` + notBlocked(reposterColumn, "$1") + `
+	AND ` + notMuted(reposterColumn, "posts.content", "$1")
+}
+
+// GetFeed returns the posts of the users followed by userID and the posts
+// they reposted, most recent activity first. A post shows up once, with the
+// latest repost of it as the attribution, unless the author's own post is
+// more recent.
 func (fs FeedStore) GetFeed(userID uuid.UUID, pagination Pagination, search Search) ([]model.Post, error) {
 	var posts []model.Post
 
 	query := `
-	WITH limited_posts AS (
-		SELECT * FROM posts
-		WHERE content ILIKE '%' || $4 || '%' AND user_id = ANY (SELECT follow_id FROM follows WHERE user_id = $1)
-		ORDER BY created_at DESC
+	WITH followed AS (
+		SELECT follow_id FROM follows WHERE user_id = $1
+	),
+
+	-- Each branch stops at the rows a page can reach before the branches
+	-- are merged, so a feed doesn't read everything its followees posted.
+	-- Reposts keep only the latest repost of each post, so no post shows up
+	-- twice in a branch and the branches hold every post of the page.
+	feed_items AS (
+		SELECT DISTINCT ON (post_id) post_id, reposter_id, activity_at FROM (
+			(SELECT posts.id AS post_id, NULL::uuid AS reposter_id, posts.created_at AS activity_at FROM posts
+			WHERE posts.user_id IN (SELECT follow_id FROM followed)
+			AND ` + feedPostFilters + `
+			ORDER BY posts.created_at DESC
+			LIMIT $2::bigint + $3::bigint)
+			UNION ALL
+			(SELECT reposts.post_id, reposts.user_id, reposts.created_at FROM reposts
+			JOIN posts ON posts.id = reposts.post_id
+			WHERE reposts.user_id IN (SELECT follow_id FROM followed)
+			AND ` + feedReposterFilters("reposts.user_id") + `
+			AND ` + feedPostFilters + `
+			AND NOT EXISTS (SELECT 1 FROM reposts AS later
+			WHERE later.post_id = reposts.post_id AND later.created_at > reposts.created_at
+			AND later.user_id IN (SELECT follow_id FROM followed)
+			AND ` + feedReposterFilters("later.user_id") + `)
+			ORDER BY reposts.created_at DESC
+			LIMIT $2::bigint + $3::bigint)
+		) AS items
+		ORDER BY post_id, activity_at DESC
+	),
+
+	limited_posts AS (
+		SELECT posts.*, feed_items.reposter_id, feed_items.activity_at FROM feed_items
+		JOIN posts ON posts.id = feed_items.post_id
+		ORDER BY feed_items.activity_at DESC
 		LIMIT $2 OFFSET $3
 	),
 
@@ -42,17 +94,30 @@ func (fs FeedStore) GetFeed(userID uuid.UUID, pagination Pagination, search Sear
 		GROUP BY post_id
 	),
 
+	reposts_count AS (
+		SELECT post_id, COUNT(*) as total_reposts FROM reposts
+		GROUP BY post_id
+	),
+
 	user_likes AS (
 		SELECT post_id FROM post_likes
 		WHERE user_id = $1
+	),
+
+	user_reposts AS (
+		SELECT post_id FROM reposts
+		WHERE user_id = $1
 	)
 
 
 	SELECT 
 	posts.id,
 	posts.content,
+	posts.image,
+	posts.thumbnail,
 	posts.created_at,
 	posts.updated_at,
+	(posts.updated_at > posts.created_at) AS edited,
 
     post_user.id,
 	post_user.name,
@@ -61,14 +126,31 @@ func (fs FeedStore) GetFeed(userID uuid.UUID, pagination Pagination, search Sear
 	
 	COALESCE(likes_count.total_likes,0) AS total_likes,
 	COALESCE(comments_count.total_comments,0) AS total_comments,
+	COALESCE(reposts_count.total_reposts,0) AS total_reposts,
 
-	(user_likes.post_id IS NOT NULL) AS is_liked
+	(user_likes.post_id IS NOT NULL) AS is_liked,
+	(posts.user_id IN (SELECT follow_id FROM followed)) AS is_following,
+	(user_reposts.post_id IS NOT NULL) AS is_reposted,
+
+	reposter.id,
+	reposter.name,
+	reposter.last_name,
+	reposter.username,
+	CASE WHEN posts.reposter_id IS NOT NULL THEN posts.activity_at END AS reposted_at,
+
+	` + quotedPostColumns + `
 
     FROM limited_posts as posts 
     JOIN users AS post_user ON post_user.id = posts.user_id
     LEFT JOIN likes_count ON likes_count.post_id = posts.id
 	LEFT JOIN comments_count ON comments_count.post_id = posts.id
+	LEFT JOIN reposts_count ON reposts_count.post_id = posts.id
 	LEFT JOIN user_likes ON user_likes.post_id = posts.id
+	LEFT JOIN user_reposts ON user_reposts.post_id = posts.id
+	LEFT JOIN users AS reposter ON reposter.id = posts.reposter_id
+	LEFT JOIN posts AS quoted ON quoted.id = posts.quote_id AND ` + quotedPostVisibleTo("$1") + `
+	LEFT JOIN users AS quoted_user ON quoted_user.id = quoted.user_id
+	ORDER BY posts.activity_at DESC
 	`
 
 	rows, err := fs.DB.Query(query, userID, pagination.Limit, pagination.Offset, search.Query)
@@ -79,14 +161,27 @@ func (fs FeedStore) GetFeed(userID uuid.UUID, pagination Pagination, search Sear
 
 	for rows.Next() {
 		post := model.Post{}
-		err := rows.Scan(&post.ID, &post.Content, &post.CreatedAt, &post.UpdatedAt,
+		var reposterID *uuid.UUID
+		var reposterName, reposterLastName, reposterUsername *string
+		var quote quotedPost
+		err := rows.Scan(append([]any{&post.ID, &post.Content, &post.Image, &post.Thumbnail, &post.CreatedAt, &post.UpdatedAt, &post.Edited,
 			&post.User.ID, &post.User.Name, &post.User.LastName, &post.User.Username,
-			&post.LikeCount, &post.CommentCount,
-			&post.IsLiked,
-		)
+			&post.LikeCount, &post.CommentCount, &post.RepostCount,
+			&post.IsLiked, &post.IsFollowing, &post.IsReposted,
+			&reposterID, &reposterName, &reposterLastName, &reposterUsername, &post.RepostedAt,
+		}, quote.dest()...)...)
 		if err != nil {
 			return nil, err
 		}
+		if reposterID != nil {
+			post.RepostedBy = &model.User{
+				ID:       *reposterID,
+				Name:     *reposterName,
+				LastName: *reposterLastName,
+				Username: *reposterUsername,
+			}
+		}
+		post.Quote = quote.post()
 
 		posts = append(posts, post)
 
@@ -5,13 +5,30 @@ import (
 
 	"github.com/fatihesergg/go_social/internal/model"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// maxFollowPathFollows bounds the number of follows a follow path search
+// reads, so a search through accounts with many follows stays cheap.
+var maxFollowPathFollows = 10_000
+
 type BaseFollowStore interface {
-	GetFollowerByUserID(userID uuid.UUID) ([]model.Follow, error)
-	GetFollowingByUserID(userID uuid.UUID) ([]model.Follow, error)
+	GetFollowerByUserID(userID, viewerID uuid.UUID, pagination Pagination) ([]model.Follow, error)
+	GetFollowingByUserID(userID, viewerID uuid.UUID, pagination Pagination) ([]model.Follow, error)
 	FollowUser(userID, followID uuid.UUID) error
 	UnFollowUser(userID, followID uuid.UUID) error
+	IsFollowing(userID, followID uuid.UUID) (bool, error)
+	CreateFollowRequest(userID, followID uuid.UUID) error
+	GetFollowRequest(userID, followID uuid.UUID) (*model.FollowRequest, error)
+	GetFollowRequestsByFollowID(followID uuid.UUID, pagination Pagination) ([]model.FollowRequest, error)
+	ApproveFollowRequest(userID, followID uuid.UUID) (bool, error)
+	ApproveAllFollowRequests(followID uuid.UUID) error
+	DeleteFollowRequest(userID, followID uuid.UUID) (bool, error)
+	GetSuggestions(userID uuid.UUID, pagination Pagination) ([]model.Suggestion, error)
+	GetMutualFollowers(userID, otherID uuid.UUID, pagination Pagination) ([]model.User, error)
+	GetFollowersYouKnow(userID, otherID uuid.UUID, pagination Pagination) ([]model.User, error)
+	GetRelationship(userID, otherID uuid.UUID) (*model.Relationship, error)
+	GetFollowPath(fromID, toID uuid.UUID, maxDepth int) ([]model.User, error)
 }
 
 type FollowStore struct {
@@ -22,21 +39,47 @@ func NewFollowStore(db *sql.DB) BaseFollowStore {
 	return &FollowStore{db: db}
 }
 
-func (s FollowStore) GetFollowerByUserID(userID uuid.UUID) ([]model.Follow, error) {
-	var follows []model.Follow
-	query := "SELECT id, user_id, follow_id FROM follows WHERE follow_id = $1"
-	rows, err := s.db.Query(query, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return follows, nil
-		}
+// GetFollowerByUserID returns the followers of a user, newest first. Users
+// that blocked the viewer or were blocked by them are left out.
+func (s FollowStore) GetFollowerByUserID(userID, viewerID uuid.UUID, pagination Pagination) ([]model.Follow, error) {
+	query := `SELECT follows.id, follows.user_id, follows.follow_id, follows.created_at,
+	users.id, users.name, users.last_name, users.username, users.avatar, users.display_name,
+	EXISTS (SELECT 1 FROM follows AS viewer_follows WHERE viewer_follows.user_id = $2 AND viewer_follows.follow_id = users.id)
+	FROM follows
+	JOIN users ON users.id = follows.user_id
+	WHERE follows.follow_id = $1 AND users.deactivated_at IS NULL AND ` + notBlocked("users.id", "$2") + `
+	ORDER BY follows.created_at DESC
+	LIMIT $3 OFFSET $4`
+	return s.getFollows(query, userID, viewerID, pagination)
+}
+
+// GetFollowingByUserID returns the users a user follows, newest first. Users
+// that blocked the viewer or were blocked by them are left out.
+func (s FollowStore) GetFollowingByUserID(userID, viewerID uuid.UUID, pagination Pagination) ([]model.Follow, error) {
+	query := `SELECT follows.id, follows.user_id, follows.follow_id, follows.created_at,
+	users.id, users.name, users.last_name, users.username, users.avatar, users.display_name,
+	EXISTS (SELECT 1 FROM follows AS viewer_follows WHERE viewer_follows.user_id = $2 AND viewer_follows.follow_id = users.id)
+	FROM follows
+	JOIN users ON users.id = follows.follow_id
+	WHERE follows.user_id = $1 AND users.deactivated_at IS NULL AND ` + notBlocked("users.id", "$2") + `
+	ORDER BY follows.created_at DESC
+	LIMIT $3 OFFSET $4`
+	return s.getFollows(query, userID, viewerID, pagination)
+}
 
+func (s FollowStore) getFollows(query string, userID, viewerID uuid.UUID, pagination Pagination) ([]model.Follow, error) {
+	follows := []model.Follow{}
+	rows, err := s.db.Query(query, userID, viewerID, pagination.Limit, pagination.Offset)
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var follow model.Follow
-		if err := rows.Scan(&follow.ID, &follow.UserID, &follow.FollowID); err != nil {
+		err := rows.Scan(&follow.ID, &follow.UserID, &follow.FollowID, &follow.CreatedAt,
+			&follow.User.ID, &follow.User.Name, &follow.User.LastName, &follow.User.Username, &follow.User.Avatar, &follow.User.DisplayName,
+			&follow.IsFollowing)
+		if err != nil {
 			return nil, err
 		}
 		follows = append(follows, follow)
@@ -47,37 +90,342 @@ func (s FollowStore) GetFollowerByUserID(userID uuid.UUID) ([]model.Follow, erro
 	return follows, nil
 }
 
-func (s FollowStore) GetFollowingByUserID(userID uuid.UUID) ([]model.Follow, error) {
-	var follows []model.Follow
-	query := "SELECT id, user_id, follow_id FROM follows WHERE user_id = $1"
-	rows, err := s.db.Query(query, userID)
+func (s FollowStore) FollowUser(userID, followID uuid.UUID) error {
+	query := "INSERT INTO follows (user_id, follow_id) VALUES ($1, $2)"
+	_, err := s.db.Exec(query, userID, followID)
+	return err
+}
+func (s FollowStore) UnFollowUser(userID, followID uuid.UUID) error {
+	query := "DELETE FROM follows WHERE user_id = $1 AND follow_id = $2"
+	_, err := s.db.Exec(query, userID, followID)
+	return err
+}
+
+func (s FollowStore) IsFollowing(userID, followID uuid.UUID) (bool, error) {
+	var result bool
+	query := "SELECT EXISTS (SELECT 1 FROM follows WHERE user_id = $1 AND follow_id = $2)"
+	err := s.db.QueryRow(query, userID, followID).Scan(&result)
+	if err != nil {
+		return false, err
+	}
+	return result, nil
+}
+
+func (s FollowStore) CreateFollowRequest(userID, followID uuid.UUID) error {
+	query := "INSERT INTO follow_requests (user_id, follow_id) VALUES ($1, $2) ON CONFLICT (user_id, follow_id) DO NOTHING"
+	_, err := s.db.Exec(query, userID, followID)
+	return err
+}
+
+func (s FollowStore) GetFollowRequest(userID, followID uuid.UUID) (*model.FollowRequest, error) {
+	request := &model.FollowRequest{}
+	query := "SELECT id, user_id, follow_id, created_at FROM follow_requests WHERE user_id = $1 AND follow_id = $2"
+	err := s.db.QueryRow(query, userID, followID).Scan(&request.ID, &request.UserID, &request.FollowID, &request.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return follows, nil
+			return nil, nil
 		}
 		return nil, err
 	}
+	return request, nil
+}
+
+// GetFollowRequestsByFollowID returns the pending requests to follow a user,
+// oldest first, together with the users who sent them.
+func (s FollowStore) GetFollowRequestsByFollowID(followID uuid.UUID, pagination Pagination) ([]model.FollowRequest, error) {
+	requests := []model.FollowRequest{}
+	query := `SELECT follow_requests.id, follow_requests.user_id, follow_requests.follow_id, follow_requests.created_at,
+	users.id, users.name, users.last_name, users.username, users.avatar
+	FROM follow_requests
+	JOIN users ON users.id = follow_requests.user_id
+	WHERE follow_requests.follow_id = $1
+	ORDER BY follow_requests.created_at ASC
+	LIMIT $2 OFFSET $3`
+	rows, err := s.db.Query(query, followID, pagination.Limit, pagination.Offset)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 	for rows.Next() {
-		var follow model.Follow
-		if err := rows.Scan(&follow.ID, &follow.UserID, &follow.FollowID); err != nil {
+		request := model.FollowRequest{}
+		err := rows.Scan(&request.ID, &request.UserID, &request.FollowID, &request.CreatedAt,
+			&request.User.ID, &request.User.Name, &request.User.LastName, &request.User.Username, &request.User.Avatar)
+		if err != nil {
 			return nil, err
 		}
-		follows = append(follows, follow)
+		requests = append(requests, request)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return follows, nil
+	return requests, nil
 }
 
-func (s FollowStore) FollowUser(userID, followID uuid.UUID) error {
-	query := "INSERT INTO follows (user_id, follow_id) VALUES ($1, $2)"
-	_, err := s.db.Exec(query, userID, followID)
-	return err
+// ApproveFollowRequest turns a pending request into a follow in a single
+// transaction. It returns false if there was no such request.
+func (s FollowStore) ApproveFollowRequest(userID, followID uuid.UUID) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM follow_requests WHERE user_id = $1 AND follow_id = $2", userID, followID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	query := `INSERT INTO follows (user_id, follow_id)
+	SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM follows WHERE user_id = $1 AND follow_id = $2)`
+	if _, err := tx.Exec(query, userID, followID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
 }
-func (s FollowStore) UnFollowUser(userID, followID uuid.UUID) error {
-	query := "DELETE FROM follows WHERE user_id = $1 AND follow_id = $2"
-	_, err := s.db.Exec(query, userID, followID)
-	return err
+
+// ApproveAllFollowRequests approves every pending request to follow a user,
+// used when a private account is made public.
+func (s FollowStore) ApproveAllFollowRequests(followID uuid.UUID) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO follows (user_id, follow_id)
+	SELECT follow_requests.user_id, follow_requests.follow_id FROM follow_requests
+	WHERE follow_requests.follow_id = $1
+	AND NOT EXISTS (SELECT 1 FROM follows WHERE follows.user_id = follow_requests.user_id AND follows.follow_id = follow_requests.follow_id)`
+	if _, err := tx.Exec(query, followID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM follow_requests WHERE follow_id = $1", followID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteFollowRequest rejects or cancels a pending request. It returns false
+// if there was no such request.
+func (s FollowStore) DeleteFollowRequest(userID, followID uuid.UUID) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM follow_requests WHERE user_id = $1 AND follow_id = $2", userID, followID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetSuggestions ranks accounts for a user to follow. Accounts followed by
+// more of the accounts the user follows come first, then the accounts with
+// the most followers, so new users without follows still get suggestions.
+// The user, accounts they follow or asked to follow, and blocked or muted
+// accounts are left out.
+func (s FollowStore) GetSuggestions(userID uuid.UUID, pagination Pagination) ([]model.Suggestion, error) {
+	suggestions := []model.Suggestion{}
+	query := `
+	WITH following AS (
+		SELECT follow_id FROM follows WHERE user_id = $1
+	),
+
+	mutuals AS (
+		SELECT follows.follow_id AS user_id, COUNT(*) AS mutual_count FROM follows
+		WHERE follows.user_id IN (SELECT follow_id FROM following)
+		GROUP BY follows.follow_id
+	),
+
+	popular AS (
+		SELECT follow_id AS user_id, COUNT(*) AS follower_count FROM follows
+		GROUP BY follow_id
+	)
+
+	SELECT users.id, users.name, users.last_name, users.username, users.avatar, users.display_name, users.is_private,
+	COALESCE(mutuals.mutual_count, 0), COALESCE(popular.follower_count, 0)
+	FROM users
+	LEFT JOIN mutuals ON mutuals.user_id = users.id
+	LEFT JOIN popular ON popular.user_id = users.id
+	WHERE (mutuals.user_id IS NOT NULL OR popular.user_id IS NOT NULL)
+	AND users.id <> $1 AND users.deactivated_at IS NULL
+	AND users.id NOT IN (SELECT follow_id FROM following)
+	AND NOT EXISTS (SELECT 1 FROM follow_requests WHERE follow_requests.user_id = $1 AND follow_requests.follow_id = users.id)
+	AND ` + notBlocked("users.id", "$1") + `
+	AND NOT EXISTS (SELECT 1 FROM mutes WHERE mutes.user_id = $1 AND mutes.muted_id = users.id
+		AND (mutes.expires_at IS NULL OR mutes.expires_at > NOW()))
+	ORDER BY COALESCE(mutuals.mutual_count, 0) DESC, COALESCE(popular.follower_count, 0) DESC, users.id
+	LIMIT $2 OFFSET $3`
+	rows, err := s.db.Query(query, userID, pagination.Limit, pagination.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		suggestion := model.Suggestion{}
+		err := rows.Scan(&suggestion.User.ID, &suggestion.User.Name, &suggestion.User.LastName, &suggestion.User.Username,
+			&suggestion.User.Avatar, &suggestion.User.DisplayName, &suggestion.User.IsPrivate,
+			&suggestion.MutualCount, &suggestion.FollowerCount)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// GetMutualFollowers returns the users that follow both userID and otherID.
+func (s FollowStore) GetMutualFollowers(userID, otherID uuid.UUID, pagination Pagination) ([]model.User, error) {
+	query := `SELECT users.id, users.name, users.last_name, users.username, users.avatar, users.display_name
+	FROM users
+	JOIN follows AS user_follows ON user_follows.user_id = users.id AND user_follows.follow_id = $1
+	JOIN follows AS other_follows ON other_follows.user_id = users.id AND other_follows.follow_id = $2
+	WHERE users.deactivated_at IS NULL AND ` + notBlocked("users.id", "$1") + `
+	ORDER BY users.username
+	LIMIT $3 OFFSET $4`
+	return s.getUsers(query, userID, otherID, pagination.Limit, pagination.Offset)
+}
+
+// GetFollowersYouKnow returns the followers of otherID that userID follows.
+func (s FollowStore) GetFollowersYouKnow(userID, otherID uuid.UUID, pagination Pagination) ([]model.User, error) {
+	query := `SELECT users.id, users.name, users.last_name, users.username, users.avatar, users.display_name
+	FROM users
+	JOIN follows AS user_follows ON user_follows.user_id = $1 AND user_follows.follow_id = users.id
+	JOIN follows AS other_follows ON other_follows.user_id = users.id AND other_follows.follow_id = $2
+	WHERE users.deactivated_at IS NULL
+	ORDER BY users.username
+	LIMIT $3 OFFSET $4`
+	return s.getUsers(query, userID, otherID, pagination.Limit, pagination.Offset)
+}
+
+func (s FollowStore) getUsers(query string, args ...any) ([]model.User, error) {
+	users := []model.User{}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user := model.User{}
+		if err := rows.Scan(&user.ID, &user.Name, &user.LastName, &user.Username, &user.Avatar, &user.DisplayName); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s FollowStore) GetRelationship(userID, otherID uuid.UUID) (*model.Relationship, error) {
+	relationship := &model.Relationship{}
+	query := `SELECT
+	EXISTS (SELECT 1 FROM follows WHERE user_id = $1 AND follow_id = $2),
+	EXISTS (SELECT 1 FROM follows WHERE user_id = $2 AND follow_id = $1),
+	EXISTS (SELECT 1 FROM follow_requests WHERE user_id = $1 AND follow_id = $2)`
+	err := s.db.QueryRow(query, userID, otherID).Scan(&relationship.Following, &relationship.FollowedBy, &relationship.FollowRequested)
+	if err != nil {
+		return nil, err
+	}
+	relationship.Mutual = relationship.Following && relationship.FollowedBy
+	return relationship, nil
+}
+
+// GetFollowPath finds the shortest chain of follows from fromID to toID with
+// at most maxDepth follows, searching one level of the graph per query. The
+// search only goes through the follows fromID is allowed to see, so private
+// accounts they don't follow and blocked users are skipped. It returns the
+// users on the path including both ends, or nil if there is no such path or
+// the search read maxFollowPathFollows follows without finding one.
+func (s FollowStore) GetFollowPath(fromID, toID uuid.UUID, maxDepth int) ([]model.User, error) {
+	previous := map[uuid.UUID]uuid.UUID{fromID: uuid.Nil}
+	frontier := []string{fromID.String()}
+	found := fromID == toID
+	read := 0
+
+	query := `SELECT follows.user_id, follows.follow_id FROM follows
+	WHERE follows.user_id = ANY ($1::uuid[])
+	AND ` + visibleTo("follows.user_id", "$2") + `
+	AND ` + isActive("follows.follow_id") + `
+	AND ` + notBlocked("follows.follow_id", "$2") + `
+	LIMIT $3`
+	for depth := 0; depth < maxDepth && len(frontier) > 0 && !found; depth++ {
+		limit := maxFollowPathFollows - read
+		rows, err := s.db.Query(query, pq.Array(frontier), fromID, limit)
+		if err != nil {
+			return nil, err
+		}
+		next := []string{}
+		count := 0
+		for rows.Next() {
+			count++
+			var userID, followID uuid.UUID
+			if err := rows.Scan(&userID, &followID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if _, seen := previous[followID]; seen {
+				continue
+			}
+			previous[followID] = userID
+			next = append(next, followID.String())
+			if followID == toID {
+				found = true
+				break
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		read += count
+		if !found && read >= maxFollowPathFollows {
+			return nil, nil
+		}
+		frontier = next
+	}
+	if !found {
+		return nil, nil
+	}
+
+	ids := []uuid.UUID{}
+	for id := toID; id != uuid.Nil; id = previous[id] {
+		ids = append([]uuid.UUID{id}, ids...)
+	}
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	users, err := s.getUsers(`SELECT id, name, last_name, username, avatar, display_name FROM users WHERE id = ANY ($1::uuid[])`, pq.Array(idStrings))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]model.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	path := make([]model.User, 0, len(ids))
+	for _, id := range ids {
+		user, ok := byID[id]
+		if !ok {
+			// A user on the path was deleted in the meantime.
+			return nil, nil
+		}
+		path = append(path, user)
+	}
+	return path, nil
 }
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+type BaseIdentityStore interface {
+	CreateIdentity(identity *model.UserIdentity) error
+	CreateUserWithIdentity(user *model.User, identity *model.UserIdentity) error
+	GetIdentity(provider, subject string) (*model.UserIdentity, error)
+	GetIdentitiesByUserID(userID uuid.UUID) ([]model.UserIdentity, error)
+	CreateOIDCState(state *model.OIDCState) error
+	ConsumeOIDCState(stateHash string) (*model.OIDCState, error)
+}
+
+type IdentityStore struct {
+	DB *sql.DB
+}
+
+func NewIdentityStore(db *sql.DB) BaseIdentityStore {
+	return &IdentityStore{DB: db}
+}
+
+func (s *IdentityStore) CreateIdentity(identity *model.UserIdentity) error {
+	query := "INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	return s.DB.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+// CreateUserWithIdentity creates a user and links the identity to it in a
+// single transaction, so a user is never created without a way to log in.
+func (s *IdentityStore) CreateUserWithIdentity(user *model.User, identity *model.UserIdentity) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO users (name, last_name, username, email, password, avatar, email_verified_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id"
+	err = tx.QueryRow(query, user.Name, user.LastName, user.Username, user.Email, user.Password, user.Avatar, user.EmailVerifiedAt).Scan(&user.ID)
+	if err != nil {
+		return err
+	}
+
+	identity.UserID = user.ID
+	query = "INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	err = tx.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *IdentityStore) GetIdentity(provider, subject string) (*model.UserIdentity, error) {
+	identity := &model.UserIdentity{}
+	query := "SELECT id, user_id, provider, subject, email, created_at FROM user_identities WHERE provider = $1 AND subject = $2"
+	err := s.DB.QueryRow(query, provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (s *IdentityStore) GetIdentitiesByUserID(userID uuid.UUID) ([]model.UserIdentity, error) {
+	identities := []model.UserIdentity{}
+	query := "SELECT id, user_id, provider, subject, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at"
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		identity := model.UserIdentity{}
+		err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (s *IdentityStore) CreateOIDCState(state *model.OIDCState) error {
+	// Expired states of abandoned logins are removed on the way.
+	if _, err := s.DB.Exec("DELETE FROM oidc_states WHERE expires_at < $1", time.Now().UTC()); err != nil {
+		return err
+	}
+	query := "INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING created_at"
+	return s.DB.QueryRow(query, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt.UTC()).Scan(&state.CreatedAt)
+}
+
+// ConsumeOIDCState deletes and returns a state, so every state can only be
+// used for a single callback.
+func (s *IdentityStore) ConsumeOIDCState(stateHash string) (*model.OIDCState, error) {
+	state := &model.OIDCState{}
+	query := "DELETE FROM oidc_states WHERE state_hash = $1 RETURNING state_hash, provider, nonce, code_verifier, expires_at, created_at"
+	err := s.DB.QueryRow(query, stateHash).Scan(&state.StateHash, &state.Provider, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return state, nil
+}
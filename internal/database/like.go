@@ -14,6 +14,8 @@ type BaseLikeStore interface {
 	UnlikeComment(commentID uuid.UUID, userID uuid.UUID) error
 	IsPostLiked(postID uuid.UUID, userID uuid.UUID) (bool, error)
 	IsCommentLiked(commentID uuid.UUID, userID uuid.UUID) (bool, error)
+	GetPostLikesByUserID(userID uuid.UUID) ([]model.PostLike, error)
+	GetCommentLikesByUserID(userID uuid.UUID) ([]model.CommentLike, error)
 }
 
 type LikeStore struct {
@@ -63,3 +65,45 @@ func (s *LikeStore) IsCommentLiked(commentID uuid.UUID, userID uuid.UUID) (bool,
 	}
 	return result, nil
 }
+
+func (s *LikeStore) GetPostLikesByUserID(userID uuid.UUID) ([]model.PostLike, error) {
+	likes := []model.PostLike{}
+	query := "SELECT id, post_id, user_id FROM post_likes WHERE user_id = $1"
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		like := model.PostLike{}
+		if err := rows.Scan(&like.ID, &like.PostID, &like.UserID); err != nil {
+			return nil, err
+		}
+		likes = append(likes, like)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
+
+func (s *LikeStore) GetCommentLikesByUserID(userID uuid.UUID) ([]model.CommentLike, error) {
+	likes := []model.CommentLike{}
+	query := "SELECT id, comment_id, user_id FROM comment_likes WHERE user_id = $1"
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		like := model.CommentLike{}
+		if err := rows.Scan(&like.ID, &like.CommentID, &like.UserID); err != nil {
+			return nil, err
+		}
+		likes = append(likes, like)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
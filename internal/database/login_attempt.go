@@ -0,0 +1,103 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+)
+
+type BaseLoginAttemptStore interface {
+	GetLoginAttempt(scope, subject string) (*model.LoginAttempt, error)
+	RecordFailedLogin(scope, subject string, now, resetBefore time.Time) (*model.LoginAttempt, error)
+	LockLogin(scope, subject string, lockedUntil time.Time) error
+	ResetLoginAttempts(scope, subject string) error
+	CreateLoginLockout(lockout *model.LoginLockout) error
+	GetLoginLockoutsBySubject(scope, subject string) ([]model.LoginLockout, error)
+}
+
+type LoginAttemptStore struct {
+	DB *sql.DB
+}
+
+func NewLoginAttemptStore(db *sql.DB) BaseLoginAttemptStore {
+	return &LoginAttemptStore{DB: db}
+}
+
+func (s *LoginAttemptStore) GetLoginAttempt(scope, subject string) (*model.LoginAttempt, error) {
+	attempt := &model.LoginAttempt{}
+	query := "SELECT scope, subject, failed_count, last_failed_at, locked_until FROM login_attempts WHERE scope = $1 AND subject = $2"
+	err := s.DB.QueryRow(query, scope, subject).Scan(&attempt.Scope, &attempt.Subject, &attempt.FailedCount, &attempt.LastFailedAt, &attempt.LockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// RecordFailedLogin increments the failed login counter and returns it. If the
+// last failure happened before resetBefore, counting starts over. Counters of
+// the scope that were reset that way and aren't locked are deleted first, so
+// failures for addresses that are never tried again don't pile up.
+func (s *LoginAttemptStore) RecordFailedLogin(scope, subject string, now, resetBefore time.Time) (*model.LoginAttempt, error) {
+	query := `DELETE FROM login_attempts WHERE scope = $1 AND last_failed_at < $2
+	AND (locked_until IS NULL OR locked_until < $3)`
+	if _, err := s.DB.Exec(query, scope, resetBefore.UTC(), now.UTC()); err != nil {
+		return nil, err
+	}
+
+	attempt := &model.LoginAttempt{}
+	query = `INSERT INTO login_attempts (scope, subject, failed_count, last_failed_at) VALUES ($1, $2, 1, $3)
+	ON CONFLICT (scope, subject) DO UPDATE SET
+	failed_count = CASE WHEN login_attempts.last_failed_at < $4 THEN 1 ELSE login_attempts.failed_count + 1 END,
+	last_failed_at = EXCLUDED.last_failed_at
+	RETURNING scope, subject, failed_count, last_failed_at, locked_until`
+	err := s.DB.QueryRow(query, scope, subject, now.UTC(), resetBefore.UTC()).Scan(&attempt.Scope, &attempt.Subject, &attempt.FailedCount, &attempt.LastFailedAt, &attempt.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (s *LoginAttemptStore) LockLogin(scope, subject string, lockedUntil time.Time) error {
+	query := "UPDATE login_attempts SET locked_until = $1 WHERE scope = $2 AND subject = $3"
+	_, err := s.DB.Exec(query, lockedUntil.UTC(), scope, subject)
+	return err
+}
+
+func (s *LoginAttemptStore) ResetLoginAttempts(scope, subject string) error {
+	query := "DELETE FROM login_attempts WHERE scope = $1 AND subject = $2"
+	_, err := s.DB.Exec(query, scope, subject)
+	return err
+}
+
+func (s *LoginAttemptStore) CreateLoginLockout(lockout *model.LoginLockout) error {
+	query := "INSERT INTO login_lockouts (scope, subject, user_id, ip_address, failed_count, locked_until) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at"
+	return s.DB.QueryRow(query, lockout.Scope, lockout.Subject, lockout.UserID, lockout.IPAddress, lockout.FailedCount, lockout.LockedUntil.UTC()).Scan(&lockout.ID, &lockout.CreatedAt)
+}
+
+func (s *LoginAttemptStore) GetLoginLockoutsBySubject(scope, subject string) ([]model.LoginLockout, error) {
+	lockouts := []model.LoginLockout{}
+	query := `SELECT id, scope, subject, user_id, ip_address, failed_count, locked_until, created_at FROM login_lockouts
+	WHERE scope = $1 AND subject = $2
+	ORDER BY created_at DESC`
+	rows, err := s.DB.Query(query, scope, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		lockout := model.LoginLockout{}
+		err := rows.Scan(&lockout.ID, &lockout.Scope, &lockout.Subject, &lockout.UserID, &lockout.IPAddress, &lockout.FailedCount, &lockout.LockedUntil, &lockout.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lockouts, nil
+}
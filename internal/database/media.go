@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+// ErrMediaAttached is returned when media that is already attached to a post
+// or a comment is attached again.
+var ErrMediaAttached = errors.New("media already attached")
+
+type BaseMediaStore interface {
+	CreateMedia(media *model.Media) error
+	GetMediaByID(id uuid.UUID) (*model.Media, error)
+	GetOrphanedMedia(unattachedBefore time.Time, limit int) ([]model.Media, error)
+	DeleteOrphanedMedia(id uuid.UUID, deleteBlobs func(media model.Media) error) (bool, error)
+}
+
+type MediaStore struct {
+	DB *sql.DB
+}
+
+func NewMediaStore(db *sql.DB) BaseMediaStore {
+	return &MediaStore{DB: db}
+}
+
+const mediaColumns = "id, user_id, blob_key, thumbnail_key, content_type, width, height, size, post_id, comment_id, attached_at, created_at"
+
+func scanMedia(row interface{ Scan(...any) error }, media *model.Media) error {
+	return row.Scan(&media.ID, &media.UserID, &media.BlobKey, &media.ThumbnailKey, &media.ContentType, &media.Width, &media.Height, &media.Size, &media.PostID, &media.CommentID, &media.AttachedAt, &media.CreatedAt)
+}
+
+func (s *MediaStore) CreateMedia(media *model.Media) error {
+	query := `INSERT INTO media (user_id, blob_key, thumbnail_key, content_type, width, height, size)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ` + mediaColumns
+	return scanMedia(s.DB.QueryRow(query, media.UserID, media.BlobKey, media.ThumbnailKey, media.ContentType, media.Width, media.Height, media.Size), media)
+}
+
+func (s *MediaStore) GetMediaByID(id uuid.UUID) (*model.Media, error) {
+	media := &model.Media{}
+	query := "SELECT " + mediaColumns + " FROM media WHERE id = $1"
+	if err := scanMedia(s.DB.QueryRow(query, id), media); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return media, nil
+}
+
+// GetOrphanedMedia returns media that isn't attached to anything: uploads
+// older than unattachedBefore that were never attached, and media whose post
+// or comment was deleted.
+func (s *MediaStore) GetOrphanedMedia(unattachedBefore time.Time, limit int) ([]model.Media, error) {
+	query := "SELECT " + mediaColumns + ` FROM media
+	WHERE post_id IS NULL AND comment_id IS NULL
+	AND (attached_at IS NOT NULL OR created_at < $1)
+	ORDER BY created_at ASC
+	LIMIT $2`
+	rows, err := s.DB.Query(query, unattachedBefore.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []model.Media{}
+	for rows.Next() {
+		m := model.Media{}
+		if err := scanMedia(rows, &m); err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// DeleteOrphanedMedia deletes media if it still isn't attached to anything.
+// Its row stays locked while deleteBlobs deletes its files, so it can't be
+// attached in the meantime, and is only deleted if they were. It returns false
+// without calling deleteBlobs if the media was attached since it was listed or
+// is being attached.
+func (s *MediaStore) DeleteOrphanedMedia(id uuid.UUID, deleteBlobs func(media model.Media) error) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	media := model.Media{}
+	query := "SELECT " + mediaColumns + ` FROM media
+	WHERE id = $1 AND post_id IS NULL AND comment_id IS NULL
+	FOR UPDATE SKIP LOCKED`
+	if err := scanMedia(tx.QueryRow(query, id), &media); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := deleteBlobs(media); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM media WHERE id = $1", id); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// attachMedia attaches media to a post or a comment inside tx.
+func attachMedia(tx *sql.Tx, mediaID uuid.UUID, postID, commentID *uuid.UUID) error {
+	query := "UPDATE media SET post_id = $1, comment_id = $2, attached_at = NOW() WHERE id = $3 AND attached_at IS NULL"
+	result, err := tx.Exec(query, postID, commentID, mediaID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrMediaAttached
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/fatihesergg/go_social/internal/model"
+)
+
+type BaseModerationStore interface {
+	CreateModerationAction(action *model.ModerationAction) error
+	GetModerationActions(pagination Pagination) ([]model.ModerationAction, error)
+}
+
+type ModerationStore struct {
+	DB *sql.DB
+}
+
+func NewModerationStore(db *sql.DB) BaseModerationStore {
+	return &ModerationStore{DB: db}
+}
+
+func (s *ModerationStore) CreateModerationAction(action *model.ModerationAction) error {
+	query := "INSERT INTO moderation_actions (moderator_id, action, target_type, target_id, target_user_id, previous_content) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at"
+	return s.DB.QueryRow(query, action.ModeratorID, action.Action, action.TargetType, action.TargetID, action.TargetUserID, action.PreviousContent).Scan(&action.ID, &action.CreatedAt)
+}
+
+func (s *ModerationStore) GetModerationActions(pagination Pagination) ([]model.ModerationAction, error) {
+	actions := []model.ModerationAction{}
+	query := `SELECT id, moderator_id, action, target_type, target_id, target_user_id, previous_content, created_at FROM moderation_actions
+	ORDER BY created_at DESC
+	LIMIT $1 OFFSET $2`
+	rows, err := s.DB.Query(query, pagination.Limit, pagination.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		action := model.ModerationAction{}
+		err := rows.Scan(&action.ID, &action.ModeratorID, &action.Action, &action.TargetType, &action.TargetID, &action.TargetUserID, &action.PreviousContent, &action.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+type BaseMuteStore interface {
+	MuteUser(mute *model.Mute) error
+	UnmuteUser(userID, mutedID uuid.UUID) (bool, error)
+	GetMutesByUserID(userID uuid.UUID) ([]model.Mute, error)
+	MuteKeyword(keyword *model.MutedKeyword) error
+	UnmuteKeyword(id, userID uuid.UUID) (bool, error)
+	GetMutedKeywordsByUserID(userID uuid.UUID) ([]model.MutedKeyword, error)
+}
+
+type MuteStore struct {
+	DB *sql.DB
+}
+
+func NewMuteStore(db *sql.DB) BaseMuteStore {
+	return &MuteStore{DB: db}
+}
+
+// MuteUser mutes a user, or changes the expiry of an existing mute.
+func (s *MuteStore) MuteUser(mute *model.Mute) error {
+	var expiresAt *time.Time
+	if mute.ExpiresAt != nil {
+		utc := mute.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+	query := `INSERT INTO mutes (user_id, muted_id, expires_at) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, muted_id) DO UPDATE SET expires_at = EXCLUDED.expires_at
+	RETURNING id, created_at`
+	return s.DB.QueryRow(query, mute.UserID, mute.MutedID, expiresAt).Scan(&mute.ID, &mute.CreatedAt)
+}
+
+func (s *MuteStore) UnmuteUser(userID, mutedID uuid.UUID) (bool, error) {
+	result, err := s.DB.Exec("DELETE FROM mutes WHERE user_id = $1 AND muted_id = $2", userID, mutedID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetMutesByUserID returns the mutes of a user that haven't expired, newest
+// first.
+func (s *MuteStore) GetMutesByUserID(userID uuid.UUID) ([]model.Mute, error) {
+	mutes := []model.Mute{}
+	query := `SELECT mutes.id, mutes.user_id, mutes.muted_id, mutes.expires_at, mutes.created_at,
+	users.id, users.name, users.last_name, users.username, users.avatar
+	FROM mutes
+	JOIN users ON users.id = mutes.muted_id
+	WHERE mutes.user_id = $1 AND (mutes.expires_at IS NULL OR mutes.expires_at > NOW())
+	ORDER BY mutes.created_at DESC`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		mute := model.Mute{}
+		err := rows.Scan(&mute.ID, &mute.UserID, &mute.MutedID, &mute.ExpiresAt, &mute.CreatedAt,
+			&mute.User.ID, &mute.User.Name, &mute.User.LastName, &mute.User.Username, &mute.User.Avatar)
+		if err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, mute)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return mutes, nil
+}
+
+// MuteKeyword mutes a keyword, or changes the expiry of an existing one.
+// Keywords are expected to be lower case.
+func (s *MuteStore) MuteKeyword(keyword *model.MutedKeyword) error {
+	var expiresAt *time.Time
+	if keyword.ExpiresAt != nil {
+		utc := keyword.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+	query := `INSERT INTO muted_keywords (user_id, keyword, expires_at) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, keyword) DO UPDATE SET expires_at = EXCLUDED.expires_at
+	RETURNING id, created_at`
+	return s.DB.QueryRow(query, keyword.UserID, keyword.Keyword, expiresAt).Scan(&keyword.ID, &keyword.CreatedAt)
+}
+
+func (s *MuteStore) UnmuteKeyword(id, userID uuid.UUID) (bool, error) {
+	result, err := s.DB.Exec("DELETE FROM muted_keywords WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetMutedKeywordsByUserID returns the muted keywords of a user that haven't
+// expired, newest first.
+func (s *MuteStore) GetMutedKeywordsByUserID(userID uuid.UUID) ([]model.MutedKeyword, error) {
+	keywords := []model.MutedKeyword{}
+	query := `SELECT id, user_id, keyword, expires_at, created_at FROM muted_keywords
+	WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+	ORDER BY created_at DESC`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		keyword := model.MutedKeyword{}
+		if err := rows.Scan(&keyword.ID, &keyword.UserID, &keyword.Keyword, &keyword.ExpiresAt, &keyword.CreatedAt); err != nil {
+			return nil, err
+		}
+		keywords = append(keywords, keyword)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keywords, nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type BasePersonalAccessTokenStore interface {
+	CreatePersonalAccessToken(token *model.PersonalAccessToken) error
+	GetPersonalAccessTokenByHash(tokenHash string) (*model.PersonalAccessToken, error)
+	GetPersonalAccessTokensByUserID(userID uuid.UUID) ([]model.PersonalAccessToken, error)
+	TouchPersonalAccessToken(id uuid.UUID) error
+	RevokePersonalAccessToken(id, userID uuid.UUID) (bool, error)
+}
+
+type PersonalAccessTokenStore struct {
+	DB *sql.DB
+}
+
+func NewPersonalAccessTokenStore(db *sql.DB) BasePersonalAccessTokenStore {
+	return &PersonalAccessTokenStore{DB: db}
+}
+
+func (s *PersonalAccessTokenStore) CreatePersonalAccessToken(token *model.PersonalAccessToken) error {
+	var expiresAt *time.Time
+	if token.ExpiresAt != nil {
+		utc := token.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+	query := "INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at"
+	return s.DB.QueryRow(query, token.UserID, token.Name, token.TokenHash, pq.Array(token.Scopes), expiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (s *PersonalAccessTokenStore) GetPersonalAccessTokenByHash(tokenHash string) (*model.PersonalAccessToken, error) {
+	token := &model.PersonalAccessToken{}
+	query := "SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM personal_access_tokens WHERE token_hash = $1"
+	err := s.DB.QueryRow(query, tokenHash).Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, pq.Array(&token.Scopes), &token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+func (s *PersonalAccessTokenStore) GetPersonalAccessTokensByUserID(userID uuid.UUID) ([]model.PersonalAccessToken, error) {
+	tokens := []model.PersonalAccessToken{}
+	query := `SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM personal_access_tokens
+	WHERE user_id = $1 AND revoked_at IS NULL
+	ORDER BY created_at DESC`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token := model.PersonalAccessToken{}
+		err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, pq.Array(&token.Scopes), &token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// TouchPersonalAccessToken updates the last used time of a token, at most once
+// a minute like TouchSession.
+func (s *PersonalAccessTokenStore) TouchPersonalAccessToken(id uuid.UUID) error {
+	query := "UPDATE personal_access_tokens SET last_used_at = NOW() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+// RevokePersonalAccessToken revokes a token of the given user. It returns false
+// if the user has no such active token.
+func (s *PersonalAccessTokenStore) RevokePersonalAccessToken(id, userID uuid.UUID) (bool, error) {
+	query := "UPDATE personal_access_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL"
+	result, err := s.DB.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -2,20 +2,33 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/fatihesergg/go_social/internal/model"
 	"github.com/google/uuid"
 )
 
+// ErrPostPublished is returned when a post that was already published is
+// scheduled or published again.
+var ErrPostPublished = errors.New("post already published")
+
 type BasePostStore interface {
 	GetPostByID(postID uuid.UUID) (*model.Post, error)
 	GetPosts(pagination Pagination, search Search, userID uuid.UUID) ([]model.Post, error)
 	GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, error)
 	GetPostsByUserID(userID uuid.UUID, pagination Pagination, search Search) ([]model.Post, error)
 	CreatePost(post *model.Post) error
-	UpdatePost(post *model.Post) error
+	UpdatePost(post *model.Post, moderated bool) error
 	DeletePost(id uuid.UUID) error
+	GetPostRevisions(postID uuid.UUID) ([]model.Revision, error)
+	IsContentVisible(postID, authorID, viewerID uuid.UUID) (bool, error)
+	GetDraftsByUserID(userID uuid.UUID, pagination Pagination) ([]model.Post, error)
+	SchedulePost(id uuid.UUID, publishAt time.Time) error
+	UnschedulePost(id uuid.UUID) error
+	PublishPost(id uuid.UUID) error
+	PublishDuePosts(now time.Time, limit int) ([]uuid.UUID, error)
 }
 
 type PostStore struct {
@@ -42,6 +55,13 @@ func (s *PostStore) GetPosts(pagination Pagination, search Search, userID uuid.U
 	WITH limited_posts AS (
 		SELECT * FROM posts
 		WHERE content ILIKE '%' || $1 || '%'
+		AND status = 'published'
+		AND ($6 = '' OR EXISTS (SELECT 1 FROM post_tags JOIN tags ON tags.id = post_tags.tag_id
+			WHERE post_tags.post_id = posts.id AND tags.name = $6))
+		AND ` + visibleTo("posts.user_id", "$4") + `
+		AND ` + isActive("posts.user_id") + `
+		AND ` + notBlocked("posts.user_id", "$4") + `
+		AND ` + notMuted("posts.user_id", "posts.content", "$4") + `
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	),
@@ -65,19 +85,33 @@ func (s *PostStore) GetPosts(pagination Pagination, search Search, userID uuid.U
 		SELECT follow_id
 		FROM follows
 		WHERE user_id = $5
+	),
+
+	reposts_count AS (
+		SELECT post_id, COUNT(*) as total_reposts FROM reposts
+		GROUP BY post_id
+	),
+
+	user_reposts AS (
+		SELECT post_id FROM reposts
+		WHERE user_id = $4
 	)
 
 
 
 	SELECT 
-	posts.id,posts.content,posts.created_at,posts.updated_at,
+	posts.id,posts.content,posts.image,posts.thumbnail,posts.created_at,posts.updated_at,(posts.updated_at > posts.created_at),
     post_user.id,post_user.name,post_user.last_name,post_user.username,
 	
 	COALESCE(likes_count.total_likes,0),
 	COALESCE(comments_count.total_comments,0),
+	COALESCE(reposts_count.total_reposts,0),
 
 	(user_likes.post_id IS NOT NULL),
-	(user_follows.follow_id IS NOT NULL)
+	(user_follows.follow_id IS NOT NULL),
+	(user_reposts.post_id IS NOT NULL),
+
+	` + quotedPostColumns + `
 
     FROM limited_posts as posts 
     JOIN users as post_user ON posts.user_id = post_user.id
@@ -85,9 +119,12 @@ func (s *PostStore) GetPosts(pagination Pagination, search Search, userID uuid.U
 	LEFT JOIN comments_count ON comments_count.post_id = posts.id
 	LEFT JOIN user_likes ON user_likes.post_id = posts.id
 	LEFT JOIN user_follows ON user_follows.follow_id = post_user.id
-	`
+	LEFT JOIN reposts_count ON reposts_count.post_id = posts.id
+	LEFT JOIN user_reposts ON user_reposts.post_id = posts.id
+	LEFT JOIN posts AS quoted ON quoted.id = posts.quote_id AND ` + quotedPostVisibleTo("$4") + `
+	LEFT JOIN users AS quoted_user ON quoted_user.id = quoted.user_id`
 
-	rows, err := s.DB.Query(query, search.Query, pagination.Limit, pagination.Offset, userID, userID)
+	rows, err := s.DB.Query(query, search.Query, pagination.Limit, pagination.Offset, userID, userID, search.Tag)
 	if err != nil {
 		fmt.Println(err)
 		if err == sql.ErrNoRows {
@@ -101,12 +138,13 @@ func (s *PostStore) GetPosts(pagination Pagination, search Search, userID uuid.U
 		post := model.Post{}
 		var commentCount, postLikeCount *int
 		var isLiked, isFollowing *bool
+		var quote quotedPost
 
-		err := rows.Scan(&post.ID, &post.Content, &post.CreatedAt, &post.UpdatedAt,
+		err := rows.Scan(append([]any{&post.ID, &post.Content, &post.Image, &post.Thumbnail, &post.CreatedAt, &post.UpdatedAt, &post.Edited,
 			&post.User.ID, &post.User.Name, &post.User.LastName, &post.User.Username,
-			&postLikeCount, &commentCount,
-			&isLiked, &isFollowing,
-		)
+			&postLikeCount, &commentCount, &post.RepostCount,
+			&isLiked, &isFollowing, &post.IsReposted,
+		}, quote.dest()...)...)
 		if err != nil {
 			fmt.Println(err)
 			return nil, err
@@ -115,6 +153,7 @@ func (s *PostStore) GetPosts(pagination Pagination, search Search, userID uuid.U
 		post.CommentCount = *commentCount
 		post.IsLiked = *isLiked
 		post.IsFollowing = *isFollowing
+		post.Quote = quote.post()
 
 		posts = append(posts, post)
 
@@ -128,9 +167,9 @@ func (s *PostStore) GetPosts(pagination Pagination, search Search, userID uuid.U
 }
 
 func (s *PostStore) GetPostByID(postID uuid.UUID) (*model.Post, error) {
-	var result *model.Post
-	query := `SELECT * FROM posts WHERE id = $1`
-	err := s.DB.QueryRow(query, postID).Scan(&result)
+	result := &model.Post{}
+	query := `SELECT id, content, user_id, status, publish_at, created_at, updated_at FROM posts WHERE id = $1`
+	err := s.DB.QueryRow(query, postID).Scan(&result.ID, &result.Content, &result.UserID, &result.Status, &result.PublishAt, &result.CreatedAt, &result.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -178,13 +217,26 @@ func (s *PostStore) GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, e
 		user_post_likes AS  (
 			SELECT post_id FROM post_likes
 			WHERE user_id  = $1
+		),
+
+		post_repost_count AS (
+			SELECT post_id,COUNT(*) AS total_post_repost FROM reposts
+			GROUP BY post_id
+		),
+
+		user_post_reposts AS (
+			SELECT post_id FROM reposts
+			WHERE user_id = $1
 		)
 
 		SELECT 
 		posts.id,
 		posts.content,
+		posts.image,
+		posts.thumbnail,
 		posts.created_at,
 		posts.updated_at,
+		(posts.updated_at > posts.created_at) AS is_post_edited,
 
         post_user.id,
 		post_user.name,
@@ -193,6 +245,11 @@ func (s *PostStore) GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, e
 
 		comments.id,
 		comments.content,
+		comments.image,
+		comments.thumbnail,
+		comments.created_at,
+		comments.updated_at,
+		(comments.updated_at > comments.created_at) AS is_comment_edited,
 
 		comment_user.id,
 		comment_user.name,
@@ -208,11 +265,16 @@ func (s *PostStore) GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, e
 		(user_comment_likes.comment_id IS NOT NULL) is_comment_liked,
 
 		(post_follows.follow_id IS NOT NULL) AS is_post_following,
-		(comment_follows.follow_id IS NOT NULL) AS is_comment_following
+		(comment_follows.follow_id IS NOT NULL) AS is_comment_following,
+
+		COALESCE(post_repost_count.total_post_repost,0) AS total_post_repost,
+		(user_post_reposts.post_id IS NOT NULL) AS is_post_reposted,
+
+		` + quotedPostColumns + `
 
         FROM posts
         JOIN users AS post_user ON posts.user_id = post_user.id
-		LEFT JOIN comments ON comments.post_id = posts.id
+		LEFT JOIN comments ON comments.post_id = posts.id AND ` + isActive("comments.user_id") + ` AND ` + notBlocked("comments.user_id", "$1") + `
 		LEFT JOIN users AS comment_user ON comment_user.id = comments.user_id
 		LEFT JOIN user_follows AS post_follows ON post_follows.follow_id = post_user.id
 		LEFT JOIN user_follows AS comment_follows ON comment_follows.follow_id = comments.user_id
@@ -222,8 +284,12 @@ func (s *PostStore) GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, e
 		LEFT JOIN reply_count ON  reply_count.comment_id = comments.id
 		LEFT JOIN comment_like_count ON  comment_like_count.comment_id = comments.id
 		LEFT JOIN post_like_count ON  post_like_count.post_id = posts.id
+		LEFT JOIN post_repost_count ON post_repost_count.post_id = posts.id
+		LEFT JOIN user_post_reposts ON user_post_reposts.post_id = posts.id
+		LEFT JOIN posts AS quoted ON quoted.id = posts.quote_id AND ` + quotedPostVisibleTo("$1") + `
+		LEFT JOIN users AS quoted_user ON quoted_user.id = quoted.user_id
 
-        WHERE posts.id = $2`
+        WHERE posts.id = $2 AND (posts.status = 'published' OR posts.user_id = $1) AND post_user.deactivated_at IS NULL AND ` + visibleTo("posts.user_id", "$1") + ` AND ` + notBlocked("posts.user_id", "$1")
 
 	rows, err := s.DB.Query(postQuery, userID, postID)
 	if err != nil {
@@ -233,23 +299,26 @@ func (s *PostStore) GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, e
 	defer rows.Close()
 
 	post := &model.Post{}
+	var quote quotedPost
 	for rows.Next() {
 		var commentID, commentUserID *uuid.UUID
-		var commentContent *string
+		var commentContent, commentImage, commentThumbnail, commentCreatedAt, commentUpdatedAt *string
+		var isCommentEdited *bool
 		var commentUserName *string
 		var commentUserLastName *string
 		var commentUserUsername *string
 		var replyCount, commentLikeCount *int
 		var isCommentFollowing, isCommentLiked *bool
 
-		err := rows.Scan(&post.ID, &post.Content, &post.CreatedAt, &post.UpdatedAt,
+		err := rows.Scan(append([]any{&post.ID, &post.Content, &post.Image, &post.Thumbnail, &post.CreatedAt, &post.UpdatedAt, &post.Edited,
 			&post.User.ID, &post.User.Name, &post.User.LastName, &post.User.Username,
-			&commentID, &commentContent,
+			&commentID, &commentContent, &commentImage, &commentThumbnail, &commentCreatedAt, &commentUpdatedAt, &isCommentEdited,
 			&commentUserID, &commentUserName, &commentUserLastName, &commentUserUsername,
 			&post.CommentCount, &replyCount, &commentLikeCount, &post.LikeCount,
 			&post.IsLiked, &isCommentLiked,
 			&post.IsFollowing, &isCommentFollowing,
-		)
+			&post.RepostCount, &post.IsReposted,
+		}, quote.dest()...)...)
 		if err != nil {
 			fmt.Println(err)
 			return nil, err
@@ -257,8 +326,13 @@ func (s *PostStore) GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, e
 		if commentID != nil {
 
 			comment := &model.Comment{
-				ID:      *commentID,
-				Content: *commentContent,
+				ID:        *commentID,
+				Content:   *commentContent,
+				Image:     commentImage,
+				Thumbnail: commentThumbnail,
+				CreatedAt: *commentCreatedAt,
+				UpdatedAt: *commentUpdatedAt,
+				Edited:    *isCommentEdited,
 				User: model.User{
 					ID:       *commentUserID,
 					Name:     *commentUserName,
@@ -284,6 +358,7 @@ func (s *PostStore) GetPostDetailsByID(postID, userID uuid.UUID) (*model.Post, e
 	if post.ID == uuid.Nil {
 		return nil, nil
 	}
+	post.Quote = quote.post()
 
 	return post, nil
 
@@ -296,11 +371,12 @@ func (s *PostStore) GetPostsByUserID(userID uuid.UUID, pagination Pagination, se
 	WITH limited_posts AS (
 		SELECT * FROM posts
 		WHERE user_id = $1
+		AND status = 'published'
 		AND content ILIKE '%' || $2 || '%'
 		ORDER BY created_at DESC
 		LIMIT $3 OFFSET $4
 	)
-	SELECT posts.id, posts.content, posts.created_at, posts.updated_at,
+	SELECT posts.id, posts.content, posts.image, posts.thumbnail, posts.created_at, posts.updated_at, (posts.updated_at > posts.created_at),
         users.id,users.name, users.last_name, users.username,
 		comments.id,comments.content,comment_user.name, comment_user.last_name, comment_user.username
         FROM limited_posts as posts
@@ -322,7 +398,7 @@ func (s *PostStore) GetPostsByUserID(userID uuid.UUID, pagination Pagination, se
 		var commentUserName *string
 		var commentUserLastName *string
 		var commentUserUsername *string
-		err := rows.Scan(&post.ID, &post.Content, &post.CreatedAt, &post.UpdatedAt,
+		err := rows.Scan(&post.ID, &post.Content, &post.Image, &post.Thumbnail, &post.CreatedAt, &post.UpdatedAt, &post.Edited,
 			&post.User.ID, &post.User.Name, &post.User.LastName, &post.User.Username, &commentID, &commentContent,
 			&commentUserName, &commentUserLastName, &commentUserUsername,
 		)
@@ -361,33 +437,202 @@ func (s *PostStore) GetPostsByUserID(userID uuid.UUID, pagination Pagination, se
 	return posts, nil
 }
 
+// CreatePost stores a post with its hashtags and attaches its media in a
+// single transaction.
+// Posts without a status are published right away. If the media was attached
+// to something else in the meantime, nothing is stored and ErrMediaAttached is
+// returned.
 func (s *PostStore) CreatePost(post *model.Post) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	query := "INSERT INTO posts (content, user_id) VALUES ($1, $2)"
+	if post.Status == "" {
+		post.Status = model.PostStatusPublished
+	}
+	query := "INSERT INTO posts (content, image, thumbnail, user_id, status, quote_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at"
+	if err := tx.QueryRow(query, post.Content, post.Image, post.Thumbnail, post.UserID.String(), post.Status, post.QuoteID).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt); err != nil {
+		return err
+	}
+	if post.MediaID != nil {
+		if err := attachMedia(tx, *post.MediaID, &post.ID, nil); err != nil {
+			return err
+		}
+	}
+	if err := setPostTags(tx, post.ID, post.Content); err != nil {
+		return err
+	}
 
-	_, err := s.DB.Exec(query, post.Content, post.UserID.String())
+	return tx.Commit()
+}
+
+// UpdatePost replaces the content of a post and its hashtags and keeps the
+// previous version as a revision. moderated marks an edit by a moderator.
+func (s *PostStore) UpdatePost(post *model.Post, moderated bool) error {
+	return revise(s.DB,
+		"SELECT content FROM posts WHERE id = $1 FOR UPDATE",
+		"INSERT INTO post_revisions (post_id, content, created_at, moderated) SELECT id, content, updated_at, $2 FROM posts WHERE id = $1",
+		"UPDATE posts SET content = $1, updated_at = NOW() WHERE id = $2",
+		post.ID, post.Content, moderated,
+		func(tx *sql.Tx) error { return setPostTags(tx, post.ID, post.Content) })
+}
+
+func (s *PostStore) DeletePost(id uuid.UUID) error {
+	query := "DELETE FROM posts WHERE id = $1"
+	_, err := s.DB.Exec(query, id)
 	if err != nil {
 		return err
 	}
-
 	return nil
 }
 
-func (s *PostStore) UpdatePost(post *model.Post) error {
-	query := "UPDATE posts SET content = $1 WHERE id = $2"
-	_, err := s.DB.Exec(query, post.Content, post.ID)
+// GetPostRevisions returns the earlier versions of a post, newest first.
+// Versions a moderator edited away are left out.
+func (s *PostStore) GetPostRevisions(postID uuid.UUID) ([]model.Revision, error) {
+	query := "SELECT id, content, created_at FROM post_revisions WHERE post_id = $1 AND NOT moderated ORDER BY created_at DESC"
+	return getRevisions(s.DB, query, postID)
+}
+
+// IsContentVisible reports whether the viewer can see content that authorID
+// wrote on a post: the post must be published or the viewer's own draft and
+// visible to the viewer, and neither its author nor authorID may be
+// deactivated or blocked by or blocking the viewer.
+func (s *PostStore) IsContentVisible(postID, authorID, viewerID uuid.UUID) (bool, error) {
+	var visible bool
+	query := `SELECT EXISTS (
+		SELECT 1 FROM posts
+		WHERE posts.id = $1
+		AND (posts.status = 'published' OR posts.user_id = $3)
+		AND ` + visibleTo("posts.user_id", "$3") + `
+		AND ` + isActive("posts.user_id") + `
+		AND ` + notBlocked("posts.user_id", "$3") + `
+		AND ` + isActive("$2") + `
+		AND ` + notBlocked("$2", "$3") + `
+	)`
+	if err := s.DB.QueryRow(query, postID, authorID, viewerID).Scan(&visible); err != nil {
+		return false, err
+	}
+	return visible, nil
+}
+
+// GetDraftsByUserID returns the drafts and scheduled posts of a user, most
+// recently saved first.
+func (s *PostStore) GetDraftsByUserID(userID uuid.UUID, pagination Pagination) ([]model.Post, error) {
+	query := `SELECT id, content, image, thumbnail, user_id, status, publish_at, created_at, updated_at FROM posts
+	WHERE user_id = $1 AND status <> 'published'
+	ORDER BY updated_at DESC
+	LIMIT $2 OFFSET $3`
+	rows, err := s.DB.Query(query, userID, pagination.Limit, pagination.Offset)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	posts := []model.Post{}
+	for rows.Next() {
+		post := model.Post{}
+		err := rows.Scan(&post.ID, &post.Content, &post.Image, &post.Thumbnail, &post.UserID, &post.Status, &post.PublishAt, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
 }
 
-func (s *PostStore) DeletePost(id uuid.UUID) error {
-	query := "DELETE FROM posts WHERE id = $1"
-	_, err := s.DB.Exec(query, id)
+// SchedulePost schedules a draft to be published at publishAt, or moves the
+// publish time of a scheduled post.
+func (s *PostStore) SchedulePost(id uuid.UUID, publishAt time.Time) error {
+	query := "UPDATE posts SET status = 'scheduled', publish_at = $1 WHERE id = $2 AND status <> 'published'"
+	return s.updateUnpublished(query, publishAt.UTC(), id)
+}
+
+// UnschedulePost turns a scheduled post back into a draft.
+func (s *PostStore) UnschedulePost(id uuid.UUID) error {
+	query := "UPDATE posts SET status = 'draft', publish_at = NULL WHERE id = $1 AND status <> 'published'"
+	return s.updateUnpublished(query, id)
+}
+
+func (s *PostStore) updateUnpublished(query string, args ...any) error {
+	result, err := s.DB.Exec(query, args...)
 	if err != nil {
 		return err
 	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrPostPublished
+	}
+	return nil
+}
+
+// PublishPost publishes a draft or scheduled post right away. The post is
+// dated to the time it is published and the revisions of the draft are
+// dropped, so it doesn't show up as edited.
+func (s *PostStore) PublishPost(id uuid.UUID) error {
+	var published int
+	query := `WITH published AS (
+		UPDATE posts SET status = 'published', publish_at = NULL, created_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status <> 'published'
+		RETURNING id
+	), revisions AS (
+		DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM published)
+	)
+	SELECT COUNT(*) FROM published`
+	if err := s.DB.QueryRow(query, id).Scan(&published); err != nil {
+		return err
+	}
+	if published == 0 {
+		return ErrPostPublished
+	}
 	return nil
 }
+
+// PublishDuePosts publishes up to limit scheduled posts whose publish time is
+// before now and returns their IDs. Posts are dated to their publish time.
+// The status change is a single statement and rows locked by another server
+// are skipped, so every post is published exactly once. Posts that came due
+// while no server was running are published on the next call. Posts of
+// deactivated users wait until the account is reactivated.
+func (s *PostStore) PublishDuePosts(now time.Time, limit int) ([]uuid.UUID, error) {
+	query := `WITH published AS (
+		UPDATE posts SET status = 'published', created_at = publish_at, updated_at = publish_at, publish_at = NULL
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= $1
+			AND ` + isActive("posts.user_id") + `
+			ORDER BY publish_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	), revisions AS (
+		DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM published)
+	)
+	SELECT id FROM published`
+	rows, err := s.DB.Query(query, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
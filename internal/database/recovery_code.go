@@ -0,0 +1,62 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type BaseRecoveryCodeStore interface {
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	DeleteRecoveryCodes(userID uuid.UUID) error
+}
+
+type RecoveryCodeStore struct {
+	DB *sql.DB
+}
+
+func NewRecoveryCodeStore(db *sql.DB) BaseRecoveryCodeStore {
+	return &RecoveryCodeStore{DB: db}
+}
+
+// ReplaceRecoveryCodes deletes the existing recovery codes of a user and
+// stores the new ones in a single transaction.
+func (s *RecoveryCodeStore) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks a recovery code as used. It returns false if the code
+// doesn't exist or was already used.
+func (s *RecoveryCodeStore) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	query := "UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+	result, err := s.DB.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (s *RecoveryCodeStore) DeleteRecoveryCodes(userID uuid.UUID) error {
+	_, err := s.DB.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	return err
+}
@@ -9,10 +9,12 @@ import (
 
 type BaseReplyStore interface {
 	CreateReply(reply *model.Reply) error
-	UpdateReply(reply *model.Reply) error
-	GetRepliesByCommentID(commentID uuid.UUID) ([]model.Reply, error)
+	UpdateReply(reply *model.Reply, moderated bool) error
+	GetRepliesByCommentID(commentID, userID uuid.UUID) ([]model.Reply, error)
 	GetReplyByID(replyID uuid.UUID) (*model.Reply, error)
 	DeleteReply(replyID uuid.UUID) error
+	GetRepliesByUserID(userID uuid.UUID) ([]model.Reply, error)
+	GetReplyRevisions(replyID uuid.UUID) ([]model.Revision, error)
 }
 
 type ReplyStore struct {
@@ -31,10 +33,21 @@ func (rs *ReplyStore) CreateReply(reply *model.Reply) error {
 	return err
 }
 
-func (rs *ReplyStore) UpdateReply(reply *model.Reply) error {
-	query := "UPDATE replies SET comment_id = $1, user_id = $2, message = $3 WHERE id = $4"
-	_, err := rs.DB.Exec(query, reply.CommentID, reply.UserID, reply.Message, reply.ID)
-	return err
+// UpdateReply replaces the message of a reply and keeps the previous version
+// as a revision. moderated marks an edit by a moderator.
+func (rs *ReplyStore) UpdateReply(reply *model.Reply, moderated bool) error {
+	return revise(rs.DB,
+		"SELECT message FROM replies WHERE id = $1 FOR UPDATE",
+		"INSERT INTO reply_revisions (reply_id, content, created_at, moderated) SELECT id, message, updated_at, $2 FROM replies WHERE id = $1",
+		"UPDATE replies SET message = $1, updated_at = NOW() WHERE id = $2",
+		reply.ID, reply.Message, moderated, nil)
+}
+
+// GetReplyRevisions returns the earlier versions of a reply, newest first.
+// The text a moderator replaced isn't among them.
+func (rs *ReplyStore) GetReplyRevisions(replyID uuid.UUID) ([]model.Revision, error) {
+	query := "SELECT id, content, created_at FROM reply_revisions WHERE reply_id = $1 AND NOT moderated ORDER BY created_at DESC"
+	return getRevisions(rs.DB, query, replyID)
 }
 
 func (rs *ReplyStore) GetReplyByID(replyID uuid.UUID) (*model.Reply, error) {
@@ -58,7 +71,7 @@ func (rc *ReplyStore) DeleteReply(replyID uuid.UUID) error {
 	_, err := rc.DB.Exec(query, replyID)
 	return err
 }
-func (rc *ReplyStore) GetRepliesByCommentID(commentID uuid.UUID) ([]model.Reply, error) {
+func (rc *ReplyStore) GetRepliesByCommentID(commentID, userID uuid.UUID) ([]model.Reply, error) {
 	replies := []model.Reply{}
 	query := `
 
@@ -66,6 +79,9 @@ func (rc *ReplyStore) GetRepliesByCommentID(commentID uuid.UUID) ([]model.Reply,
 
 	replies.id,
 	replies.message,
+	replies.created_at,
+	replies.updated_at,
+	(replies.updated_at > replies.created_at) AS edited,
 
 	reply_user.id,
 	reply_user.name,
@@ -74,9 +90,12 @@ func (rc *ReplyStore) GetRepliesByCommentID(commentID uuid.UUID) ([]model.Reply,
 
 	FROM replies
 	LEFT JOIN users as reply_user ON reply_user.id = replies.user_id
-	WHERE replies.comment_id = $1
-	`
-	rows, err := rc.DB.Query(query, commentID)
+	JOIN comments ON comments.id = replies.comment_id
+	JOIN posts ON posts.id = comments.post_id
+	WHERE replies.comment_id = $1 AND ` + isActive("replies.user_id") + ` AND ` + notBlocked("replies.user_id", "$2") + `
+	AND ` + notBlocked("comments.user_id", "$2") + ` AND ` + notBlocked("posts.user_id", "$2")
+
+	rows, err := rc.DB.Query(query, commentID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +105,7 @@ func (rc *ReplyStore) GetRepliesByCommentID(commentID uuid.UUID) ([]model.Reply,
 		reply := model.Reply{}
 
 		err := rows.Scan(
-			&reply.ID, &reply.Message,
+			&reply.ID, &reply.Message, &reply.CreatedAt, &reply.UpdatedAt, &reply.Edited,
 			&reply.User.ID, &reply.User.Name, &reply.User.LastName, &reply.User.Username,
 		)
 		if err != nil {
@@ -105,3 +124,25 @@ func (rc *ReplyStore) GetRepliesByCommentID(commentID uuid.UUID) ([]model.Reply,
 	return replies, nil
 
 }
+
+// GetRepliesByUserID returns every reply of a user.
+func (rs *ReplyStore) GetRepliesByUserID(userID uuid.UUID) ([]model.Reply, error) {
+	replies := []model.Reply{}
+	query := "SELECT id, comment_id, user_id, message, created_at, updated_at FROM replies WHERE user_id = $1 ORDER BY created_at ASC"
+	rows, err := rs.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		reply := model.Reply{}
+		if err := rows.Scan(&reply.ID, &reply.CommentID, &reply.UserID, &reply.Message, &reply.CreatedAt, &reply.UpdatedAt); err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
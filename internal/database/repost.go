@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+var ErrAlreadyReposted = errors.New("post already reposted")
+var ErrNotReposted = errors.New("post not reposted")
+
+type BaseRepostStore interface {
+	Repost(repost *model.Repost) error
+	Unrepost(postID, userID uuid.UUID) error
+	IsReposted(postID, userID uuid.UUID) (bool, error)
+	GetRepostsByUserID(userID uuid.UUID) ([]model.Repost, error)
+}
+
+type RepostStore struct {
+	DB *sql.DB
+}
+
+func NewRepostStore(db *sql.DB) BaseRepostStore {
+	return &RepostStore{DB: db}
+}
+
+// Repost shares a post with the followers of a user. A user can repost a post
+// only once, ErrAlreadyReposted is returned if it was reposted before.
+func (s *RepostStore) Repost(repost *model.Repost) error {
+	query := `INSERT INTO reposts (post_id, user_id) VALUES ($1, $2)
+	ON CONFLICT (user_id, post_id) DO NOTHING
+	RETURNING id, created_at`
+	err := s.DB.QueryRow(query, repost.PostID, repost.UserID).Scan(&repost.ID, &repost.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrAlreadyReposted
+	}
+	return err
+}
+
+func (s *RepostStore) Unrepost(postID, userID uuid.UUID) error {
+	query := "DELETE FROM reposts WHERE post_id = $1 AND user_id = $2"
+	result, err := s.DB.Exec(query, postID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotReposted
+	}
+	return nil
+}
+
+func (s *RepostStore) IsReposted(postID, userID uuid.UUID) (bool, error) {
+	var result bool
+	query := "SELECT EXISTS (SELECT 1 FROM reposts WHERE post_id = $1 AND user_id = $2)"
+	if err := s.DB.QueryRow(query, postID, userID).Scan(&result); err != nil {
+		return false, err
+	}
+	return result, nil
+}
+
+func (s *RepostStore) GetRepostsByUserID(userID uuid.UUID) ([]model.Repost, error) {
+	reposts := []model.Repost{}
+	query := "SELECT id, post_id, user_id, created_at FROM reposts WHERE user_id = $1 ORDER BY created_at DESC"
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		repost := model.Repost{}
+		if err := rows.Scan(&repost.ID, &repost.PostID, &repost.UserID, &repost.CreatedAt); err != nil {
+			return nil, err
+		}
+		reposts = append(reposts, repost)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reposts, nil
+}
+
+// quotedPostColumns are the columns of a quoted post, in the order
+// quotedPost.dest expects them. The quoted post is joined as quoted and its
+// author as quoted_user:
+//
+//	LEFT JOIN posts AS quoted ON quoted.id = posts.quote_id AND quotedPostVisibleTo(viewer)
+//	LEFT JOIN users AS quoted_user ON quoted_user.id = quoted.user_id
+const quotedPostColumns = `quoted.id, quoted.content, quoted.image, quoted.thumbnail, quoted.created_at,
+	quoted_user.id, quoted_user.name, quoted_user.last_name, quoted_user.username`
+
+// quotedPostVisibleTo returns an SQL condition that is true if the viewer in
+// viewerParam can see the quoted post. Quotes of posts that were hidden from
+// the viewer since are shown without the quoted post.
+func quotedPostVisibleTo(viewerParam string) string {
+	return fmt.Sprintf(`quoted.status = 'published' AND %s AND %s AND %s`,
+		visibleTo("quoted.user_id", viewerParam), isActive("quoted.user_id"), notBlocked("quoted.user_id", viewerParam))
+}
+
+// quotedPost holds the nullable columns of quotedPostColumns.
+type quotedPost struct {
+	ID        *uuid.UUID
+	Content   *string
+	Image     *string
+	Thumbnail *string
+	CreatedAt *string
+	UserID    *uuid.UUID
+	Name      *string
+	LastName  *string
+	Username  *string
+}
+
+func (q *quotedPost) dest() []any {
+	return []any{&q.ID, &q.Content, &q.Image, &q.Thumbnail, &q.CreatedAt, &q.UserID, &q.Name, &q.LastName, &q.Username}
+}
+
+// post returns the quoted post, or nil if there is none or the viewer can't
+// see it.
+func (q *quotedPost) post() *model.Post {
+	if q.ID == nil {
+		return nil
+	}
+	return &model.Post{
+		ID:        *q.ID,
+		Content:   *q.Content,
+		Image:     q.Image,
+		Thumbnail: q.Thumbnail,
+		CreatedAt: *q.CreatedAt,
+		User: model.User{
+			ID:       *q.UserID,
+			Name:     *q.Name,
+			LastName: *q.LastName,
+			Username: *q.Username,
+		},
+	}
+}
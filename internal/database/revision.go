@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+// revise replaces the content of a post, comment or reply and keeps the
+// previous version as a revision, in a single transaction. lockQuery selects
+// the current content for update, revisionQuery copies it to the revisions
+// table, marked with whether a moderator made the edit, and updateQuery stores
+// the new content. If afterUpdate is not nil it runs in the same transaction
+// to update what is derived from the content. Saving the same content again
+// is not an edit and changes nothing.
+func revise(db *sql.DB, lockQuery, revisionQuery, updateQuery string, id uuid.UUID, content string, moderated bool, afterUpdate func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow(lockQuery, id).Scan(&current); err != nil {
+		return err
+	}
+	if current == content {
+		return nil
+	}
+	if _, err := tx.Exec(revisionQuery, id, moderated); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(updateQuery, content, id); err != nil {
+		return err
+	}
+	if afterUpdate != nil {
+		if err := afterUpdate(tx); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func getRevisions(db *sql.DB, query string, id uuid.UUID) ([]model.Revision, error) {
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.Revision{}
+	for rows.Next() {
+		revision := model.Revision{}
+		if err := rows.Scan(&revision.ID, &revision.Content, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
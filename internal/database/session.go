@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+type BaseSessionStore interface {
+	CreateSession(session *model.Session) error
+	GetSessionByID(id uuid.UUID) (*model.Session, error)
+	GetActiveSessionsByUserID(userID uuid.UUID) ([]model.Session, error)
+	TouchSession(id uuid.UUID) error
+	RevokeSession(id uuid.UUID) error
+	RevokeOtherSessions(userID, currentSessionID uuid.UUID) error
+	RevokeAllSessions(userID uuid.UUID) error
+	GetSessionsByUserID(userID uuid.UUID) ([]model.Session, error)
+}
+
+type SessionStore struct {
+	DB *sql.DB
+}
+
+func NewSessionStore(db *sql.DB) BaseSessionStore {
+	return &SessionStore{DB: db}
+}
+
+func (s *SessionStore) CreateSession(session *model.Session) error {
+	query := "INSERT INTO sessions (user_id, user_agent, ip_address) VALUES ($1, $2, $3) RETURNING id, created_at, last_seen_at"
+	return s.DB.QueryRow(query, session.UserID, session.UserAgent, session.IPAddress).Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+}
+
+func (s *SessionStore) GetSessionByID(id uuid.UUID) (*model.Session, error) {
+	session := &model.Session{}
+	query := "SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at FROM sessions WHERE id = $1"
+	err := s.DB.QueryRow(query, id).Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastSeenAt, &session.RevokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *SessionStore) GetActiveSessionsByUserID(userID uuid.UUID) ([]model.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at FROM sessions
+	WHERE user_id = $1 AND revoked_at IS NULL
+	ORDER BY last_seen_at DESC`
+	return s.getSessions(query, userID)
+}
+
+// GetSessionsByUserID returns every session of a user including revoked
+// ones, newest first.
+func (s *SessionStore) GetSessionsByUserID(userID uuid.UUID) ([]model.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at FROM sessions
+	WHERE user_id = $1
+	ORDER BY created_at DESC`
+	return s.getSessions(query, userID)
+}
+
+func (s *SessionStore) getSessions(query string, userID uuid.UUID) ([]model.Session, error) {
+	sessions := []model.Session{}
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		session := model.Session{}
+		err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastSeenAt, &session.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TouchSession updates the last seen time of a session. Writes are throttled
+// to once a minute so authenticated requests don't update the row every time.
+func (s *SessionStore) TouchSession(id uuid.UUID) error {
+	query := "UPDATE sessions SET last_seen_at = NOW() WHERE id = $1 AND last_seen_at < NOW() - INTERVAL '1 minute'"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+func (s *SessionStore) RevokeSession(id uuid.UUID) error {
+	query := "UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+func (s *SessionStore) RevokeOtherSessions(userID, currentSessionID uuid.UUID) error {
+	query := "UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL"
+	_, err := s.DB.Exec(query, userID, currentSessionID)
+	return err
+}
+
+func (s *SessionStore) RevokeAllSessions(userID uuid.UUID) error {
+	query := "UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL"
+	_, err := s.DB.Exec(query, userID)
+	return err
+}
@@ -1,23 +1,53 @@
 package database
 
 type Storage struct {
-	UserStore    BaseUserStore
-	PostStore    BasePostStore
-	CommentStore BaseCommentStore
-	FollowStore  BaseFollowStore
-	FeedStore    BaseFeedStore
-	LikeStore    BaseLikeStore
-	ReplyStore   BaseReplyStore
+	UserStore                BaseUserStore
+	PostStore                BasePostStore
+	CommentStore             BaseCommentStore
+	FollowStore              BaseFollowStore
+	FeedStore                BaseFeedStore
+	LikeStore                BaseLikeStore
+	ReplyStore               BaseReplyStore
+	TokenStore               BaseTokenStore
+	SessionStore             BaseSessionStore
+	UserTokenStore           BaseUserTokenStore
+	RecoveryCodeStore        BaseRecoveryCodeStore
+	LoginAttemptStore        BaseLoginAttemptStore
+	IdentityStore            BaseIdentityStore
+	PersonalAccessTokenStore BasePersonalAccessTokenStore
+	ModerationStore          BaseModerationStore
+	UsernameChangeStore      BaseUsernameChangeStore
+	BlockStore               BaseBlockStore
+	MuteStore                BaseMuteStore
+	DataExportStore          BaseDataExportStore
+	MediaStore               BaseMediaStore
+	RepostStore              BaseRepostStore
+	TagStore                 BaseTagStore
 }
 
-func NewPostgresStorage(userStore BaseUserStore, postStore BasePostStore, commentStore BaseCommentStore, followStore BaseFollowStore, feedStore BaseFeedStore, likeStore BaseLikeStore, replyStore BaseReplyStore) *Storage {
+func NewPostgresStorage(userStore BaseUserStore, postStore BasePostStore, commentStore BaseCommentStore, followStore BaseFollowStore, feedStore BaseFeedStore, likeStore BaseLikeStore, replyStore BaseReplyStore, tokenStore BaseTokenStore, sessionStore BaseSessionStore, userTokenStore BaseUserTokenStore, recoveryCodeStore BaseRecoveryCodeStore, loginAttemptStore BaseLoginAttemptStore, identityStore BaseIdentityStore, personalAccessTokenStore BasePersonalAccessTokenStore, moderationStore BaseModerationStore, usernameChangeStore BaseUsernameChangeStore, blockStore BaseBlockStore, muteStore BaseMuteStore, dataExportStore BaseDataExportStore, mediaStore BaseMediaStore, repostStore BaseRepostStore, tagStore BaseTagStore) *Storage {
 	return &Storage{
-		UserStore:    userStore,
-		PostStore:    postStore,
-		CommentStore: commentStore,
-		FollowStore:  followStore,
-		FeedStore:    feedStore,
-		LikeStore:    likeStore,
-		ReplyStore:   replyStore,
+		UserStore:                userStore,
+		PostStore:                postStore,
+		CommentStore:             commentStore,
+		FollowStore:              followStore,
+		FeedStore:                feedStore,
+		LikeStore:                likeStore,
+		ReplyStore:               replyStore,
+		TokenStore:               tokenStore,
+		SessionStore:             sessionStore,
+		UserTokenStore:           userTokenStore,
+		RecoveryCodeStore:        recoveryCodeStore,
+		LoginAttemptStore:        loginAttemptStore,
+		IdentityStore:            identityStore,
+		PersonalAccessTokenStore: personalAccessTokenStore,
+		ModerationStore:          moderationStore,
+		UsernameChangeStore:      usernameChangeStore,
+		BlockStore:               blockStore,
+		MuteStore:                muteStore,
+		DataExportStore:          dataExportStore,
+		MediaStore:               mediaStore,
+		RepostStore:              repostStore,
+		TagStore:                 tagStore,
 	}
 }
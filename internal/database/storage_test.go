@@ -2,9 +2,11 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/fatihesergg/go_social/internal/model"
 	"github.com/golang-migrate/migrate/v4"
@@ -49,18 +51,33 @@ func NewPostgresTestStorage() *Storage {
 	}
 
 	return &Storage{
-		UserStore:    NewUserStore(db),
-		PostStore:    NewPostStore(db),
-		CommentStore: NewCommentStore(db),
-		FollowStore:  NewFollowStore(db),
-		FeedStore:    NewFeedStore(db),
-		LikeStore:    NewLikeStore(db),
-		ReplyStore:   NewReplyStore(db),
+		UserStore:                NewUserStore(db),
+		PostStore:                NewPostStore(db),
+		CommentStore:             NewCommentStore(db),
+		FollowStore:              NewFollowStore(db),
+		FeedStore:                NewFeedStore(db),
+		LikeStore:                NewLikeStore(db),
+		ReplyStore:               NewReplyStore(db),
+		TokenStore:               NewTokenStore(db),
+		SessionStore:             NewSessionStore(db),
+		UserTokenStore:           NewUserTokenStore(db),
+		RecoveryCodeStore:        NewRecoveryCodeStore(db),
+		LoginAttemptStore:        NewLoginAttemptStore(db),
+		IdentityStore:            NewIdentityStore(db),
+		PersonalAccessTokenStore: NewPersonalAccessTokenStore(db),
+		ModerationStore:          NewModerationStore(db),
+		UsernameChangeStore:      NewUsernameChangeStore(db),
+		BlockStore:               NewBlockStore(db),
+		MuteStore:                NewMuteStore(db),
+		DataExportStore:          NewDataExportStore(db),
+		MediaStore:               NewMediaStore(db),
+		RepostStore:              NewRepostStore(db),
+		TagStore:                 NewTagStore(db),
 	}
 }
 
 func cleanupAllTables() {
-	tables := []string{"posts", "post_likes", "comments", "comment_likes", "users"}
+	tables := []string{"posts", "post_likes", "comments", "comment_likes", "refresh_tokens", "revoked_tokens", "sessions", "user_tokens", "recovery_codes", "login_attempts", "login_lockouts", "user_identities", "oidc_states", "personal_access_tokens", "moderation_actions", "username_changes", "follow_requests", "blocks", "mutes", "muted_keywords", "data_exports", "media", "reposts", "tags", "users"}
 	for _, table := range tables {
 		if _, err := testDB.Exec(fmt.Sprintf("TRUNCATE TABLE %s CASCADE", table)); err != nil {
 			fmt.Printf("Error truncate table %s, %s \n", table, err.Error())
@@ -136,6 +153,15 @@ func createTestCommentReply(t *testing.T, commentID, userID uuid.UUID, message s
 	}
 }
 
+func createTestSession(t *testing.T, userID uuid.UUID) *model.Session {
+	t.Helper()
+	return &model.Session{
+		UserID:    userID,
+		UserAgent: "test",
+		IPAddress: "127.0.0.1",
+	}
+}
+
 func TestUserStore_CreateUser(t *testing.T) {
 
 	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
@@ -273,6 +299,164 @@ func TestUserStore_GetUserByEmail(t *testing.T) {
 	})
 }
 
+func TestUserStore_DeactivateUser(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	other := createTestUser(t, "test_2", "test_2", "test_2", "test_2@test.com", "test_2")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(other)
+	assert.NoError(t, err)
+
+	post := createTestPost(t, "test post", other.ID)
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+	err = testStorage.CommentStore.CreateComment(createTestComment(t, "test comment", post.ID, user.ID))
+	assert.NoError(t, err)
+	err = testStorage.FollowStore.FollowUser(user.ID, other.ID)
+	assert.NoError(t, err)
+	scheduled := createTestPost(t, "scheduled post", user.ID)
+	scheduled.Status = model.PostStatusDraft
+	err = testStorage.PostStore.CreatePost(scheduled)
+	assert.NoError(t, err)
+	err = testStorage.PostStore.SchedulePost(scheduled.ID, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	err = testStorage.UserStore.DeactivateUser(user.ID)
+	assert.NoError(t, err)
+
+	// Scheduled posts of deactivated users aren't published.
+	published, err := testStorage.PostStore.PublishDuePosts(time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, published, 0)
+
+	deactivated, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, deactivated.DeactivatedAt)
+	comments, err := testStorage.CommentStore.GetCommentsByPostID(post.ID, other.ID)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 0)
+
+	users, err := testStorage.UserStore.GetDeactivatedUsers(time.Now().Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	// Accounts deactivated after the cutoff are left alone.
+	purged, err := testStorage.UserStore.AnonymizeUser(user.ID, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, purged)
+	purged, err = testStorage.UserStore.DeleteDeactivatedUser(user.ID, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, purged)
+
+	// Failed logins are keyed by the email, with or without the account.
+	for _, u := range []*model.User{user, other} {
+		_, err = testStorage.LoginAttemptStore.RecordFailedLogin(model.LoginAttemptScopeAccount, u.Email, time.Now(), time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		err = testStorage.LoginAttemptStore.CreateLoginLockout(&model.LoginLockout{Scope: model.LoginAttemptScopeAccount, Subject: u.Email, IPAddress: "127.0.0.1", FailedCount: 5, LockedUntil: time.Now().Add(time.Hour)})
+		assert.NoError(t, err)
+	}
+
+	purged, err = testStorage.UserStore.AnonymizeUser(user.ID, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, purged)
+
+	attempt, err := testStorage.LoginAttemptStore.GetLoginAttempt(model.LoginAttemptScopeAccount, user.Email)
+	assert.NoError(t, err)
+	assert.Nil(t, attempt)
+	lockouts, err := testStorage.LoginAttemptStore.GetLoginLockoutsBySubject(model.LoginAttemptScopeAccount, user.Email)
+	assert.NoError(t, err)
+	assert.Len(t, lockouts, 0)
+
+	drafts, err := testStorage.PostStore.GetDraftsByUserID(user.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, drafts, 0)
+
+	anonymized, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, anonymized.DeactivatedAt)
+	assert.NotEqual(t, user.Username, anonymized.Username)
+	comments, err = testStorage.CommentStore.GetCommentsByPostID(post.ID, other.ID)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+	isFollowing, err := testStorage.FollowStore.IsFollowing(user.ID, other.ID)
+	assert.NoError(t, err)
+	assert.False(t, isFollowing)
+
+	// An account that was reactivated before the purge isn't touched.
+	err = testStorage.UserStore.DeactivateUser(other.ID)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.ReactivateUser(other.ID)
+	assert.NoError(t, err)
+	purged, err = testStorage.UserStore.DeleteDeactivatedUser(other.ID, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, purged)
+	existUser, err := testStorage.UserStore.GetUserByID(other.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+	lockouts, err = testStorage.LoginAttemptStore.GetLoginLockoutsBySubject(model.LoginAttemptScopeAccount, other.Email)
+	assert.NoError(t, err)
+	assert.Len(t, lockouts, 1)
+
+	err = testStorage.UserStore.DeactivateUser(other.ID)
+	assert.NoError(t, err)
+	purged, err = testStorage.UserStore.DeleteDeactivatedUser(other.ID, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, purged)
+	attempt, err = testStorage.LoginAttemptStore.GetLoginAttempt(model.LoginAttemptScopeAccount, other.Email)
+	assert.NoError(t, err)
+	assert.Nil(t, attempt)
+	lockouts, err = testStorage.LoginAttemptStore.GetLoginLockoutsBySubject(model.LoginAttemptScopeAccount, other.Email)
+	assert.NoError(t, err)
+	assert.Len(t, lockouts, 0)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(other.ID)
+	})
+}
+
+func TestUserStore_GetProfile(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	other := createTestUser(t, "test_2", "test_2", "test_2", "test_2@test.com", "test_2")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(other)
+	assert.NoError(t, err)
+
+	err = testStorage.FollowStore.FollowUser(other.ID, user.ID)
+	assert.NoError(t, err)
+	err = testStorage.PostStore.CreatePost(createTestPost(t, "test post", user.ID))
+	assert.NoError(t, err)
+
+	profile, err := testStorage.UserStore.GetProfile(user.ID, other.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, profile)
+	assert.Equal(t, user.Username, profile.Username)
+	assert.Equal(t, 1, profile.FollowerCount)
+	assert.Equal(t, 0, profile.FollowingCount)
+	assert.Equal(t, 1, profile.PostCount)
+	assert.True(t, profile.IsFollowing)
+	assert.False(t, profile.FollowsYou)
+
+	profile, err = testStorage.UserStore.GetProfile(other.ID, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, profile.IsFollowing)
+	assert.True(t, profile.FollowsYou)
+
+	followers, err := testStorage.FollowStore.GetFollowerByUserID(user.ID, user.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, followers, 1)
+	assert.Equal(t, other.Username, followers[0].User.Username)
+	assert.False(t, followers[0].IsFollowing)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(other.ID)
+	})
+}
+
 func TestFollowStore_FollowUser(t *testing.T) {
 	user1 := createTestUser(t, "test", "test", "test", "test@test.com", "test")
 	user2 := createTestUser(t, "test_2", "test_2", "test_2", "test_2@test.com", "test_2")
@@ -293,12 +477,13 @@ func TestFollowStore_FollowUser(t *testing.T) {
 	err = testStorage.FollowStore.FollowUser(existUser1.ID, existUser2.ID)
 	assert.NoError(t, err)
 
-	follows, err := testStorage.FollowStore.GetFollowingByUserID(existUser1.ID)
+	follows, err := testStorage.FollowStore.GetFollowingByUserID(existUser1.ID, existUser1.ID, createTestPagination(t))
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(follows))
 	first := follows[0]
 	assert.Equal(t, first.UserID.String(), existUser1.ID.String())
 	assert.Equal(t, first.FollowID.String(), existUser2.ID.String())
+	assert.Equal(t, existUser2.Username, first.User.Username)
 
 	t.Cleanup(func() {
 		_ = testStorage.FollowStore.UnFollowUser(existUser1.ID, existUser2.ID)
@@ -329,14 +514,14 @@ func TestFollowStore_UnFollowStore(t *testing.T) {
 	err = testStorage.FollowStore.FollowUser(existUser1.ID, existUser2.ID)
 	assert.NoError(t, err)
 
-	follows, err := testStorage.FollowStore.GetFollowingByUserID(existUser1.ID)
+	follows, err := testStorage.FollowStore.GetFollowingByUserID(existUser1.ID, existUser1.ID, createTestPagination(t))
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(follows))
 
 	err = testStorage.FollowStore.UnFollowUser(existUser1.ID, existUser2.ID)
 	assert.NoError(t, err)
 
-	follows, err = testStorage.FollowStore.GetFollowingByUserID(existUser1.ID)
+	follows, err = testStorage.FollowStore.GetFollowingByUserID(existUser1.ID, existUser1.ID, createTestPagination(t))
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(follows))
 
@@ -349,6 +534,100 @@ func TestFollowStore_UnFollowStore(t *testing.T) {
 
 }
 
+func TestFollowStore_GetSuggestions(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	friend := createTestUser(t, "friend", "friend", "friend", "friend@test.com", "test")
+	friendOfFriend := createTestUser(t, "fof", "fof", "fof", "fof@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(friend)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(friendOfFriend)
+	assert.NoError(t, err)
+
+	err = testStorage.FollowStore.FollowUser(friend.ID, friendOfFriend.ID)
+	assert.NoError(t, err)
+
+	suggestions, err := testStorage.FollowStore.GetSuggestions(user.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, suggestions, 1)
+	assert.Equal(t, friendOfFriend.ID, suggestions[0].User.ID)
+	assert.Equal(t, 0, suggestions[0].MutualCount)
+	assert.Equal(t, 1, suggestions[0].FollowerCount)
+
+	err = testStorage.FollowStore.FollowUser(user.ID, friend.ID)
+	assert.NoError(t, err)
+
+	suggestions, err = testStorage.FollowStore.GetSuggestions(user.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, suggestions, 1)
+	assert.Equal(t, friendOfFriend.ID, suggestions[0].User.ID)
+	assert.Equal(t, 1, suggestions[0].MutualCount)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(friend.ID)
+		_ = testStorage.UserStore.DeleteUser(friendOfFriend.ID)
+	})
+}
+
+func TestFollowStore_GetFollowPath(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	friend := createTestUser(t, "friend", "friend", "friend", "friend@test.com", "test")
+	other := createTestUser(t, "other", "other", "other", "other@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(friend)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(other)
+	assert.NoError(t, err)
+
+	err = testStorage.FollowStore.FollowUser(user.ID, friend.ID)
+	assert.NoError(t, err)
+	err = testStorage.FollowStore.FollowUser(friend.ID, other.ID)
+	assert.NoError(t, err)
+	err = testStorage.FollowStore.FollowUser(friend.ID, user.ID)
+	assert.NoError(t, err)
+
+	path, err := testStorage.FollowStore.GetFollowPath(user.ID, other.ID, 3)
+	assert.NoError(t, err)
+	assert.Len(t, path, 3)
+	assert.Equal(t, friend.ID, path[1].ID)
+
+	path, err = testStorage.FollowStore.GetFollowPath(user.ID, other.ID, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, path)
+
+	// The search gives up once it read maxFollowPathFollows follows.
+	defaultMaxFollows := maxFollowPathFollows
+	maxFollowPathFollows = 1
+	path, err = testStorage.FollowStore.GetFollowPath(user.ID, other.ID, 3)
+	maxFollowPathFollows = defaultMaxFollows
+	assert.NoError(t, err)
+	assert.Nil(t, path)
+
+	relationship, err := testStorage.FollowStore.GetRelationship(user.ID, friend.ID)
+	assert.NoError(t, err)
+	assert.True(t, relationship.Mutual)
+
+	mutuals, err := testStorage.FollowStore.GetMutualFollowers(user.ID, other.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, mutuals, 1)
+	assert.Equal(t, friend.ID, mutuals[0].ID)
+
+	known, err := testStorage.FollowStore.GetFollowersYouKnow(user.ID, other.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, known, 1)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(friend.ID)
+		_ = testStorage.UserStore.DeleteUser(other.ID)
+	})
+}
+
 func TestPostStore_CreatePost(t *testing.T) {
 	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
 	pagination := createTestPagination(t)
@@ -408,13 +687,44 @@ func TestPostStore_UpdatePost(t *testing.T) {
 
 	first.Content = "updated"
 
-	err = testStorage.PostStore.UpdatePost(&first)
+	err = testStorage.PostStore.UpdatePost(&first, false)
 	assert.NoError(t, err)
 
 	updatedPost, err := testStorage.PostStore.GetPostDetailsByID(first.ID, existUser.ID)
 	assert.NoError(t, err)
 	assert.NotNil(t, updatedPost)
 	assert.Equal(t, first.Content, updatedPost.Content)
+	assert.True(t, updatedPost.Edited)
+
+	revisions, err := testStorage.PostStore.GetPostRevisions(first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, post.Content, revisions[0].Content)
+
+	err = testStorage.PostStore.UpdatePost(&first, false)
+	assert.NoError(t, err)
+	revisions, err = testStorage.PostStore.GetPostRevisions(first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+
+	// The text a moderator replaces isn't listed, what they wrote is once the
+	// author edits it again.
+	first.Content = "moderated"
+	err = testStorage.PostStore.UpdatePost(&first, true)
+	assert.NoError(t, err)
+	revisions, err = testStorage.PostStore.GetPostRevisions(first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, post.Content, revisions[0].Content)
+
+	first.Content = "edited again"
+	err = testStorage.PostStore.UpdatePost(&first, false)
+	assert.NoError(t, err)
+	revisions, err = testStorage.PostStore.GetPostRevisions(first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "moderated", revisions[0].Content)
+	assert.Equal(t, post.Content, revisions[1].Content)
 
 	t.Cleanup(func() {
 		_ = testStorage.PostStore.DeletePost(updatedPost.ID)
@@ -423,6 +733,88 @@ func TestPostStore_UpdatePost(t *testing.T) {
 
 }
 
+func TestPostStore_SchedulePost(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	pagination := createTestPagination(t)
+	search := createTestSearch(t, "")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+
+	post := createTestPost(t, "test", existUser.ID)
+	post.Status = model.PostStatusDraft
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+
+	drafts, err := testStorage.PostStore.GetDraftsByUserID(existUser.ID, pagination)
+	assert.NoError(t, err)
+	assert.Len(t, drafts, 1)
+	assert.Equal(t, model.PostStatusDraft, drafts[0].Status)
+
+	posts, err := testStorage.PostStore.GetPostsByUserID(existUser.ID, pagination, search)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 0)
+
+	err = testStorage.PostStore.SchedulePost(post.ID, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	published, err := testStorage.PostStore.PublishDuePosts(time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{post.ID}, published)
+
+	published, err = testStorage.PostStore.PublishDuePosts(time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, published, 0)
+
+	posts, err = testStorage.PostStore.GetPostsByUserID(existUser.ID, pagination, search)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	err = testStorage.PostStore.SchedulePost(post.ID, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrPostPublished)
+
+	t.Cleanup(func() {
+		_ = testStorage.PostStore.DeletePost(post.ID)
+		_ = testStorage.UserStore.DeleteUser(existUser.ID)
+	})
+}
+
+func TestPostStore_GetPostByID(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	pagination := createTestPagination(t)
+	search := createTestSearch(t, "")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	post := createTestPost(t, "test", user.ID)
+
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+
+	existPosts, err := testStorage.PostStore.GetPostsByUserID(user.ID, pagination, search)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(existPosts))
+
+	existPost, err := testStorage.PostStore.GetPostByID(existPosts[0].ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existPost)
+	assert.Equal(t, user.ID.String(), existPost.UserID.String())
+	assert.Equal(t, "test", existPost.Content)
+
+	missingPost, err := testStorage.PostStore.GetPostByID(uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, missingPost)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
 func TestPostStore_DeletePost(t *testing.T) {
 	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
 	pagination := createTestPagination(t)
@@ -631,7 +1023,7 @@ func TestCommentStore_UpdateComment(t *testing.T) {
 
 	firstComment.Content = "updated"
 
-	err = testStorage.CommentStore.UpdateComment(&firstComment)
+	err = testStorage.CommentStore.UpdateComment(&firstComment, false)
 	assert.NoError(t, err)
 
 	updatedComment, err := testStorage.CommentStore.GetCommentByID(firstComment.ID)
@@ -884,78 +1276,237 @@ func TestLikeStore_UnlikeComment(t *testing.T) {
 	})
 }
 
-func TestReplyStore_CreateReply(t *testing.T) {
+func TestRepostStore_Repost(t *testing.T) {
 	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	follower := createTestUser(t, "test_2", "test_2", "test_2", "test_2@test.com", "test_2")
+	author := createTestUser(t, "test_3", "test_3", "test_3", "test_3@test.com", "test_3")
+	pagination := createTestPagination(t)
+	search := createTestSearch(t, "")
 
-	err := testStorage.UserStore.CreateUser(user)
+	for _, u := range []*model.User{user, follower, author} {
+		err := testStorage.UserStore.CreateUser(u)
+		assert.NoError(t, err)
+	}
+	err := testStorage.FollowStore.FollowUser(follower.ID, user.ID)
 	assert.NoError(t, err)
 
-	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	post := createTestPost(t, "test", author.ID)
+	err = testStorage.PostStore.CreatePost(post)
 	assert.NoError(t, err)
-	assert.NotNil(t, existUser)
-
-	post := createTestPost(t, "test", existUser.ID)
 
-	err = testStorage.PostStore.CreatePost(post)
+	err = testStorage.RepostStore.Repost(&model.Repost{PostID: post.ID, UserID: user.ID})
 	assert.NoError(t, err)
+	err = testStorage.RepostStore.Repost(&model.Repost{PostID: post.ID, UserID: user.ID})
+	assert.ErrorIs(t, err, ErrAlreadyReposted)
 
-	pagination := createTestPagination(t)
-	search := createTestSearch(t, "")
-	existPosts, err := testStorage.PostStore.GetPostsByUserID(existUser.ID, pagination, search)
+	details, err := testStorage.PostStore.GetPostDetailsByID(post.ID, user.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(existPosts))
+	assert.Equal(t, 1, details.RepostCount)
+	assert.True(t, details.IsReposted)
 
-	first := existPosts[0]
+	feed, err := testStorage.FeedStore.GetFeed(follower.ID, pagination, search)
+	assert.NoError(t, err)
+	assert.Len(t, feed, 1)
+	assert.Equal(t, post.ID, feed[0].ID)
+	assert.NotNil(t, feed[0].RepostedBy)
+	assert.Equal(t, user.ID, feed[0].RepostedBy.ID)
 
-	comment := createTestComment(t, "test", first.ID, existUser.ID)
+	quote := createTestPost(t, "quote", user.ID)
+	quote.QuoteID = &post.ID
+	err = testStorage.PostStore.CreatePost(quote)
+	assert.NoError(t, err)
 
-	err = testStorage.CommentStore.CreateComment(comment)
+	details, err = testStorage.PostStore.GetPostDetailsByID(quote.ID, follower.ID)
 	assert.NoError(t, err)
+	assert.NotNil(t, details.Quote)
+	assert.Equal(t, post.ID, details.Quote.ID)
+	assert.Equal(t, author.ID, details.Quote.User.ID)
 
-	existComments, err := testStorage.CommentStore.GetCommentsByPostID(first.ID, existUser.ID)
+	err = testStorage.RepostStore.Unrepost(post.ID, user.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(existComments))
-	firstComment := existComments[0]
+	err = testStorage.RepostStore.Unrepost(post.ID, user.ID)
+	assert.ErrorIs(t, err, ErrNotReposted)
 
-	reply := createTestCommentReply(t, firstComment.ID, existUser.ID, "test")
+	t.Cleanup(func() {
+		_ = testStorage.PostStore.DeletePost(quote.ID)
+		_ = testStorage.PostStore.DeletePost(post.ID)
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(follower.ID)
+		_ = testStorage.UserStore.DeleteUser(author.ID)
+	})
+}
 
-	err = testStorage.ReplyStore.CreateReply(reply)
+func TestFeedStore_GetFeedPages(t *testing.T) {
+	follower := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	first := createTestUser(t, "test_2", "test_2", "test_2", "test_2@test.com", "test_2")
+	second := createTestUser(t, "test_3", "test_3", "test_3", "test_3@test.com", "test_3")
+	author := createTestUser(t, "test_4", "test_4", "test_4", "test_4@test.com", "test_4")
+	search := createTestSearch(t, "")
 
-	assert.NoError(t, err)
+	for _, u := range []*model.User{follower, first, second, author} {
+		err := testStorage.UserStore.CreateUser(u)
+		assert.NoError(t, err)
+	}
+	for _, followed := range []*model.User{first, second} {
+		err := testStorage.FollowStore.FollowUser(follower.ID, followed.ID)
+		assert.NoError(t, err)
+	}
 
-	replies, err := testStorage.ReplyStore.GetRepliesByCommentID(firstComment.ID)
+	// Both followees repost the same post, then the first one posts twice.
+	reposted := createTestPost(t, "reposted", author.ID)
+	err := testStorage.PostStore.CreatePost(reposted)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(replies))
-	firstReply := replies[0]
-
-	existReply, err := testStorage.ReplyStore.GetReplyByID(firstReply.ID)
+	err = testStorage.RepostStore.Repost(&model.Repost{PostID: reposted.ID, UserID: first.ID})
+	assert.NoError(t, err)
+	err = testStorage.RepostStore.Repost(&model.Repost{PostID: reposted.ID, UserID: second.ID})
+	assert.NoError(t, err)
+	older := createTestPost(t, "older", first.ID)
+	err = testStorage.PostStore.CreatePost(older)
+	assert.NoError(t, err)
+	newer := createTestPost(t, "newer", first.ID)
+	err = testStorage.PostStore.CreatePost(newer)
 	assert.NoError(t, err)
-	assert.NotNil(t, existReply)
 
-	assert.Equal(t, reply.CommentID.String(), existReply.CommentID.String())
-	assert.Equal(t, reply.UserID.String(), existReply.UserID.String())
+	var ids []uuid.UUID
+	for offset := 0; offset < 3; offset++ {
+		feed, err := testStorage.FeedStore.GetFeed(follower.ID, Pagination{Limit: 1, Offset: offset}, search)
+		assert.NoError(t, err)
+		if assert.Len(t, feed, 1) {
+			ids = append(ids, feed[0].ID)
+		}
+		if offset == 2 && len(feed) == 1 {
+			assert.NotNil(t, feed[0].RepostedBy)
+			assert.Equal(t, second.ID, feed[0].RepostedBy.ID)
+		}
+	}
+	assert.Equal(t, []uuid.UUID{newer.ID, older.ID, reposted.ID}, ids)
 
-	t.Cleanup(func() {
+	_, err = testStorage.FeedStore.GetFeed(follower.ID, Pagination{Limit: 1, Offset: 3}, search)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
 
-		_ = testStorage.PostStore.DeletePost(first.ID)
-		_ = testStorage.CommentStore.DeleteComment(firstComment.ID)
-		_ = testStorage.ReplyStore.DeleteReply(first.ID)
-		_ = testStorage.LikeStore.UnlikeComment(first.ID, existUser.ID)
-		_ = testStorage.UserStore.DeleteUser(existUser.ID)
+	t.Cleanup(func() {
+		_ = testStorage.PostStore.DeletePost(newer.ID)
+		_ = testStorage.PostStore.DeletePost(older.ID)
+		_ = testStorage.PostStore.DeletePost(reposted.ID)
+		for _, u := range []*model.User{follower, first, second, author} {
+			_ = testStorage.UserStore.DeleteUser(u.ID)
+		}
 	})
 }
 
-func TestReplyStore_UpdateReply(t *testing.T) {
+func TestTagStore_GetTrendingTags(t *testing.T) {
 	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	pagination := createTestPagination(t)
 
 	err := testStorage.UserStore.CreateUser(user)
 	assert.NoError(t, err)
 
-	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	post := createTestPost(t, "hello #Go and #golang", user.ID)
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+	err = testStorage.CommentStore.CreateComment(createTestComment(t, "#go", post.ID, user.ID))
 	assert.NoError(t, err)
-	assert.NotNil(t, existUser)
 
-	post := createTestPost(t, "test", existUser.ID)
+	posts, err := testStorage.PostStore.GetPosts(pagination, Search{Tag: "go"}, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	tags, err := testStorage.TagStore.GetTrendingTags(time.Now().Add(-time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+	assert.Equal(t, "go", tags[0].Name)
+	assert.Equal(t, 1, tags[0].UserCount)
+	assert.Equal(t, 2, tags[0].UseCount)
+
+	post.Content = "hello #rust"
+	err = testStorage.PostStore.UpdatePost(post, false)
+	assert.NoError(t, err)
+
+	posts, err = testStorage.PostStore.GetPosts(pagination, Search{Tag: "go"}, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 0)
+	posts, err = testStorage.PostStore.GetPosts(pagination, Search{Tag: "rust"}, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	t.Cleanup(func() {
+		_ = testStorage.PostStore.DeletePost(post.ID)
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestReplyStore_CreateReply(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+
+	post := createTestPost(t, "test", existUser.ID)
+
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+
+	pagination := createTestPagination(t)
+	search := createTestSearch(t, "")
+	existPosts, err := testStorage.PostStore.GetPostsByUserID(existUser.ID, pagination, search)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(existPosts))
+
+	first := existPosts[0]
+
+	comment := createTestComment(t, "test", first.ID, existUser.ID)
+
+	err = testStorage.CommentStore.CreateComment(comment)
+	assert.NoError(t, err)
+
+	existComments, err := testStorage.CommentStore.GetCommentsByPostID(first.ID, existUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(existComments))
+	firstComment := existComments[0]
+
+	reply := createTestCommentReply(t, firstComment.ID, existUser.ID, "test")
+
+	err = testStorage.ReplyStore.CreateReply(reply)
+
+	assert.NoError(t, err)
+
+	replies, err := testStorage.ReplyStore.GetRepliesByCommentID(firstComment.ID, existUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(replies))
+	firstReply := replies[0]
+
+	existReply, err := testStorage.ReplyStore.GetReplyByID(firstReply.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existReply)
+
+	assert.Equal(t, reply.CommentID.String(), existReply.CommentID.String())
+	assert.Equal(t, reply.UserID.String(), existReply.UserID.String())
+
+	t.Cleanup(func() {
+
+		_ = testStorage.PostStore.DeletePost(first.ID)
+		_ = testStorage.CommentStore.DeleteComment(firstComment.ID)
+		_ = testStorage.ReplyStore.DeleteReply(first.ID)
+		_ = testStorage.LikeStore.UnlikeComment(first.ID, existUser.ID)
+		_ = testStorage.UserStore.DeleteUser(existUser.ID)
+	})
+}
+
+func TestReplyStore_UpdateReply(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+
+	post := createTestPost(t, "test", existUser.ID)
 
 	err = testStorage.PostStore.CreatePost(post)
 	assert.NoError(t, err)
@@ -984,7 +1535,7 @@ func TestReplyStore_UpdateReply(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	replies, err := testStorage.ReplyStore.GetRepliesByCommentID(firstComment.ID)
+	replies, err := testStorage.ReplyStore.GetRepliesByCommentID(firstComment.ID, existUser.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(replies))
 	firstReply := replies[0]
@@ -998,13 +1549,25 @@ func TestReplyStore_UpdateReply(t *testing.T) {
 
 	existReply.Message = "updated"
 
-	err = testStorage.ReplyStore.UpdateReply(existReply)
+	err = testStorage.ReplyStore.UpdateReply(existReply, false)
 	assert.NoError(t, err)
 
 	updatedReply, err := testStorage.ReplyStore.GetReplyByID(existReply.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, existReply.Message, updatedReply.Message)
 
+	revisions, err := testStorage.ReplyStore.GetReplyRevisions(existReply.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, "test", revisions[0].Content)
+
+	userReplies, err := testStorage.ReplyStore.GetRepliesByUserID(existUser.ID)
+	assert.NoError(t, err)
+	assert.Len(t, userReplies, 1)
+	assert.Equal(t, "updated", userReplies[0].Message)
+	assert.NotEmpty(t, userReplies[0].CreatedAt)
+	assert.NotEmpty(t, userReplies[0].UpdatedAt)
+
 	t.Cleanup(func() {
 
 		_ = testStorage.PostStore.DeletePost(first.ID)
@@ -1054,7 +1617,7 @@ func TestReplyStore_DeleteReply(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	replies, err := testStorage.ReplyStore.GetRepliesByCommentID(firstComment.ID)
+	replies, err := testStorage.ReplyStore.GetRepliesByCommentID(firstComment.ID, existUser.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(replies))
 	firstReply := replies[0]
@@ -1083,6 +1646,1190 @@ func TestReplyStore_DeleteReply(t *testing.T) {
 	})
 }
 
+func TestTokenStore_RotateRefreshToken(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+
+	session := createTestSession(t, existUser.ID)
+	err = testStorage.SessionStore.CreateSession(session)
+	assert.NoError(t, err)
+
+	oldToken := &model.RefreshToken{
+		UserID:    existUser.ID,
+		SessionID: session.ID,
+		TokenHash: "old_hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	err = testStorage.TokenStore.CreateRefreshToken(oldToken)
+	assert.NoError(t, err)
+
+	newToken := &model.RefreshToken{
+		UserID:    existUser.ID,
+		SessionID: session.ID,
+		TokenHash: "new_hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	err = testStorage.TokenStore.RotateRefreshToken(oldToken.ID, newToken)
+	assert.NoError(t, err)
+
+	rotated, err := testStorage.TokenStore.GetRefreshTokenByHash("old_hash")
+	assert.NoError(t, err)
+	assert.NotNil(t, rotated)
+	assert.NotNil(t, rotated.RevokedAt)
+
+	err = testStorage.TokenStore.RotateRefreshToken(oldToken.ID, &model.RefreshToken{
+		UserID:    existUser.ID,
+		SessionID: session.ID,
+		TokenHash: "reused_hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	current, err := testStorage.TokenStore.GetRefreshTokenByHash("new_hash")
+	assert.NoError(t, err)
+	assert.NotNil(t, current)
+	assert.Nil(t, current.RevokedAt)
+	assert.Equal(t, session.ID.String(), current.SessionID.String())
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(existUser.ID)
+	})
+}
+
+func TestTokenStore_RevokeAccessToken(t *testing.T) {
+	jti := uuid.New()
+
+	revoked, err := testStorage.TokenStore.IsAccessTokenRevoked(jti)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	err = testStorage.TokenStore.RevokeAccessToken(jti, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+
+	revoked, err = testStorage.TokenStore.IsAccessTokenRevoked(jti)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Revoking another token purges the entries of expired tokens.
+	expiredJTI := uuid.New()
+	_, err = testDB.Exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)", expiredJTI, time.Now().Add(-time.Minute).UTC())
+	assert.NoError(t, err)
+
+	otherJTI := uuid.New()
+	err = testStorage.TokenStore.RevokeAccessToken(otherJTI, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+
+	revoked, err = testStorage.TokenStore.IsAccessTokenRevoked(expiredJTI)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = testStorage.TokenStore.IsAccessTokenRevoked(jti)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	t.Cleanup(func() {
+		_, _ = testDB.Exec("DELETE FROM revoked_tokens WHERE jti IN ($1, $2)", jti, otherJTI)
+	})
+}
+
+func TestSessionStore_RevokeSession(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+
+	session := createTestSession(t, existUser.ID)
+	err = testStorage.SessionStore.CreateSession(session)
+	assert.NoError(t, err)
+
+	sessions, err := testStorage.SessionStore.GetActiveSessionsByUserID(existUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sessions))
+	assert.Equal(t, session.UserAgent, sessions[0].UserAgent)
+	assert.Equal(t, session.IPAddress, sessions[0].IPAddress)
+
+	err = testStorage.SessionStore.RevokeSession(session.ID)
+	assert.NoError(t, err)
+
+	revoked, err := testStorage.SessionStore.GetSessionByID(session.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, revoked)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	sessions, err = testStorage.SessionStore.GetActiveSessionsByUserID(existUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(sessions))
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(existUser.ID)
+	})
+}
+
+func TestSessionStore_RevokeOtherSessions(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByUsername("test")
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+
+	current := createTestSession(t, existUser.ID)
+	err = testStorage.SessionStore.CreateSession(current)
+	assert.NoError(t, err)
+
+	other := createTestSession(t, existUser.ID)
+	err = testStorage.SessionStore.CreateSession(other)
+	assert.NoError(t, err)
+
+	err = testStorage.SessionStore.RevokeOtherSessions(existUser.ID, current.ID)
+	assert.NoError(t, err)
+
+	sessions, err := testStorage.SessionStore.GetActiveSessionsByUserID(existUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sessions))
+	assert.Equal(t, current.ID.String(), sessions[0].ID.String())
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(existUser.ID)
+	})
+}
+
+func TestSessionStore_RevokeAllSessions(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = testStorage.SessionStore.CreateSession(createTestSession(t, user.ID))
+		assert.NoError(t, err)
+	}
+
+	err = testStorage.SessionStore.RevokeAllSessions(user.ID)
+	assert.NoError(t, err)
+
+	sessions, err := testStorage.SessionStore.GetActiveSessionsByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(sessions))
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestUserTokenStore_UseUserToken(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	token := &model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.UserTokenPurposeEmailVerification,
+		TokenHash: "verification_hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	err = testStorage.UserTokenStore.CreateUserToken(token)
+	assert.NoError(t, err)
+
+	existToken, err := testStorage.UserTokenStore.GetUserTokenByHash("verification_hash", model.UserTokenPurposeEmailVerification)
+	assert.NoError(t, err)
+	assert.NotNil(t, existToken)
+	assert.Equal(t, user.ID.String(), existToken.UserID.String())
+	assert.Nil(t, existToken.UsedAt)
+
+	used, err := testStorage.UserTokenStore.UseUserToken(existToken.ID)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = testStorage.UserTokenStore.UseUserToken(existToken.ID)
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	err = testStorage.UserTokenStore.DeleteUserTokens(user.ID, model.UserTokenPurposeEmailVerification)
+	assert.NoError(t, err)
+
+	deletedToken, err := testStorage.UserTokenStore.GetUserTokenByHash("verification_hash", model.UserTokenPurposeEmailVerification)
+	assert.NoError(t, err)
+	assert.Nil(t, deletedToken)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestUserTokenStore_ResetPassword(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	token := &model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.UserTokenPurposePasswordReset,
+		TokenHash: "reset_hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	err = testStorage.UserTokenStore.CreateUserToken(token)
+	assert.NoError(t, err)
+
+	used, err := testStorage.UserTokenStore.ResetPassword(token.ID, user.ID, "new_password")
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	existUser, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new_password", existUser.Password)
+
+	// A used token can't reset the password again.
+	used, err = testStorage.UserTokenStore.ResetPassword(token.ID, user.ID, "other_password")
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	existUser, err = testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new_password", existUser.Password)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestUserStore_VerifyEmail(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+	assert.Nil(t, existUser.EmailVerifiedAt)
+
+	err = testStorage.UserStore.VerifyEmail(user.ID)
+	assert.NoError(t, err)
+
+	verifiedUser, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, verifiedUser)
+	assert.NotNil(t, verifiedUser.EmailVerifiedAt)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestUserStore_TOTP(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	err = testStorage.UserStore.SetTOTPSecret(user.ID, "JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+
+	err = testStorage.UserStore.EnableTOTP(user.ID)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existUser)
+	assert.NotNil(t, existUser.TOTPSecret)
+	assert.NotNil(t, existUser.TOTPEnabledAt)
+
+	used, err := testStorage.UserStore.UseTOTPStep(user.ID, 100)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = testStorage.UserStore.UseTOTPStep(user.ID, 100)
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	used, err = testStorage.UserStore.UseTOTPStep(user.ID, 99)
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	used, err = testStorage.UserStore.UseTOTPStep(user.ID, 101)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	err = testStorage.UserStore.DisableTOTP(user.ID)
+	assert.NoError(t, err)
+
+	disabledUser, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, disabledUser)
+	assert.Nil(t, disabledUser.TOTPSecret)
+	assert.Nil(t, disabledUser.TOTPEnabledAt)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestRecoveryCodeStore_UseRecoveryCode(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	err = testStorage.RecoveryCodeStore.ReplaceRecoveryCodes(user.ID, []string{"old_hash"})
+	assert.NoError(t, err)
+
+	err = testStorage.RecoveryCodeStore.ReplaceRecoveryCodes(user.ID, []string{"first_hash", "second_hash"})
+	assert.NoError(t, err)
+
+	used, err := testStorage.RecoveryCodeStore.UseRecoveryCode(user.ID, "old_hash")
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	used, err = testStorage.RecoveryCodeStore.UseRecoveryCode(user.ID, "first_hash")
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = testStorage.RecoveryCodeStore.UseRecoveryCode(user.ID, "first_hash")
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	err = testStorage.RecoveryCodeStore.DeleteRecoveryCodes(user.ID)
+	assert.NoError(t, err)
+
+	used, err = testStorage.RecoveryCodeStore.UseRecoveryCode(user.ID, "second_hash")
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestLoginAttemptStore_RecordFailedLogin(t *testing.T) {
+	now := time.Now()
+
+	attempt, err := testStorage.LoginAttemptStore.RecordFailedLogin(model.LoginAttemptScopeAccount, "test@test.com", now, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempt.FailedCount)
+
+	attempt, err = testStorage.LoginAttemptStore.RecordFailedLogin(model.LoginAttemptScopeAccount, "test@test.com", now, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempt.FailedCount)
+
+	// Failures before the window are forgotten.
+	later := now.Add(time.Hour * 2)
+	attempt, err = testStorage.LoginAttemptStore.RecordFailedLogin(model.LoginAttemptScopeAccount, "test@test.com", later, later.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempt.FailedCount)
+
+	err = testStorage.LoginAttemptStore.LockLogin(model.LoginAttemptScopeAccount, "test@test.com", now.Add(time.Minute))
+	assert.NoError(t, err)
+
+	lockedAttempt, err := testStorage.LoginAttemptStore.GetLoginAttempt(model.LoginAttemptScopeAccount, "test@test.com")
+	assert.NoError(t, err)
+	assert.NotNil(t, lockedAttempt)
+	assert.NotNil(t, lockedAttempt.LockedUntil)
+
+	otherAttempt, err := testStorage.LoginAttemptStore.GetLoginAttempt(model.LoginAttemptScopeIP, "test@test.com")
+	assert.NoError(t, err)
+	assert.Nil(t, otherAttempt)
+
+	err = testStorage.LoginAttemptStore.ResetLoginAttempts(model.LoginAttemptScopeAccount, "test@test.com")
+	assert.NoError(t, err)
+
+	resetAttempt, err := testStorage.LoginAttemptStore.GetLoginAttempt(model.LoginAttemptScopeAccount, "test@test.com")
+	assert.NoError(t, err)
+	assert.Nil(t, resetAttempt)
+}
+
+func TestLoginAttemptStore_PrunesStaleAttempts(t *testing.T) {
+	now := time.Now()
+
+	for _, subject := range []string{"stale@test.com", "locked@test.com"} {
+		_, err := testStorage.LoginAttemptStore.RecordFailedLogin(model.LoginAttemptScopeAccount, subject, now, now.Add(-time.Hour))
+		assert.NoError(t, err)
+	}
+	err := testStorage.LoginAttemptStore.LockLogin(model.LoginAttemptScopeAccount, "locked@test.com", now.Add(time.Hour*3))
+	assert.NoError(t, err)
+
+	// A failure after the window deletes the counters nobody added to since,
+	// except the ones still locked.
+	later := now.Add(time.Hour * 2)
+	_, err = testStorage.LoginAttemptStore.RecordFailedLogin(model.LoginAttemptScopeAccount, "other@test.com", later, later.Add(-time.Hour))
+	assert.NoError(t, err)
+
+	stale, err := testStorage.LoginAttemptStore.GetLoginAttempt(model.LoginAttemptScopeAccount, "stale@test.com")
+	assert.NoError(t, err)
+	assert.Nil(t, stale)
+	locked, err := testStorage.LoginAttemptStore.GetLoginAttempt(model.LoginAttemptScopeAccount, "locked@test.com")
+	assert.NoError(t, err)
+	assert.NotNil(t, locked)
+
+	t.Cleanup(func() {
+		for _, subject := range []string{"stale@test.com", "locked@test.com", "other@test.com"} {
+			_ = testStorage.LoginAttemptStore.ResetLoginAttempts(model.LoginAttemptScopeAccount, subject)
+		}
+	})
+}
+
+func TestLoginAttemptStore_CreateLoginLockout(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	lockout := &model.LoginLockout{
+		Scope:       model.LoginAttemptScopeAccount,
+		Subject:     "test@test.com",
+		UserID:      &user.ID,
+		IPAddress:   "127.0.0.1",
+		FailedCount: 10,
+		LockedUntil: time.Now().Add(time.Minute * 15),
+	}
+	err = testStorage.LoginAttemptStore.CreateLoginLockout(lockout)
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, lockout.ID)
+
+	lockouts, err := testStorage.LoginAttemptStore.GetLoginLockoutsBySubject(model.LoginAttemptScopeAccount, "test@test.com")
+	assert.NoError(t, err)
+	assert.Len(t, lockouts, 1)
+	assert.Equal(t, user.ID.String(), lockouts[0].UserID.String())
+	assert.Equal(t, 10, lockouts[0].FailedCount)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestIdentityStore_CreateUserWithIdentity(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	identity := &model.UserIdentity{
+		Provider: "mock",
+		Subject:  "subject",
+		Email:    "test@test.com",
+	}
+
+	err := testStorage.IdentityStore.CreateUserWithIdentity(user, identity)
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, user.ID)
+	assert.Equal(t, user.ID.String(), identity.UserID.String())
+
+	existIdentity, err := testStorage.IdentityStore.GetIdentity("mock", "subject")
+	assert.NoError(t, err)
+	assert.NotNil(t, existIdentity)
+	assert.Equal(t, user.ID.String(), existIdentity.UserID.String())
+
+	otherIdentity, err := testStorage.IdentityStore.GetIdentity("other", "subject")
+	assert.NoError(t, err)
+	assert.Nil(t, otherIdentity)
+
+	err = testStorage.IdentityStore.CreateIdentity(&model.UserIdentity{UserID: user.ID, Provider: "other", Subject: "subject"})
+	assert.NoError(t, err)
+
+	identities, err := testStorage.IdentityStore.GetIdentitiesByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, identities, 2)
+
+	// A taken username rolls back the whole user.
+	duplicateUser := createTestUser(t, "test", "test", "test", "other@test.com", "test")
+	err = testStorage.IdentityStore.CreateUserWithIdentity(duplicateUser, &model.UserIdentity{Provider: "mock", Subject: "duplicate"})
+	assert.Error(t, err)
+
+	duplicateIdentity, err := testStorage.IdentityStore.GetIdentity("mock", "duplicate")
+	assert.NoError(t, err)
+	assert.Nil(t, duplicateIdentity)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestIdentityStore_ConsumeOIDCState(t *testing.T) {
+	state := &model.OIDCState{
+		StateHash:    "state_hash",
+		Provider:     "mock",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+		ExpiresAt:    time.Now().Add(time.Minute * 10),
+	}
+	err := testStorage.IdentityStore.CreateOIDCState(state)
+	assert.NoError(t, err)
+
+	existState, err := testStorage.IdentityStore.ConsumeOIDCState("state_hash")
+	assert.NoError(t, err)
+	assert.NotNil(t, existState)
+	assert.Equal(t, "nonce", existState.Nonce)
+	assert.Equal(t, "verifier", existState.CodeVerifier)
+
+	usedState, err := testStorage.IdentityStore.ConsumeOIDCState("state_hash")
+	assert.NoError(t, err)
+	assert.Nil(t, usedState)
+}
+
+func TestPersonalAccessTokenStore_RevokePersonalAccessToken(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour * 24)
+	token := &model.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      "bot",
+		TokenHash: "pat_hash",
+		Scopes:    []string{model.ScopePostsWrite, model.ScopeFeedRead},
+		ExpiresAt: &expiresAt,
+	}
+	err = testStorage.PersonalAccessTokenStore.CreatePersonalAccessToken(token)
+	assert.NoError(t, err)
+
+	existToken, err := testStorage.PersonalAccessTokenStore.GetPersonalAccessTokenByHash("pat_hash")
+	assert.NoError(t, err)
+	assert.NotNil(t, existToken)
+	assert.Equal(t, []string{model.ScopePostsWrite, model.ScopeFeedRead}, existToken.Scopes)
+	assert.NotNil(t, existToken.ExpiresAt)
+	assert.Nil(t, existToken.LastUsedAt)
+
+	err = testStorage.PersonalAccessTokenStore.TouchPersonalAccessToken(token.ID)
+	assert.NoError(t, err)
+
+	tokens, err := testStorage.PersonalAccessTokenStore.GetPersonalAccessTokensByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.NotNil(t, tokens[0].LastUsedAt)
+
+	revoked, err := testStorage.PersonalAccessTokenStore.RevokePersonalAccessToken(token.ID, uuid.New())
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = testStorage.PersonalAccessTokenStore.RevokePersonalAccessToken(token.ID, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	tokens, err = testStorage.PersonalAccessTokenStore.GetPersonalAccessTokensByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 0)
+
+	revokedToken, err := testStorage.PersonalAccessTokenStore.GetPersonalAccessTokenByHash("pat_hash")
+	assert.NoError(t, err)
+	assert.NotNil(t, revokedToken)
+	assert.NotNil(t, revokedToken.RevokedAt)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestUserStore_UpdateUserRole(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleUser, existUser.Role)
+
+	err = testStorage.UserStore.UpdateUserRole(user.ID, model.RoleModerator)
+	assert.NoError(t, err)
+
+	moderator, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleModerator, moderator.Role)
+	assert.True(t, moderator.HasRole(model.RoleModerator))
+	assert.False(t, moderator.HasRole(model.RoleAdmin))
+
+	err = testStorage.UserStore.UpdateUserRole(user.ID, "superuser")
+	assert.Error(t, err)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestUserStore_UpdateProfile(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	user.DisplayName = "Test User"
+	user.Bio = "Hello"
+	user.Website = "https://example.com"
+	user.Location = "Istanbul"
+	err = testStorage.UserStore.UpdateProfile(user)
+	assert.NoError(t, err)
+
+	avatar := "http://localhost:3000/media/avatars/test.png"
+	err = testStorage.UserStore.UpdateAvatar(user.ID, &avatar)
+	assert.NoError(t, err)
+
+	existUser, err := testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Test User", existUser.DisplayName)
+	assert.Equal(t, "Hello", existUser.Bio)
+	assert.Equal(t, "https://example.com", existUser.Website)
+	assert.Equal(t, "Istanbul", existUser.Location)
+	assert.Equal(t, avatar, *existUser.Avatar)
+
+	err = testStorage.UserStore.UpdateAvatar(user.ID, nil)
+	assert.NoError(t, err)
+
+	existUser, err = testStorage.UserStore.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, existUser.Avatar)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestUsernameChangeStore_ChangeUsername(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	otherUser := createTestUser(t, "other", "other", "other", "other@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(otherUser)
+	assert.NoError(t, err)
+
+	change := &model.UsernameChange{UserID: user.ID, NewUsername: "renamed", ReservedUntil: time.Now().Add(time.Hour)}
+	err = testStorage.UsernameChangeStore.ChangeUsername(change, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "test", change.OldUsername)
+
+	renamed, err := testStorage.UserStore.GetUserByUsername("renamed")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, renamed.ID)
+
+	reserved, err := testStorage.UsernameChangeStore.GetReservedUsername("test")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, reserved.UserID)
+
+	// The old username is reserved and the new one is taken.
+	err = testStorage.UsernameChangeStore.ChangeUsername(&model.UsernameChange{UserID: otherUser.ID, NewUsername: "test", ReservedUntil: time.Now().Add(time.Hour)}, time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+	err = testStorage.UsernameChangeStore.ChangeUsername(&model.UsernameChange{UserID: otherUser.ID, NewUsername: "renamed", ReservedUntil: time.Now().Add(time.Hour)}, time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+
+	err = testStorage.UsernameChangeStore.ChangeUsername(&model.UsernameChange{UserID: user.ID, NewUsername: "again", ReservedUntil: time.Now().Add(time.Hour)}, time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, ErrUsernameChangeTooSoon)
+
+	latest, err := testStorage.UsernameChangeStore.GetLatestUsernameChange(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, change.ID, latest.ID)
+
+	changes, err := testStorage.UsernameChangeStore.GetUsernameChangesByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(otherUser.ID)
+	})
+}
+
+func TestFollowStore_ApproveFollowRequest(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	privateUser := createTestUser(t, "private", "private", "private", "private@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(privateUser)
+	assert.NoError(t, err)
+
+	err = testStorage.FollowStore.CreateFollowRequest(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+	// Sending the same request twice keeps a single request.
+	err = testStorage.FollowStore.CreateFollowRequest(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+
+	requests, err := testStorage.FollowStore.GetFollowRequestsByFollowID(privateUser.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+	assert.Equal(t, user.Username, requests[0].User.Username)
+
+	isFollowing, err := testStorage.FollowStore.IsFollowing(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+	assert.False(t, isFollowing)
+
+	approved, err := testStorage.FollowStore.ApproveFollowRequest(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+	assert.True(t, approved)
+
+	isFollowing, err = testStorage.FollowStore.IsFollowing(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+	assert.True(t, isFollowing)
+
+	approved, err = testStorage.FollowStore.ApproveFollowRequest(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+	assert.False(t, approved)
+
+	request, err := testStorage.FollowStore.GetFollowRequest(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, request)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(privateUser.ID)
+	})
+}
+
+func TestPostStore_GetPostDetailsByID_PrivateAccount(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	privateUser := createTestUser(t, "private", "private", "private", "private@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(privateUser)
+	assert.NoError(t, err)
+
+	privateUser.IsPrivate = true
+	err = testStorage.UserStore.UpdateProfile(privateUser)
+	assert.NoError(t, err)
+
+	post := createTestPost(t, "private post", privateUser.ID)
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+
+	existPost, err := testStorage.PostStore.GetPostDetailsByID(post.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, existPost)
+
+	existPost, err = testStorage.PostStore.GetPostDetailsByID(post.ID, privateUser.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existPost)
+
+	err = testStorage.FollowStore.FollowUser(user.ID, privateUser.ID)
+	assert.NoError(t, err)
+
+	existPost, err = testStorage.PostStore.GetPostDetailsByID(post.ID, user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existPost)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(privateUser.ID)
+	})
+}
+
+func TestBlockStore_BlockUser(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	blockedUser := createTestUser(t, "blocked", "blocked", "blocked", "blocked@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(blockedUser)
+	assert.NoError(t, err)
+
+	err = testStorage.FollowStore.FollowUser(user.ID, blockedUser.ID)
+	assert.NoError(t, err)
+	err = testStorage.FollowStore.FollowUser(blockedUser.ID, user.ID)
+	assert.NoError(t, err)
+
+	err = testStorage.BlockStore.BlockUser(user.ID, blockedUser.ID)
+	assert.NoError(t, err)
+
+	isFollowing, err := testStorage.FollowStore.IsFollowing(user.ID, blockedUser.ID)
+	assert.NoError(t, err)
+	assert.False(t, isFollowing)
+	isFollowing, err = testStorage.FollowStore.IsFollowing(blockedUser.ID, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, isFollowing)
+
+	blocked, err := testStorage.BlockStore.IsBlocked(blockedUser.ID, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+
+	users, err := testStorage.UserStore.GetUsersByUsername("blocked", user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, users, 0)
+
+	blocks, err := testStorage.BlockStore.GetBlocksByUserID(user.ID, createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 1)
+	assert.Equal(t, blockedUser.Username, blocks[0].User.Username)
+
+	unblocked, err := testStorage.BlockStore.UnblockUser(user.ID, blockedUser.ID)
+	assert.NoError(t, err)
+	assert.True(t, unblocked)
+
+	users, err = testStorage.UserStore.GetUsersByUsername("blocked", user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(blockedUser.ID)
+	})
+}
+
+func TestBlockStore_BlockUser_HidesThread(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	blockedUser := createTestUser(t, "blocked", "blocked", "blocked", "blocked@test.com", "test")
+	otherUser := createTestUser(t, "other", "other", "other", "other@test.com", "test")
+
+	for _, u := range []*model.User{user, blockedUser, otherUser} {
+		err := testStorage.UserStore.CreateUser(u)
+		assert.NoError(t, err)
+	}
+
+	post := createTestPost(t, "test", user.ID)
+	err := testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+	comment := createTestComment(t, "test", post.ID, otherUser.ID)
+	err = testStorage.CommentStore.CreateComment(comment)
+	assert.NoError(t, err)
+	reply := createTestCommentReply(t, comment.ID, otherUser.ID, "test")
+	err = testStorage.ReplyStore.CreateReply(reply)
+	assert.NoError(t, err)
+
+	comments, err := testStorage.CommentStore.GetCommentsByPostID(post.ID, blockedUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(comments))
+	replies, err := testStorage.ReplyStore.GetRepliesByCommentID(comment.ID, blockedUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(replies))
+
+	// The thread under the post of a user who blocked the viewer is hidden,
+	// even though the comment and reply were written by someone else.
+	err = testStorage.BlockStore.BlockUser(user.ID, blockedUser.ID)
+	assert.NoError(t, err)
+
+	comments, err = testStorage.CommentStore.GetCommentsByPostID(post.ID, blockedUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(comments))
+	replies, err = testStorage.ReplyStore.GetRepliesByCommentID(comment.ID, blockedUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(replies))
+
+	comments, err = testStorage.CommentStore.GetCommentsByPostID(post.ID, otherUser.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(comments))
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(blockedUser.ID)
+		_ = testStorage.UserStore.DeleteUser(otherUser.ID)
+	})
+}
+
+func TestMuteStore_MuteUser(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	mutedUser := createTestUser(t, "muted", "muted", "muted", "muted@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(mutedUser)
+	assert.NoError(t, err)
+
+	err = testStorage.PostStore.CreatePost(createTestPost(t, "muted post", mutedUser.ID))
+	assert.NoError(t, err)
+	err = testStorage.PostStore.CreatePost(createTestPost(t, "Spoilers ahead", user.ID))
+	assert.NoError(t, err)
+
+	expiresAt := time.Now().Add(-time.Hour)
+	err = testStorage.MuteStore.MuteUser(&model.Mute{UserID: user.ID, MutedID: mutedUser.ID, ExpiresAt: &expiresAt})
+	assert.NoError(t, err)
+
+	posts, err := testStorage.PostStore.GetPosts(createTestPagination(t), createTestSearch(t, ""), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+	mutes, err := testStorage.MuteStore.GetMutesByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, mutes, 0)
+
+	err = testStorage.MuteStore.MuteUser(&model.Mute{UserID: user.ID, MutedID: mutedUser.ID})
+	assert.NoError(t, err)
+
+	posts, err = testStorage.PostStore.GetPosts(createTestPagination(t), createTestSearch(t, ""), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	mutes, err = testStorage.MuteStore.GetMutesByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, mutes, 1)
+	assert.Equal(t, mutedUser.Username, mutes[0].User.Username)
+
+	unmuted, err := testStorage.MuteStore.UnmuteUser(user.ID, mutedUser.ID)
+	assert.NoError(t, err)
+	assert.True(t, unmuted)
+
+	keyword := &model.MutedKeyword{UserID: mutedUser.ID, Keyword: "spoilers"}
+	err = testStorage.MuteStore.MuteKeyword(keyword)
+	assert.NoError(t, err)
+
+	posts, err = testStorage.PostStore.GetPosts(createTestPagination(t), createTestSearch(t, ""), mutedUser.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Equal(t, "muted post", posts[0].Content)
+
+	unmuted, err = testStorage.MuteStore.UnmuteKeyword(keyword.ID, mutedUser.ID)
+	assert.NoError(t, err)
+	assert.True(t, unmuted)
+
+	// Keywords only match whole words.
+	keyword = &model.MutedKeyword{UserID: mutedUser.ID, Keyword: "spoil"}
+	err = testStorage.MuteStore.MuteKeyword(keyword)
+	assert.NoError(t, err)
+
+	posts, err = testStorage.PostStore.GetPosts(createTestPagination(t), createTestSearch(t, ""), mutedUser.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+
+	unmuted, err = testStorage.MuteStore.UnmuteKeyword(keyword.ID, mutedUser.ID)
+	assert.NoError(t, err)
+	assert.True(t, unmuted)
+
+	// Regular expression characters in keywords are matched literally.
+	keyword = &model.MutedKeyword{UserID: mutedUser.ID, Keyword: "spoilers+"}
+	err = testStorage.MuteStore.MuteKeyword(keyword)
+	assert.NoError(t, err)
+
+	posts, err = testStorage.PostStore.GetPosts(createTestPagination(t), createTestSearch(t, ""), mutedUser.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+
+	unmuted, err = testStorage.MuteStore.UnmuteKeyword(keyword.ID, mutedUser.ID)
+	assert.NoError(t, err)
+	assert.True(t, unmuted)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(mutedUser.ID)
+	})
+}
+
+func TestDataExportStore_ClaimDataExport(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	export := &model.DataExport{UserID: user.ID}
+	err = testStorage.DataExportStore.CreateDataExport(export)
+	assert.NoError(t, err)
+	assert.Equal(t, model.DataExportStatusPending, export.Status)
+
+	unfinished, err := testStorage.DataExportStore.GetUnfinishedDataExport(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, export.ID, unfinished.ID)
+
+	claimed, err := testStorage.DataExportStore.ClaimDataExport(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.NotNil(t, claimed)
+	assert.Equal(t, export.ID, claimed.ID)
+	assert.Equal(t, model.DataExportStatusRunning, claimed.Status)
+
+	claimed, err = testStorage.DataExportStore.ClaimDataExport(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Nil(t, claimed)
+
+	err = testStorage.DataExportStore.CompleteDataExport(export.ID, "exports/test.zip", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	expired, err := testStorage.DataExportStore.GetExpiredDataExports(10)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+	assert.Equal(t, "exports/test.zip", *expired[0].BlobKey)
+
+	err = testStorage.DataExportStore.ExpireDataExport(export.ID)
+	assert.NoError(t, err)
+	export, err = testStorage.DataExportStore.GetDataExportByID(export.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.DataExportStatusExpired, export.Status)
+	assert.Nil(t, export.BlobKey)
+
+	// The archive of a deleted user is expired right away.
+	export = &model.DataExport{UserID: user.ID}
+	err = testStorage.DataExportStore.CreateDataExport(export)
+	assert.NoError(t, err)
+	err = testStorage.DataExportStore.CompleteDataExport(export.ID, "exports/deleted.zip", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	expired, err = testStorage.DataExportStore.GetExpiredDataExports(10)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 0)
+
+	err = testStorage.UserStore.DeleteUser(user.ID)
+	assert.NoError(t, err)
+
+	expired, err = testStorage.DataExportStore.GetExpiredDataExports(10)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+	assert.Equal(t, uuid.Nil, expired[0].UserID)
+	assert.Equal(t, "exports/deleted.zip", *expired[0].BlobKey)
+
+	err = testStorage.DataExportStore.ExpireDataExport(export.ID)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_, _ = testDB.Exec("DELETE FROM data_exports WHERE id = $1", export.ID)
+	})
+}
+
+func TestMediaStore_AttachMedia(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	media := &model.Media{UserID: &user.ID, BlobKey: "media/test.jpg", ThumbnailKey: "media/test_thumb.jpg", ContentType: "image/jpeg", Width: 800, Height: 600, Size: 1024}
+	err = testStorage.MediaStore.CreateMedia(media)
+	assert.NoError(t, err)
+
+	orphaned, err := testStorage.MediaStore.GetOrphanedMedia(time.Now().Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, orphaned, 1)
+	orphaned, err = testStorage.MediaStore.GetOrphanedMedia(time.Now().Add(-time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, orphaned, 0)
+
+	image, thumbnail := "http://localhost/media/test.jpg", "http://localhost/media/test_thumb.jpg"
+	post := createTestPost(t, "test", user.ID)
+	post.MediaID, post.Image, post.Thumbnail = &media.ID, &image, &thumbnail
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+
+	posts, err := testStorage.PostStore.GetPosts(createTestPagination(t), createTestSearch(t, ""), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Equal(t, image, *posts[0].Image)
+	assert.Equal(t, thumbnail, *posts[0].Thumbnail)
+
+	other := createTestPost(t, "other", user.ID)
+	other.MediaID = &media.ID
+	err = testStorage.PostStore.CreatePost(other)
+	assert.ErrorIs(t, err, ErrMediaAttached)
+
+	orphaned, err = testStorage.MediaStore.GetOrphanedMedia(time.Now().Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, orphaned, 0)
+
+	err = testStorage.PostStore.DeletePost(post.ID)
+	assert.NoError(t, err)
+	orphaned, err = testStorage.MediaStore.GetOrphanedMedia(time.Now().Add(-time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, orphaned, 1)
+	assert.Equal(t, media.ID, orphaned[0].ID)
+
+	t.Cleanup(func() {
+		_, _ = testDB.Exec("DELETE FROM media WHERE id = $1", media.ID)
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestMediaStore_DeleteOrphanedMedia(t *testing.T) {
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+	err := testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	// Media attached after the cleaner listed it keeps its files.
+	attached := &model.Media{UserID: &user.ID, BlobKey: "media/attached.jpg", ThumbnailKey: "media/attached_thumb.jpg", ContentType: "image/jpeg", Width: 800, Height: 600, Size: 1024}
+	err = testStorage.MediaStore.CreateMedia(attached)
+	assert.NoError(t, err)
+	orphaned, err := testStorage.MediaStore.GetOrphanedMedia(time.Now().Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, orphaned, 1)
+
+	post := createTestPost(t, "test", user.ID)
+	post.MediaID = &attached.ID
+	err = testStorage.PostStore.CreatePost(post)
+	assert.NoError(t, err)
+
+	deleted, err := testStorage.MediaStore.DeleteOrphanedMedia(orphaned[0].ID, func(media model.Media) error {
+		t.Error("deleted the blobs of attached media")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	existing, err := testStorage.MediaStore.GetMediaByID(attached.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existing)
+
+	// Media being deleted can't be attached until it is gone.
+	media := &model.Media{UserID: &user.ID, BlobKey: "media/test.jpg", ThumbnailKey: "media/test_thumb.jpg", ContentType: "image/jpeg", Width: 800, Height: 600, Size: 1024}
+	err = testStorage.MediaStore.CreateMedia(media)
+	assert.NoError(t, err)
+
+	other := createTestPost(t, "other", user.ID)
+	other.MediaID = &media.ID
+	attachErr := make(chan error, 1)
+	deleted, err = testStorage.MediaStore.DeleteOrphanedMedia(media.ID, func(media model.Media) error {
+		go func() { attachErr <- testStorage.PostStore.CreatePost(other) }()
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	assert.ErrorIs(t, <-attachErr, ErrMediaAttached)
+
+	existing, err = testStorage.MediaStore.GetMediaByID(media.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	// Blobs that fail to delete leave the row to be retried.
+	failed := &model.Media{UserID: &user.ID, BlobKey: "media/failed.jpg", ThumbnailKey: "media/failed_thumb.jpg", ContentType: "image/jpeg", Width: 800, Height: 600, Size: 1024}
+	err = testStorage.MediaStore.CreateMedia(failed)
+	assert.NoError(t, err)
+	deleted, err = testStorage.MediaStore.DeleteOrphanedMedia(failed.ID, func(media model.Media) error {
+		return errors.New("blob store unavailable")
+	})
+	assert.Error(t, err)
+	assert.False(t, deleted)
+	existing, err = testStorage.MediaStore.GetMediaByID(failed.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, existing)
+
+	t.Cleanup(func() {
+		_ = testStorage.PostStore.DeletePost(post.ID)
+		_, _ = testDB.Exec("DELETE FROM media WHERE id IN ($1, $2, $3)", attached.ID, media.ID, failed.ID)
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+	})
+}
+
+func TestModerationStore_CreateModerationAction(t *testing.T) {
+	moderator := createTestUser(t, "moderator", "moderator", "moderator", "moderator@test.com", "test")
+	user := createTestUser(t, "test", "test", "test", "test@test.com", "test")
+
+	err := testStorage.UserStore.CreateUser(moderator)
+	assert.NoError(t, err)
+	err = testStorage.UserStore.CreateUser(user)
+	assert.NoError(t, err)
+
+	targetID := uuid.New()
+	action := &model.ModerationAction{
+		ModeratorID:     &moderator.ID,
+		Action:          model.ModerationActionDelete,
+		TargetType:      model.ModerationTargetPost,
+		TargetID:        targetID,
+		TargetUserID:    &user.ID,
+		PreviousContent: "spam",
+	}
+	err = testStorage.ModerationStore.CreateModerationAction(action)
+	assert.NoError(t, err)
+
+	actions, err := testStorage.ModerationStore.GetModerationActions(createTestPagination(t))
+	assert.NoError(t, err)
+	assert.Len(t, actions, 1)
+	assert.Equal(t, targetID.String(), actions[0].TargetID.String())
+	assert.Equal(t, moderator.ID.String(), actions[0].ModeratorID.String())
+	assert.Equal(t, "spam", actions[0].PreviousContent)
+
+	t.Cleanup(func() {
+		_ = testStorage.UserStore.DeleteUser(user.ID)
+		_ = testStorage.UserStore.DeleteUser(moderator.ID)
+	})
+}
+
 func TestMain(m *testing.M) {
 	testStorage = NewPostgresTestStorage()
 	testDB = testStorage.UserStore.(*UserStore).DB
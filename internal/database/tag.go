@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/hashtag"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type BaseTagStore interface {
+	GetTrendingTags(since time.Time, limit int) ([]model.TrendingTag, error)
+}
+
+type TagStore struct {
+	DB *sql.DB
+}
+
+func NewTagStore(db *sql.DB) BaseTagStore {
+	return &TagStore{DB: db}
+}
+
+// GetTrendingTags returns the hashtags used in the most posts and comments
+// since the given time. Tags are ranked by the number of users that used them
+// so a single account can't push a tag up by repeating it. Only published
+// posts of public, active accounts and comments on them count.
+func (s *TagStore) GetTrendingTags(since time.Time, limit int) ([]model.TrendingTag, error) {
+	query := `
+	WITH tag_uses AS (
+		SELECT post_tags.tag_id, posts.user_id, posts.id AS post_id FROM post_tags
+		JOIN posts ON posts.id = post_tags.post_id
+		WHERE posts.created_at >= $1
+		UNION ALL
+		SELECT comment_tags.tag_id, comments.user_id, comments.post_id FROM comment_tags
+		JOIN comments ON comments.id = comment_tags.comment_id
+		WHERE comments.created_at >= $1
+	)
+
+	SELECT tags.name, COUNT(DISTINCT tag_uses.user_id) AS total_users, COUNT(*) AS total_uses
+	FROM tag_uses
+	JOIN tags ON tags.id = tag_uses.tag_id
+	JOIN posts ON posts.id = tag_uses.post_id
+	JOIN users AS post_user ON post_user.id = posts.user_id
+	JOIN users AS tag_user ON tag_user.id = tag_uses.user_id
+	WHERE posts.status = 'published'
+	AND NOT post_user.is_private AND post_user.deactivated_at IS NULL
+	AND NOT tag_user.is_private AND tag_user.deactivated_at IS NULL
+	GROUP BY tags.name
+	ORDER BY total_users DESC, total_uses DESC, tags.name ASC
+	LIMIT $2`
+	rows, err := s.DB.Query(query, since.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []model.TrendingTag{}
+	for rows.Next() {
+		tag := model.TrendingTag{}
+		if err := rows.Scan(&tag.Name, &tag.UserCount, &tag.UseCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// setPostTags replaces the tags of a post with the hashtags in its content.
+func setPostTags(tx *sql.Tx, postID uuid.UUID, content string) error {
+	return setTags(tx,
+		"DELETE FROM post_tags WHERE post_id = $1 AND tag_id NOT IN (SELECT id FROM tags WHERE name = ANY ($2::text[]))",
+		"INSERT INTO post_tags (post_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY ($2::text[]) ON CONFLICT DO NOTHING",
+		postID, content)
+}
+
+// setCommentTags replaces the tags of a comment with the hashtags in its
+// content.
+func setCommentTags(tx *sql.Tx, commentID uuid.UUID, content string) error {
+	return setTags(tx,
+		"DELETE FROM comment_tags WHERE comment_id = $1 AND tag_id NOT IN (SELECT id FROM tags WHERE name = ANY ($2::text[]))",
+		"INSERT INTO comment_tags (comment_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY ($2::text[]) ON CONFLICT DO NOTHING",
+		commentID, content)
+}
+
+// setTags creates the tags for the hashtags in content that don't exist yet,
+// then removes the links to tags that are no longer used with deleteQuery and
+// links the new ones with insertQuery. Both get the ID and the tag names.
+func setTags(tx *sql.Tx, deleteQuery, insertQuery string, id uuid.UUID, content string) error {
+	names := pq.Array(hashtag.Parse(content))
+	query := "INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING"
+	if _, err := tx.Exec(query, names); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(deleteQuery, id, names); err != nil {
+		return err
+	}
+	_, err := tx.Exec(insertQuery, id, names)
+	return err
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that was already
+// rotated or revoked is presented again.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+type BaseTokenStore interface {
+	CreateRefreshToken(token *model.RefreshToken) error
+	GetRefreshTokenByHash(tokenHash string) (*model.RefreshToken, error)
+	RotateRefreshToken(oldTokenID uuid.UUID, newToken *model.RefreshToken) error
+	RevokeAccessToken(jti uuid.UUID, expiresAt time.Time) error
+	IsAccessTokenRevoked(jti uuid.UUID) (bool, error)
+}
+
+type TokenStore struct {
+	DB *sql.DB
+}
+
+func NewTokenStore(db *sql.DB) BaseTokenStore {
+	return &TokenStore{DB: db}
+}
+
+func (s *TokenStore) CreateRefreshToken(token *model.RefreshToken) error {
+	query := "INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	return s.DB.QueryRow(query, token.UserID, token.SessionID, token.TokenHash, token.ExpiresAt.UTC()).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (s *TokenStore) GetRefreshTokenByHash(tokenHash string) (*model.RefreshToken, error) {
+	token := &model.RefreshToken{}
+	query := "SELECT id, user_id, session_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = $1"
+	err := s.DB.QueryRow(query, tokenHash).Scan(&token.ID, &token.UserID, &token.SessionID, &token.TokenHash, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken revokes the old token and stores its replacement in a
+// single transaction. If the old token was already revoked, nothing is stored
+// and ErrRefreshTokenReused is returned.
+func (s *TokenStore) RotateRefreshToken(oldTokenID uuid.UUID, newToken *model.RefreshToken) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", oldTokenID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	query := "INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	err = tx.QueryRow(query, newToken.UserID, newToken.SessionID, newToken.TokenHash, newToken.ExpiresAt.UTC()).Scan(&newToken.ID, &newToken.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeAccessToken stores the jti until the access token expires. Entries
+// of tokens that have already expired are purged on the way, since an
+// expired token is rejected anyway.
+func (s *TokenStore) RevokeAccessToken(jti uuid.UUID, expiresAt time.Time) error {
+	if _, err := s.DB.Exec("DELETE FROM revoked_tokens WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+	query := "INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING"
+	_, err := s.DB.Exec(query, jti, expiresAt.UTC())
+	return err
+}
+
+func (s *TokenStore) IsAccessTokenRevoked(jti uuid.UUID) (bool, error) {
+	var result bool
+	query := "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)"
+	err := s.DB.QueryRow(query, jti).Scan(&result)
+	if err != nil {
+		return false, err
+	}
+	return result, nil
+}
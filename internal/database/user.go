@@ -2,19 +2,34 @@ package database
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/fatihesergg/go_social/internal/model"
 	"github.com/google/uuid"
 )
 
 type BaseUserStore interface {
-	GetUsersByUsername(userName string) ([]model.User, error)
+	GetUsersByUsername(userName string, userID uuid.UUID) ([]model.User, error)
 	GetUserByID(id uuid.UUID) (*model.User, error)
+	GetProfile(id, viewerID uuid.UUID) (*model.Profile, error)
 	GetUserByUsername(username string) (*model.User, error)
 	GetUserByEmail(email string) (*model.User, error)
 	CreateUser(user *model.User) error
 	UpdateUser(user *model.User) error
+	VerifyEmail(id uuid.UUID) error
+	SetTOTPSecret(id uuid.UUID, secret string) error
+	EnableTOTP(id uuid.UUID) error
+	DisableTOTP(id uuid.UUID) error
+	UseTOTPStep(id uuid.UUID, step int64) (bool, error)
+	UpdateUserRole(id uuid.UUID, role string) error
+	UpdateProfile(user *model.User) error
+	UpdateAvatar(id uuid.UUID, avatar *string) error
 	DeleteUser(id uuid.UUID) error
+	DeactivateUser(id uuid.UUID) error
+	ReactivateUser(id uuid.UUID) error
+	GetDeactivatedUsers(deactivatedBefore time.Time, limit int) ([]model.User, error)
+	DeleteDeactivatedUser(id uuid.UUID, deactivatedBefore time.Time) (bool, error)
+	AnonymizeUser(id uuid.UUID, deactivatedBefore time.Time) (bool, error)
 }
 
 type UserStore struct {
@@ -28,10 +43,10 @@ func NewUserStore(db *sql.DB) BaseUserStore {
 func (s *UserStore) GetUserByID(id uuid.UUID) (*model.User, error) {
 	user := &model.User{}
 
-	query := "SELECT id,name,last_name,username,email,password,avatar,created_at,updated_at FROM users WHERE id = $1"
+	query := "SELECT id,name,last_name,username,email,password,avatar,created_at,updated_at,email_verified_at,totp_secret,totp_enabled_at,role,display_name,bio,website,location,is_private,deactivated_at FROM users WHERE id = $1"
 	row := s.DB.QueryRow(query, id.String())
 
-	err := row.Scan(&user.ID, &user.Name, &user.LastName, &user.Username, &user.Email, &user.Password, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Name, &user.LastName, &user.Username, &user.Email, &user.Password, &user.Avatar, &user.CreatedAt, &user.UpdatedAt, &user.EmailVerifiedAt, &user.TOTPSecret, &user.TOTPEnabledAt, &user.Role, &user.DisplayName, &user.Bio, &user.Website, &user.Location, &user.IsPrivate, &user.DeactivatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -42,13 +57,38 @@ func (s *UserStore) GetUserByID(id uuid.UUID) (*model.User, error) {
 	return user, nil
 }
 
+// GetProfile returns a user with their follower, following and post counts
+// and whether they follow the viewer and the viewer follows them.
+func (s *UserStore) GetProfile(id, viewerID uuid.UUID) (*model.Profile, error) {
+	profile := &model.Profile{}
+	query := `SELECT id, name, last_name, username, avatar, display_name, bio, website, location, is_private, role,
+	(SELECT COUNT(*) FROM follows WHERE follows.follow_id = users.id),
+	(SELECT COUNT(*) FROM follows WHERE follows.user_id = users.id),
+	(SELECT COUNT(*) FROM posts WHERE posts.user_id = users.id AND posts.status = 'published'),
+	EXISTS (SELECT 1 FROM follows WHERE follows.user_id = $2 AND follows.follow_id = users.id),
+	EXISTS (SELECT 1 FROM follows WHERE follows.user_id = users.id AND follows.follow_id = $2),
+	EXISTS (SELECT 1 FROM follow_requests WHERE follow_requests.user_id = $2 AND follow_requests.follow_id = users.id)
+	FROM users WHERE id = $1`
+	err := s.DB.QueryRow(query, id, viewerID).Scan(&profile.ID, &profile.Name, &profile.LastName, &profile.Username, &profile.Avatar,
+		&profile.DisplayName, &profile.Bio, &profile.Website, &profile.Location, &profile.IsPrivate, &profile.Role,
+		&profile.FollowerCount, &profile.FollowingCount, &profile.PostCount,
+		&profile.IsFollowing, &profile.FollowsYou, &profile.IsFollowRequested)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
 func (s *UserStore) GetUserByUsername(username string) (*model.User, error) {
 	user := &model.User{}
 
-	query := "SELECT id,name,last_name,username,email,password,avatar,created_at,updated_at FROM users WHERE username = $1"
+	query := "SELECT id,name,last_name,username,email,password,avatar,created_at,updated_at,email_verified_at,totp_secret,totp_enabled_at,role,display_name,bio,website,location,is_private,deactivated_at FROM users WHERE username = $1"
 	row := s.DB.QueryRow(query, username)
 
-	err := row.Scan(&user.ID, &user.Name, &user.LastName, &user.Username, &user.Email, &user.Password, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Name, &user.LastName, &user.Username, &user.Email, &user.Password, &user.Avatar, &user.CreatedAt, &user.UpdatedAt, &user.EmailVerifiedAt, &user.TOTPSecret, &user.TOTPEnabledAt, &user.Role, &user.DisplayName, &user.Bio, &user.Website, &user.Location, &user.IsPrivate, &user.DeactivatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -62,10 +102,10 @@ func (s *UserStore) GetUserByUsername(username string) (*model.User, error) {
 func (s *UserStore) GetUserByEmail(email string) (*model.User, error) {
 	user := &model.User{}
 
-	query := "SELECT id, name, last_name, username, email, password, created_at, updated_at, avatar FROM users WHERE email = $1"
+	query := "SELECT id, name, last_name, username, email, password, created_at, updated_at, avatar, email_verified_at, totp_secret, totp_enabled_at, role, display_name, bio, website, location, is_private, deactivated_at FROM users WHERE email = $1"
 	row := s.DB.QueryRow(query, email)
 
-	err := row.Scan(&user.ID, &user.Name, &user.LastName, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt, &user.Avatar)
+	err := row.Scan(&user.ID, &user.Name, &user.LastName, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt, &user.Avatar, &user.EmailVerifiedAt, &user.TOTPSecret, &user.TOTPEnabledAt, &user.Role, &user.DisplayName, &user.Bio, &user.Website, &user.Location, &user.IsPrivate, &user.DeactivatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -77,9 +117,8 @@ func (s *UserStore) GetUserByEmail(email string) (*model.User, error) {
 }
 
 func (s *UserStore) CreateUser(user *model.User) error {
-	var id uuid.UUID
 	query := "INSERT INTO users (name, last_name, username, email, password, avatar) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
-	err := s.DB.QueryRow(query, user.Name, user.LastName, user.Username, user.Email, user.Password, user.Avatar).Scan(&id)
+	err := s.DB.QueryRow(query, user.Name, user.LastName, user.Username, user.Email, user.Password, user.Avatar).Scan(&user.ID)
 	if err != nil {
 
 		return err
@@ -96,6 +135,66 @@ func (s *UserStore) UpdateUser(user *model.User) error {
 	return nil
 }
 
+func (s *UserStore) VerifyEmail(id uuid.UUID) error {
+	query := "UPDATE users SET email_verified_at = NOW() WHERE id = $1 AND email_verified_at IS NULL"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+// SetTOTPSecret stores a new secret for a user that has not enabled two
+// factor authentication yet.
+func (s *UserStore) SetTOTPSecret(id uuid.UUID, secret string) error {
+	query := "UPDATE users SET totp_secret = $1, totp_last_used_step = NULL WHERE id = $2 AND totp_enabled_at IS NULL"
+	_, err := s.DB.Exec(query, secret, id)
+	return err
+}
+
+func (s *UserStore) EnableTOTP(id uuid.UUID) error {
+	query := "UPDATE users SET totp_enabled_at = NOW() WHERE id = $1 AND totp_secret IS NOT NULL"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+func (s *UserStore) DisableTOTP(id uuid.UUID) error {
+	query := "UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_used_step = NULL WHERE id = $1"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code. It returns false
+// if a code of the same or a later step was already used, so a code can't be
+// replayed.
+func (s *UserStore) UseTOTPStep(id uuid.UUID, step int64) (bool, error) {
+	query := "UPDATE users SET totp_last_used_step = $1 WHERE id = $2 AND (totp_last_used_step IS NULL OR totp_last_used_step < $1)"
+	result, err := s.DB.Exec(query, step, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (s *UserStore) UpdateUserRole(id uuid.UUID, role string) error {
+	query := "UPDATE users SET role = $1 WHERE id = $2"
+	_, err := s.DB.Exec(query, role, id)
+	return err
+}
+
+func (s *UserStore) UpdateProfile(user *model.User) error {
+	query := "UPDATE users SET display_name = $1, bio = $2, website = $3, location = $4, is_private = $5, updated_at = NOW() WHERE id = $6"
+	_, err := s.DB.Exec(query, user.DisplayName, user.Bio, user.Website, user.Location, user.IsPrivate, user.ID)
+	return err
+}
+
+func (s *UserStore) UpdateAvatar(id uuid.UUID, avatar *string) error {
+	query := "UPDATE users SET avatar = $1, updated_at = NOW() WHERE id = $2"
+	_, err := s.DB.Exec(query, avatar, id)
+	return err
+}
+
 func (s *UserStore) DeleteUser(id uuid.UUID) error {
 	query := "DELETE FROM users WHERE id = $1"
 	_, err := s.DB.Exec(query, id)
@@ -105,10 +204,10 @@ func (s *UserStore) DeleteUser(id uuid.UUID) error {
 	return nil
 }
 
-func (s *UserStore) GetUsersByUsername(userName string) ([]model.User, error) {
+func (s *UserStore) GetUsersByUsername(userName string, userID uuid.UUID) ([]model.User, error) {
 	users := []model.User{}
-	query := "SELECT id,name,last_name,username FROM users WHERE username ILIKE '%' || $1 || '%'"
-	rows, err := s.DB.Query(query, userName)
+	query := "SELECT id,name,last_name,username FROM users WHERE username ILIKE '%' || $1 || '%' AND deactivated_at IS NULL AND " + notBlocked("users.id", "$2")
+	rows, err := s.DB.Query(query, userName, userID)
 
 	if err != nil {
 		return nil, err
@@ -132,3 +231,168 @@ func (s *UserStore) GetUsersByUsername(userName string) ([]model.User, error) {
 	return users, err
 
 }
+
+// DeactivateUser hides a user and signs them out everywhere. The account can
+// be reactivated by logging in until it is purged.
+func (s *UserStore) DeactivateUser(id uuid.UUID) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET deactivated_at = NOW() WHERE id = $1 AND deactivated_at IS NULL", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE personal_access_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *UserStore) ReactivateUser(id uuid.UUID) error {
+	query := "UPDATE users SET deactivated_at = NULL WHERE id = $1"
+	_, err := s.DB.Exec(query, id)
+	return err
+}
+
+// GetDeactivatedUsers returns up to limit users that were deactivated before
+// deactivatedBefore, oldest first.
+func (s *UserStore) GetDeactivatedUsers(deactivatedBefore time.Time, limit int) ([]model.User, error) {
+	users := []model.User{}
+	query := `SELECT id, username, avatar, deactivated_at FROM users
+	WHERE deactivated_at IS NOT NULL AND deactivated_at < $1
+	ORDER BY deactivated_at ASC
+	LIMIT $2`
+	rows, err := s.DB.Query(query, deactivatedBefore.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user := model.User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Avatar, &user.DeactivatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// DeleteDeactivatedUser deletes a user together with everything they created
+// if they are still deactivated since before deactivatedBefore. It returns
+// false if the account was reactivated in the meantime.
+func (s *UserStore) DeleteDeactivatedUser(id uuid.UUID, deactivatedBefore time.Time) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var email string
+	query := "DELETE FROM users WHERE id = $1 AND deactivated_at IS NOT NULL AND deactivated_at < $2 RETURNING email"
+	if err := tx.QueryRow(query, id, deactivatedBefore.UTC()).Scan(&email); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := deleteLoginHistory(tx, id, email); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteLoginHistory deletes the failed login counters and lockout records of
+// a user inside tx. Those of their account are keyed by their email, which
+// they would keep otherwise.
+func deleteLoginHistory(tx *sql.Tx, id uuid.UUID, email string) error {
+	query := "DELETE FROM login_attempts WHERE scope = $1 AND subject = lower($2)"
+	if _, err := tx.Exec(query, model.LoginAttemptScopeAccount, email); err != nil {
+		return err
+	}
+	query = "DELETE FROM login_lockouts WHERE user_id = $1 OR (scope = $2 AND subject = lower($3))"
+	_, err := tx.Exec(query, id, model.LoginAttemptScopeAccount, email)
+	return err
+}
+
+// AnonymizeUser removes everything that identifies a user and their social
+// graph but keeps their posts, comments and replies, so threads other people
+// took part in stay intact. Drafts and scheduled posts are deleted, so they
+// are never published. The account can't be logged in to anymore and shows
+// up as a deleted user. Like DeleteDeactivatedUser, it only anonymizes
+// a user that is still deactivated since before deactivatedBefore and
+// returns false otherwise.
+func (s *UserStore) AnonymizeUser(id uuid.UUID, deactivatedBefore time.Time) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var email string
+	query := "SELECT email FROM users WHERE id = $1 AND deactivated_at IS NOT NULL AND deactivated_at < $2 FOR UPDATE"
+	if err := tx.QueryRow(query, id, deactivatedBefore.UTC()).Scan(&email); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	query = `UPDATE users SET
+	name = 'Deleted', last_name = 'User',
+	username = 'deleted_' || replace(id::text, '-', ''),
+	email = 'deleted+' || id::text || '@invalid',
+	password = '', avatar = NULL, display_name = '', bio = '', website = '', location = '',
+	is_private = FALSE, role = 'user', email_verified_at = NULL, totp_secret = NULL, totp_enabled_at = NULL,
+	deactivated_at = NULL, anonymized_at = NOW(), updated_at = NOW()
+	WHERE id = $1`
+	if _, err := tx.Exec(query, id); err != nil {
+		return false, err
+	}
+	if err := deleteLoginHistory(tx, id, email); err != nil {
+		return false, err
+	}
+
+	deletes := []string{
+		"DELETE FROM posts WHERE user_id = $1 AND status <> 'published'",
+		// The archives of data exports are deleted by the data exporter.
+		"UPDATE data_exports SET user_id = NULL WHERE user_id = $1",
+		"DELETE FROM follows WHERE user_id = $1 OR follow_id = $1",
+		"DELETE FROM follow_requests WHERE user_id = $1 OR follow_id = $1",
+		"DELETE FROM blocks WHERE user_id = $1 OR blocked_id = $1",
+		"DELETE FROM mutes WHERE user_id = $1 OR muted_id = $1",
+		"DELETE FROM muted_keywords WHERE user_id = $1",
+		"DELETE FROM post_likes WHERE user_id = $1",
+		"DELETE FROM comment_likes WHERE user_id = $1",
+		"DELETE FROM reposts WHERE user_id = $1",
+		"DELETE FROM refresh_tokens WHERE user_id = $1",
+		"DELETE FROM sessions WHERE user_id = $1",
+		"DELETE FROM user_tokens WHERE user_id = $1",
+		"DELETE FROM recovery_codes WHERE user_id = $1",
+		"DELETE FROM user_identities WHERE user_id = $1",
+		"DELETE FROM personal_access_tokens WHERE user_id = $1",
+		"DELETE FROM username_changes WHERE user_id = $1",
+	}
+	for _, query := range deletes {
+		if _, err := tx.Exec(query, id); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
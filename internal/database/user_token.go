@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+)
+
+type BaseUserTokenStore interface {
+	CreateUserToken(token *model.UserToken) error
+	GetUserTokenByHash(tokenHash, purpose string) (*model.UserToken, error)
+	UseUserToken(id uuid.UUID) (bool, error)
+	ResetPassword(tokenID, userID uuid.UUID, password string) (bool, error)
+	DeleteUserTokens(userID uuid.UUID, purpose string) error
+}
+
+type UserTokenStore struct {
+	DB *sql.DB
+}
+
+func NewUserTokenStore(db *sql.DB) BaseUserTokenStore {
+	return &UserTokenStore{DB: db}
+}
+
+func (s *UserTokenStore) CreateUserToken(token *model.UserToken) error {
+	query := "INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	return s.DB.QueryRow(query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt.UTC()).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (s *UserTokenStore) GetUserTokenByHash(tokenHash, purpose string) (*model.UserToken, error) {
+	token := &model.UserToken{}
+	query := "SELECT id, user_id, purpose, token_hash, expires_at, used_at, created_at FROM user_tokens WHERE token_hash = $1 AND purpose = $2"
+	err := s.DB.QueryRow(query, tokenHash, purpose).Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// UseUserToken marks a token as used. It returns false if the token was
+// already used, so a token can only be redeemed once even under concurrent
+// requests.
+func (s *UserTokenStore) UseUserToken(id uuid.UUID) (bool, error) {
+	query := "UPDATE user_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL"
+	result, err := s.DB.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ResetPassword marks a password reset token as used and sets the new
+// password in a single transaction, so a failure leaves the token usable.
+// It returns false if the token was already used.
+func (s *UserTokenStore) ResetPassword(tokenID, userID uuid.UUID, password string) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE user_tokens SET used_at = NOW() WHERE id = $1 AND user_id = $2 AND used_at IS NULL", tokenID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec("UPDATE users SET password = $1 WHERE id = $2", password, userID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *UserTokenStore) DeleteUserTokens(userID uuid.UUID, purpose string) error {
+	query := "DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2"
+	_, err := s.DB.Exec(query, userID, purpose)
+	return err
+}
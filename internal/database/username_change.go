@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrUsernameTaken is returned when the new username belongs to another user
+// or is still reserved for its previous owner.
+var ErrUsernameTaken = errors.New("username taken")
+
+// ErrUsernameChangeTooSoon is returned when the user changed their username
+// after the given time.
+var ErrUsernameChangeTooSoon = errors.New("username changed too soon")
+
+type BaseUsernameChangeStore interface {
+	ChangeUsername(change *model.UsernameChange, notChangedSince time.Time) error
+	GetLatestUsernameChange(userID uuid.UUID) (*model.UsernameChange, error)
+	GetReservedUsername(username string) (*model.UsernameChange, error)
+	GetUsernameChangesByUserID(userID uuid.UUID) ([]model.UsernameChange, error)
+}
+
+type UsernameChangeStore struct {
+	DB *sql.DB
+}
+
+func NewUsernameChangeStore(db *sql.DB) BaseUsernameChangeStore {
+	return &UsernameChangeStore{DB: db}
+}
+
+// ChangeUsername renames change.UserID to change.NewUsername and records the
+// old username in a single transaction. The user row is locked, so concurrent
+// changes of the same user can't slip past the notChangedSince check.
+func (s *UsernameChangeStore) ChangeUsername(change *model.UsernameChange, notChangedSince time.Time) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT username FROM users WHERE id = $1 FOR UPDATE", change.UserID).Scan(&change.OldUsername)
+	if err != nil {
+		return err
+	}
+
+	var changedRecently bool
+	query := "SELECT EXISTS (SELECT 1 FROM username_changes WHERE user_id = $1 AND changed_at > $2)"
+	if err := tx.QueryRow(query, change.UserID, notChangedSince.UTC()).Scan(&changedRecently); err != nil {
+		return err
+	}
+	if changedRecently {
+		return ErrUsernameChangeTooSoon
+	}
+
+	var reserved bool
+	query = "SELECT EXISTS (SELECT 1 FROM username_changes WHERE old_username = $1 AND user_id <> $2 AND reserved_until > NOW())"
+	if err := tx.QueryRow(query, change.NewUsername, change.UserID).Scan(&reserved); err != nil {
+		return err
+	}
+	if reserved {
+		return ErrUsernameTaken
+	}
+
+	_, err = tx.Exec("UPDATE users SET username = $1, updated_at = NOW() WHERE id = $2", change.NewUsername, change.UserID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrUsernameTaken
+		}
+		return err
+	}
+
+	query = "INSERT INTO username_changes (user_id, old_username, new_username, reserved_until) VALUES ($1, $2, $3, $4) RETURNING id, changed_at"
+	err = tx.QueryRow(query, change.UserID, change.OldUsername, change.NewUsername, change.ReservedUntil.UTC()).Scan(&change.ID, &change.ChangedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *UsernameChangeStore) GetLatestUsernameChange(userID uuid.UUID) (*model.UsernameChange, error) {
+	change := &model.UsernameChange{}
+	query := `SELECT id, user_id, old_username, new_username, changed_at, reserved_until FROM username_changes
+	WHERE user_id = $1
+	ORDER BY changed_at DESC
+	LIMIT 1`
+	err := s.DB.QueryRow(query, userID).Scan(&change.ID, &change.UserID, &change.OldUsername, &change.NewUsername, &change.ChangedAt, &change.ReservedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return change, nil
+}
+
+// GetReservedUsername returns the latest change away from username whose
+// reservation hasn't ended yet.
+func (s *UsernameChangeStore) GetReservedUsername(username string) (*model.UsernameChange, error) {
+	change := &model.UsernameChange{}
+	query := `SELECT id, user_id, old_username, new_username, changed_at, reserved_until FROM username_changes
+	WHERE old_username = $1 AND reserved_until > NOW()
+	ORDER BY changed_at DESC
+	LIMIT 1`
+	err := s.DB.QueryRow(query, username).Scan(&change.ID, &change.UserID, &change.OldUsername, &change.NewUsername, &change.ChangedAt, &change.ReservedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return change, nil
+}
+
+func (s *UsernameChangeStore) GetUsernameChangesByUserID(userID uuid.UUID) ([]model.UsernameChange, error) {
+	changes := []model.UsernameChange{}
+	query := `SELECT id, user_id, old_username, new_username, changed_at, reserved_until FROM username_changes
+	WHERE user_id = $1
+	ORDER BY changed_at DESC`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		change := model.UsernameChange{}
+		err := rows.Scan(&change.ID, &change.UserID, &change.OldUsername, &change.NewUsername, &change.ChangedAt, &change.ReservedUntil)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
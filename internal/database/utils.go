@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -13,6 +14,9 @@ type Pagination struct {
 
 type Search struct {
 	Query string
+	// Tag limits the results to posts with this hashtag, as normalized by
+	// hashtag.Normalize. It is not set by NewSearch.
+	Tag string
 }
 
 func NewPagination(c *gin.Context) Pagination {
@@ -47,3 +51,46 @@ func NewSearch(c *gin.Context) Search {
 		Query: query,
 	}
 }
+
+// visibleTo returns an SQL condition that is true if the content of the user
+// in userColumn can be seen by the viewer in viewerParam. The content of
+// public accounts can be seen by everyone, the content of private accounts
+// only by the account itself and its followers.
+func visibleTo(userColumn, viewerParam string) string {
+	return fmt.Sprintf(`(%[1]s = %[2]s
+		OR NOT EXISTS (SELECT 1 FROM users AS author WHERE author.id = %[1]s AND author.is_private)
+		OR EXISTS (SELECT 1 FROM follows AS viewer_follows WHERE viewer_follows.user_id = %[2]s AND viewer_follows.follow_id = %[1]s))`, userColumn, viewerParam)
+}
+
+// notBlocked returns an SQL condition that is true if neither the user in
+// userColumn nor the viewer in viewerParam blocked the other.
+func notBlocked(userColumn, viewerParam string) string {
+	return fmt.Sprintf(`NOT EXISTS (SELECT 1 FROM blocks
+		WHERE (blocks.user_id = %[2]s AND blocks.blocked_id = %[1]s)
+		OR (blocks.user_id = %[1]s AND blocks.blocked_id = %[2]s))`, userColumn, viewerParam)
+}
+
+// notMuted returns an SQL condition that is false if the viewer in viewerParam
+// muted the user in userColumn, or muted a keyword that the text in
+// contentColumn contains as a whole word, so muting "cat" doesn't hide
+// "category". Expired mutes are ignored and the viewer's own content is never
+// hidden by keywords.
+func notMuted(userColumn, contentColumn, viewerParam string) string {
+	return fmt.Sprintf(`NOT EXISTS (SELECT 1 FROM mutes
+		WHERE mutes.user_id = %[3]s AND mutes.muted_id = %[1]s
+		AND (mutes.expires_at IS NULL OR mutes.expires_at > NOW()))
+		AND (%[1]s = %[3]s OR NOT EXISTS (SELECT 1 FROM muted_keywords
+		WHERE muted_keywords.user_id = %[3]s AND %[2]s ~* ('(^|\W)' || `+escapedKeyword+` || '(\W|$)')
+		AND (muted_keywords.expires_at IS NULL OR muted_keywords.expires_at > NOW())))`, userColumn, contentColumn, viewerParam)
+}
+
+// escapedKeyword escapes the regular expression characters of a muted
+// keyword so it is matched literally.
+const escapedKeyword = `regexp_replace(muted_keywords.keyword, '([.^$*+?()[\]{}|\\])', '\\\1', 'g')`
+
+// isActive returns an SQL condition that is false if the user in userColumn
+// deactivated their account.
+func isActive(userColumn string) string {
+	return fmt.Sprintf(`NOT EXISTS (SELECT 1 FROM users AS deactivated_users
+		WHERE deactivated_users.id = %s AND deactivated_users.deactivated_at IS NOT NULL)`, userColumn)
+}
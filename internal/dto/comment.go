@@ -6,21 +6,23 @@ import (
 )
 
 type CreateCommentDTO struct {
-	PostID  uuid.UUID `json:"post_id" binding:"required,uuid"`
-	Content string    `json:"content" binding:"required,lte=200"`
-	Image   string    `json:"image"`
+	PostID  uuid.UUID  `json:"post_id" binding:"required,uuid"`
+	Content string     `json:"content" binding:"required,lte=200"`
+	MediaID *uuid.UUID `json:"media_id"`
 }
 
 type UpdateCommentDTO struct {
 	Content string `json:"content" binding:"required,alphanum,lte=200"`
-	Image   string `json:"image"`
 }
 
 type CommentResponse struct {
 	ID          uuid.UUID  `json:"id"`
 	Content     string     `json:"content"`
+	Image       *string    `json:"image"`
+	Thumbnail   *string    `json:"thumbnail"`
 	CreatedAt   string     `json:"created_at"`
 	UpdatedAt   string     `json:"updated_at"`
+	Edited      bool       `json:"edited"`
 	User        model.User `json:"user"`
 	LikeCount   int        `json:"total_likes"`
 	ReplyCount  int        `json:"total_reply"`
@@ -31,8 +33,11 @@ type CommentResponse struct {
 type CommentDetailResponse struct {
 	ID          uuid.UUID       `json:"id"`
 	Content     string          `json:"content"`
+	Image       *string         `json:"image"`
+	Thumbnail   *string         `json:"thumbnail"`
 	CreatedAt   string          `json:"created_at"`
 	UpdatedAt   string          `json:"updated_at"`
+	Edited      bool            `json:"edited"`
 	User        model.User      `json:"user"`
 	Replies     []ReplyResponse `json:"replies"`
 	LikeCount   int             `json:"total_likes"`
@@ -47,8 +52,11 @@ func NewCommentResponse(comments []model.Comment) []CommentResponse {
 		commentResponse := CommentResponse{
 			ID:          comment.ID,
 			Content:     comment.Content,
+			Image:       comment.Image,
+			Thumbnail:   comment.Thumbnail,
 			CreatedAt:   comment.CreatedAt,
 			UpdatedAt:   comment.UpdatedAt,
+			Edited:      comment.Edited,
 			User:        comment.User,
 			LikeCount:   comment.LikeCount,
 			ReplyCount:  comment.ReplyCount,
@@ -67,9 +75,12 @@ func NewCommentDetailResponse(comments []model.Comment) []CommentDetailResponse
 		commentDetailResponse := CommentDetailResponse{
 			ID:          comment.ID,
 			Content:     comment.Content,
+			Image:       comment.Image,
+			Thumbnail:   comment.Thumbnail,
 			User:        comment.User,
 			CreatedAt:   comment.CreatedAt,
 			UpdatedAt:   comment.UpdatedAt,
+			Edited:      comment.Edited,
 			Replies:     NewReplyResponse(comment.Replies),
 			LikeCount:   comment.LikeCount,
 			ReplyCount:  comment.ReplyCount,
@@ -6,15 +6,23 @@ import (
 )
 
 type FeedResponse struct {
-	ID           uuid.UUID  `json:"id"`
-	Content      string     `json:"content"`
-	CreatedAt    string     `json:"created_at"`
-	UpdatedAt    string     `json:"updated_at"`
-	User         model.User `json:"user"`
-	LikeCount    int        `json:"total_likes"`
-	CommentCount int        `json:"total_comment"`
-	IsLiked      bool       `json:"is_liked"`
-	IsFollowing  bool       `json:"is_following"`
+	ID           uuid.UUID           `json:"id"`
+	Content      string              `json:"content"`
+	Image        *string             `json:"image"`
+	Thumbnail    *string             `json:"thumbnail"`
+	CreatedAt    string              `json:"created_at"`
+	UpdatedAt    string              `json:"updated_at"`
+	Edited       bool                `json:"edited"`
+	User         model.User          `json:"user"`
+	LikeCount    int                 `json:"total_likes"`
+	CommentCount int                 `json:"total_comment"`
+	IsLiked      bool                `json:"is_liked"`
+	IsFollowing  bool                `json:"is_following"`
+	RepostCount  int                 `json:"total_reposts"`
+	IsReposted   bool                `json:"is_reposted"`
+	Quote        *QuotedPostResponse `json:"quote"`
+	RepostedBy   *model.User         `json:"reposted_by"`
+	RepostedAt   *string             `json:"reposted_at"`
 }
 
 func NewFeedResponse(posts []model.Post) []FeedResponse {
@@ -23,13 +31,21 @@ func NewFeedResponse(posts []model.Post) []FeedResponse {
 		feedResponse := FeedResponse{
 			ID:           post.ID,
 			Content:      post.Content,
+			Image:        post.Image,
+			Thumbnail:    post.Thumbnail,
 			CreatedAt:    post.CreatedAt,
 			UpdatedAt:    post.UpdatedAt,
+			Edited:       post.Edited,
 			User:         post.User,
 			LikeCount:    post.LikeCount,
 			CommentCount: post.CommentCount,
 			IsLiked:      post.IsLiked,
 			IsFollowing:  post.IsFollowing,
+			RepostCount:  post.RepostCount,
+			IsReposted:   post.IsReposted,
+			Quote:        NewQuotedPostResponse(post.Quote),
+			RepostedBy:   post.RepostedBy,
+			RepostedAt:   post.RepostedAt,
 		}
 		result = append(result, feedResponse)
 	}
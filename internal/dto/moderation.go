@@ -0,0 +1,5 @@
+package dto
+
+type UpdateUserRoleDTO struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "github.com/fatihesergg/go_social/internal/model"
+
+type MuteUserDTO struct {
+	ExpiresInDays *int `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type MuteKeywordDTO struct {
+	Keyword       string `json:"keyword" binding:"required,lte=100"`
+	ExpiresInDays *int   `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type MuteListResponse struct {
+	Users    []model.Mute         `json:"users"`
+	Keywords []model.MutedKeyword `json:"keywords"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+import "github.com/fatihesergg/go_social/internal/model"
+
+type CreatePersonalAccessTokenDTO struct {
+	Name          string   `json:"name" binding:"required,lte=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// PersonalAccessTokenResponse includes the token itself, which is only shown
+// when it is created.
+type PersonalAccessTokenResponse struct {
+	Token string `json:"token"`
+	model.PersonalAccessToken
+}
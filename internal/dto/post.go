@@ -1,43 +1,86 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/fatihesergg/go_social/internal/model"
 	"github.com/google/uuid"
 )
 
 type CreatePostDTO struct {
-	Content string `json:"content" binding:"required,lte=500"`
-	Image   string `json:"image"`
+	Content string     `json:"content" binding:"required,lte=500"`
+	MediaID *uuid.UUID `json:"media_id"`
+	QuoteID *uuid.UUID `json:"quote_id"`
+	Draft   bool       `json:"draft"`
+}
+
+type SchedulePostDTO struct {
+	PublishAt time.Time `json:"publish_at" binding:"required"`
 }
 
 type UpdatePostDTO struct {
 	Content string `json:"content" binding:"required,alphanum,lte=500"`
-	Image   string `json:"image"`
 }
 
 type AllPostResponse struct {
-	ID           uuid.UUID  `json:"id"`
-	Content      string     `json:"content"`
-	CreatedAt    string     `json:"created_at"`
-	UpdatedAt    string     `json:"updated_at"`
-	User         model.User `json:"user"`
-	LikeCount    int        `json:"total_likes"`
-	CommentCount int        `json:"total_comment"`
-	IsLiked      bool       `json:"is_liked"`
-	IsFollowing  bool       `json:"is_following"`
+	ID           uuid.UUID           `json:"id"`
+	Content      string              `json:"content"`
+	Image        *string             `json:"image"`
+	Thumbnail    *string             `json:"thumbnail"`
+	CreatedAt    string              `json:"created_at"`
+	UpdatedAt    string              `json:"updated_at"`
+	Edited       bool                `json:"edited"`
+	User         model.User          `json:"user"`
+	LikeCount    int                 `json:"total_likes"`
+	CommentCount int                 `json:"total_comment"`
+	IsLiked      bool                `json:"is_liked"`
+	IsFollowing  bool                `json:"is_following"`
+	RepostCount  int                 `json:"total_reposts"`
+	IsReposted   bool                `json:"is_reposted"`
+	Quote        *QuotedPostResponse `json:"quote"`
 }
 
 type PostDetailResponse struct {
-	ID           uuid.UUID         `json:"id"`
-	Content      string            `json:"content"`
-	CreatedAt    string            `json:"created_at"`
-	UpdatedAt    string            `json:"updated_at"`
-	User         model.User        `json:"user"`
-	Comments     []CommentResponse `json:"comments"`
-	LikeCount    int               `json:"total_likes"`
-	CommentCount int               `json:"total_comment"`
-	IsLiked      bool              `json:"is_liked"`
-	IsFollowing  bool              `json:"is_following"`
+	ID           uuid.UUID           `json:"id"`
+	Content      string              `json:"content"`
+	Image        *string             `json:"image"`
+	Thumbnail    *string             `json:"thumbnail"`
+	CreatedAt    string              `json:"created_at"`
+	UpdatedAt    string              `json:"updated_at"`
+	Edited       bool                `json:"edited"`
+	User         model.User          `json:"user"`
+	Comments     []CommentResponse   `json:"comments"`
+	LikeCount    int                 `json:"total_likes"`
+	CommentCount int                 `json:"total_comment"`
+	IsLiked      bool                `json:"is_liked"`
+	IsFollowing  bool                `json:"is_following"`
+	RepostCount  int                 `json:"total_reposts"`
+	IsReposted   bool                `json:"is_reposted"`
+	Quote        *QuotedPostResponse `json:"quote"`
+}
+
+// QuotedPostResponse is the post embedded in a quote post.
+type QuotedPostResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Content   string     `json:"content"`
+	Image     *string    `json:"image"`
+	Thumbnail *string    `json:"thumbnail"`
+	CreatedAt string     `json:"created_at"`
+	User      model.User `json:"user"`
+}
+
+func NewQuotedPostResponse(post *model.Post) *QuotedPostResponse {
+	if post == nil {
+		return nil
+	}
+	return &QuotedPostResponse{
+		ID:        post.ID,
+		Content:   post.Content,
+		Image:     post.Image,
+		Thumbnail: post.Thumbnail,
+		CreatedAt: post.CreatedAt,
+		User:      post.User,
+	}
 }
 
 func NewAllPostResponse(posts []model.Post) []AllPostResponse {
@@ -46,13 +89,19 @@ func NewAllPostResponse(posts []model.Post) []AllPostResponse {
 		result = append(result, AllPostResponse{
 			ID:           post.ID,
 			Content:      post.Content,
+			Image:        post.Image,
+			Thumbnail:    post.Thumbnail,
 			CreatedAt:    post.CreatedAt,
 			UpdatedAt:    post.UpdatedAt,
+			Edited:       post.Edited,
 			User:         post.User,
 			LikeCount:    post.LikeCount,
 			CommentCount: post.CommentCount,
 			IsLiked:      post.IsLiked,
 			IsFollowing:  post.IsFollowing,
+			RepostCount:  post.RepostCount,
+			IsReposted:   post.IsReposted,
+			Quote:        NewQuotedPostResponse(post.Quote),
 		})
 	}
 	return result
@@ -62,14 +111,52 @@ func NewPostDetailResponse(post *model.Post) PostDetailResponse {
 	result := PostDetailResponse{
 		ID:           post.ID,
 		Content:      post.Content,
+		Image:        post.Image,
+		Thumbnail:    post.Thumbnail,
 		CreatedAt:    post.CreatedAt,
 		UpdatedAt:    post.UpdatedAt,
+		Edited:       post.Edited,
 		User:         post.User,
 		LikeCount:    post.LikeCount,
 		CommentCount: post.CommentCount,
 		IsLiked:      post.IsLiked,
 		IsFollowing:  post.IsFollowing,
+		RepostCount:  post.RepostCount,
+		IsReposted:   post.IsReposted,
+		Quote:        NewQuotedPostResponse(post.Quote),
 		Comments:     NewCommentResponse(post.Comments),
 	}
 	return result
 }
+
+type DraftResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Content   string     `json:"content"`
+	Image     *string    `json:"image"`
+	Thumbnail *string    `json:"thumbnail"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	CreatedAt string     `json:"created_at"`
+	UpdatedAt string     `json:"updated_at"`
+}
+
+func NewDraftResponse(post *model.Post) DraftResponse {
+	return DraftResponse{
+		ID:        post.ID,
+		Content:   post.Content,
+		Image:     post.Image,
+		Thumbnail: post.Thumbnail,
+		Status:    post.Status,
+		PublishAt: post.PublishAt,
+		CreatedAt: post.CreatedAt,
+		UpdatedAt: post.UpdatedAt,
+	}
+}
+
+func NewDraftListResponse(posts []model.Post) []DraftResponse {
+	result := []DraftResponse{}
+	for _, post := range posts {
+		result = append(result, NewDraftResponse(&post))
+	}
+	return result
+}
@@ -6,26 +6,32 @@ import (
 )
 
 type CreateReply struct {
-	Message string `json:"message",binding:"required,alphanum,lte=100"`
+	Message string `json:"message" binding:"required,lte=100"`
 }
 
 type UpdateReply struct {
-	Message string `json:"message",binding:"required,alphanum,lte=100"`
+	Message string `json:"message" binding:"required,lte=100"`
 }
 
 type ReplyResponse struct {
-	ID      uuid.UUID  `json:"id"`
-	Message string     `json:"message"`
-	User    model.User `json:"user"`
+	ID        uuid.UUID  `json:"id"`
+	Message   string     `json:"message"`
+	CreatedAt string     `json:"created_at"`
+	UpdatedAt string     `json:"updated_at"`
+	Edited    bool       `json:"edited"`
+	User      model.User `json:"user"`
 }
 
 func NewReplyResponse(replies []model.Reply) []ReplyResponse {
 	result := []ReplyResponse{}
 	for _, reply := range replies {
 		replyResponse := ReplyResponse{
-			ID:      reply.ID,
-			Message: reply.Message,
-			User:    reply.User,
+			ID:        reply.ID,
+			Message:   reply.Message,
+			CreatedAt: reply.CreatedAt,
+			UpdatedAt: reply.UpdatedAt,
+			Edited:    reply.Edited,
+			User:      reply.User,
 		}
 		result = append(result, replyResponse)
 	}
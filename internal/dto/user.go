@@ -18,3 +18,60 @@ type ResetUserPasswordDTO struct {
 	OldPassword string `json:"old_password" binding:"required,lte=20"`
 	NewPassword string `json:"new_password" binding:"required,lte=20"`
 }
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email" binding:"required,email,lte=100"`
+}
+
+type ConfirmPasswordResetDTO struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,lte=20"`
+}
+
+type LoginTwoFactorDTO struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,lte=32"`
+}
+
+type TwoFactorCodeDTO struct {
+	Code string `json:"code" binding:"required,lte=32"`
+}
+
+type DisableTwoFactorDTO struct {
+	Password string `json:"password" binding:"required,lte=20"`
+	Code     string `json:"code" binding:"required,lte=32"`
+}
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// UpdateProfileDTO only changes the fields that are set. An empty string
+// clears a field.
+type UpdateProfileDTO struct {
+	DisplayName *string `json:"display_name" binding:"omitempty,lte=50"`
+	Bio         *string `json:"bio" binding:"omitempty,lte=160"`
+	Website     *string `json:"website" binding:"omitempty,lte=255"`
+	Location    *string `json:"location" binding:"omitempty,lte=100"`
+	IsPrivate   *bool   `json:"is_private"`
+}
+
+type ChangeUsernameDTO struct {
+	Username string `json:"username" binding:"required,alphanum,lte=50"`
+}
+
+type DeleteAccountDTO struct {
+	Password string `json:"password" binding:"required,lte=20"`
+	Code     string `json:"code" binding:"omitempty,lte=32"`
+}
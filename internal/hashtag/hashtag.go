@@ -0,0 +1,51 @@
+// Package hashtag finds the hashtags in the content of posts and comments.
+package hashtag
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength is the longest hashtag in characters, longer ones are ignored.
+const MaxLength = 100
+
+// A hashtag starts at the beginning of the content or after a character that
+// can't be part of one. "&" and "/" are excluded as well so HTML entities like
+// &#39; and URL fragments like /#top aren't taken for hashtags.
+var pattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/])#([\p{L}\p{N}_]+)`)
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// Parse returns the hashtags in content without the # and in lower case,
+// sorted and without duplicates.
+func Parse(content string) []string {
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		tag, ok := Normalize(match[1])
+		if !ok || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Normalize returns tag without a leading # and in lower case, the way it is
+// stored. It returns false if tag is not a valid hashtag: hashtags are made of
+// letters, digits and underscores and need at least one letter, so #1 or
+// #2024 are not hashtags.
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if !tagPattern.MatchString(tag) || utf8.RuneCountInString(tag) > MaxLength {
+		return "", false
+	}
+	if strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+		return "", false
+	}
+	return tag, true
+}
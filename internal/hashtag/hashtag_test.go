@@ -0,0 +1,68 @@
+package hashtag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		tags    []string
+	}{
+		{name: "none", content: "no tags here", tags: []string{}},
+		{name: "start of content", content: "#go is fun", tags: []string{"go"}},
+		{name: "after punctuation", content: "(#go), #rust!", tags: []string{"go", "rust"}},
+		{name: "underscore and digits", content: "#go_1_24", tags: []string{"go_1_24"}},
+		{name: "sorted", content: "#zig #go #c", tags: []string{"c", "go", "zig"}},
+		{name: "lower case", content: "#GoLang", tags: []string{"golang"}},
+		{name: "duplicates", content: "#go #Go #GO", tags: []string{"go"}},
+		{name: "unicode letters", content: "#Türkçe #日本語 #Ελληνικά", tags: []string{"türkçe", "ελληνικά", "日本語"}},
+		{name: "unicode digits", content: "#٣abc", tags: []string{"٣abc"}},
+		{name: "digits only", content: "#1 #2024 #42", tags: []string{}},
+		{name: "digits with a letter", content: "#2024a", tags: []string{"2024a"}},
+		{name: "html entity", content: "it&#39;s", tags: []string{}},
+		{name: "url fragment", content: "https://example.com/#top", tags: []string{}},
+		{name: "inside a word", content: "c#sharp", tags: []string{}},
+		{name: "hash alone", content: "# #", tags: []string{}},
+		{name: "max length", content: "#" + strings.Repeat("a", MaxLength), tags: []string{strings.Repeat("a", MaxLength)}},
+		{name: "too long", content: "#" + strings.Repeat("a", MaxLength+1) + " #go", tags: []string{"go"}},
+		{name: "too long in characters", content: "#" + strings.Repeat("ş", MaxLength+1), tags: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.tags, Parse(tt.content))
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag   string
+		name  string
+		valid bool
+	}{
+		{tag: "#Go", name: "go", valid: true},
+		{tag: "golang", name: "golang", valid: true},
+		{tag: "#ÇAY", name: "çay", valid: true},
+		{tag: "#1", valid: false},
+		{tag: "2024", valid: false},
+		{tag: "#", valid: false},
+		{tag: "", valid: false},
+		{tag: "#go-lang", valid: false},
+		{tag: "#go lang", valid: false},
+		{tag: "##go", valid: false},
+		{tag: strings.Repeat("a", MaxLength), name: strings.Repeat("a", MaxLength), valid: true},
+		{tag: strings.Repeat("a", MaxLength+1), valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			name, ok := Normalize(tt.tag)
+			assert.Equal(t, tt.valid, ok)
+			assert.Equal(t, tt.name, name)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package imaging
+
+// gifFrameCount counts the frames of a GIF image by walking its blocks,
+// without decoding any of them. It stops at the trailer or at the first
+// block it can't read, where decoding would fail as well.
+func gifFrameCount(data []byte) int {
+	// The header is followed by the logical screen descriptor.
+	const headerSize = 6 + 7
+	if len(data) < headerSize {
+		return 0
+	}
+	i := headerSize
+	if flags := data[10]; flags&0x80 != 0 {
+		i += colorTableSize(flags)
+	}
+
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension
+			i += 2
+		case 0x2c: // image descriptor
+			if i+10 > len(data) {
+				return frames
+			}
+			frames++
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += colorTableSize(flags)
+			}
+			// Skip the LZW minimum code size.
+			i++
+		default: // trailer or garbage
+			return frames
+		}
+		i = skipSubBlocks(data, i)
+	}
+	return frames
+}
+
+// colorTableSize returns the size in bytes of the color table described by
+// the flags of a screen or image descriptor.
+func colorTableSize(flags byte) int {
+	return 3 << ((flags & 0x07) + 1)
+}
+
+// skipSubBlocks returns the index after the data sub-blocks starting at i,
+// which end with an empty block.
+func skipSubBlocks(data []byte, i int) int {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			break
+		}
+		i += size
+	}
+	return i
+}
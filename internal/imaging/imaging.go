@@ -0,0 +1,185 @@
+// Package imaging cleans up uploaded images before they are stored. Images
+// are decoded and encoded again, which drops EXIF and other metadata like the
+// GPS position a phone embeds in photos, and a thumbnail is made of each one.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var ErrInvalidImage = errors.New("imaging: invalid image")
+var ErrImageTooLarge = errors.New("imaging: image too large")
+
+// MaxDimension and MaxPixels keep small files that decode to huge images from
+// using up memory.
+const MaxDimension = 8192
+const MaxPixels = 40_000_000
+
+// ThumbnailSize is the longest side of a thumbnail.
+const ThumbnailSize = 320
+
+const jpegQuality = 90
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is a cleaned up image and its thumbnail.
+type Image struct {
+	ContentType        string
+	Extension          string
+	Width              int
+	Height             int
+	Data               []byte
+	Thumbnail          []byte
+	ThumbnailExtension string
+}
+
+// Process validates an uploaded JPEG, PNG or GIF image and encodes it again
+// without metadata. The type is sniffed from the content. JPEG images are
+// rotated according to their EXIF orientation before it is dropped. Animated
+// GIFs keep their frames, their thumbnail is made of the first one.
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrInvalidImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension || config.Width*config.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	result := &Image{ContentType: contentType, Extension: extension}
+	var buf bytes.Buffer
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		img = orient(img, jpegOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		err = png.Encode(&buf, img)
+	case "image/gif":
+		// Every frame is decoded at once, so the frames are counted before
+		// any of them is.
+		if gifFrameCount(data)*config.Width*config.Height > MaxPixels {
+			return nil, ErrImageTooLarge
+		}
+		var animation *gif.GIF
+		animation, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(animation.Image) == 0 {
+			return nil, ErrInvalidImage
+		}
+		img = firstFrame(animation, config)
+		err = gif.EncodeAll(&buf, animation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Data = buf.Bytes()
+	result.Width = img.Bounds().Dx()
+	result.Height = img.Bounds().Dy()
+
+	buf = bytes.Buffer{}
+	thumbnail := Thumbnail(img, ThumbnailSize)
+	if contentType == "image/jpeg" {
+		result.ThumbnailExtension = ".jpg"
+		err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		// PNG keeps the transparency of PNG and GIF images.
+		result.ThumbnailExtension = ".png"
+		err = png.Encode(&buf, thumbnail)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Thumbnail = buf.Bytes()
+
+	return result, nil
+}
+
+// firstFrame draws the first frame of a GIF on a canvas of the full image
+// size, since frames can be smaller than the image.
+func firstFrame(animation *gif.GIF, config image.Config) image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+	frame := animation.Image[0]
+	draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	return canvas
+}
+
+// Thumbnail scales img down to fit in a size x size square, keeping its
+// aspect ratio. Images that already fit are only copied.
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+	return resize(toRGBA(img), width, height)
+}
+
+// resize scales src down to width x height by averaging the source pixels
+// that fall in each target pixel. The pixels are premultiplied by alpha, so
+// averaging them doesn't bleed the color of transparent pixels.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					sum[0] += int(src.Pix[i])
+					sum[1] += int(src.Pix[i+1])
+					sum[2] += int(src.Pix[i+2])
+					sum[3] += int(src.Pix[i+3])
+					i += 4
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			o := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[o+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// toRGBA copies img to an RGBA image whose bounds start at 0, 0.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,240 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// halves returns an image whose left half is red and right half is blue.
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+// exifSegment returns an APP1 segment with an orientation tag and a GPS IFD
+// holding a latitude reference.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	// Orientation, SHORT.
+	tiff = binary.BigEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	// GPS IFD pointer, LONG, to right after this IFD.
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x8825)
+	tiff = binary.BigEndian.AppendUint16(tiff, 4)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint32(tiff, 38)
+	tiff = binary.BigEndian.AppendUint32(tiff, 0)
+	// GPS IFD with GPSLatitudeRef, ASCII "N".
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0001)
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	tiff = binary.BigEndian.AppendUint32(tiff, 2)
+	tiff = append(tiff, 'N', 0, 0, 0)
+	tiff = binary.BigEndian.AppendUint32(tiff, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xff, 0xe1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// hasSegment reports whether a JPEG has a segment with the given marker
+// before its image data.
+func hasSegment(data []byte, marker byte) bool {
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		if data[i+1] == marker {
+			return true
+		}
+		if data[i+1] == 0xda {
+			return false
+		}
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+	return false
+}
+
+func assertColor(t *testing.T, img image.Image, x, y int, want color.RGBA) {
+	t.Helper()
+	r, g, b, _ := img.At(x, y).RGBA()
+	// JPEG is lossy, the colors only need to be close.
+	assert.InDelta(t, want.R, r>>8, 40, "red at %d, %d", x, y)
+	assert.InDelta(t, want.G, g>>8, 40, "green at %d, %d", x, y)
+	assert.InDelta(t, want.B, b>>8, 40, "blue at %d, %d", x, y)
+}
+
+func TestProcess_JPEGStripsEXIFAndRotates(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, halves(64, 32), &jpeg.Options{Quality: 100}))
+	encoded := buf.Bytes()
+
+	// Orientation 6 means the image is displayed rotated 90° clockwise.
+	data := append([]byte{0xff, 0xd8}, exifSegment(6)...)
+	data = append(data, encoded[2:]...)
+	require.True(t, hasSegment(data, 0xe1))
+	require.Equal(t, 6, jpegOrientation(data))
+
+	result, err := Process(data)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", result.ContentType)
+	assert.Equal(t, ".jpg", result.Extension)
+	assert.False(t, hasSegment(result.Data, 0xe1))
+	assert.NotContains(t, string(result.Data), "Exif")
+	assert.Equal(t, 32, result.Width)
+	assert.Equal(t, 64, result.Height)
+
+	img, err := jpeg.Decode(bytes.NewReader(result.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32, 64), img.Bounds())
+	// The left half ends up on top.
+	assertColor(t, img, 16, 8, red)
+	assertColor(t, img, 16, 56, blue)
+}
+
+func TestProcess_PNGRoundTrip(t *testing.T) {
+	src := halves(20, 10)
+	src.Set(0, 0, color.RGBA{})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	result, err := Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", result.ContentType)
+	assert.Equal(t, ".png", result.Extension)
+	assert.Equal(t, ".png", result.ThumbnailExtension)
+	assert.Equal(t, 20, result.Width)
+	assert.Equal(t, 10, result.Height)
+
+	img, err := png.Decode(bytes.NewReader(result.Data))
+	require.NoError(t, err)
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			assert.Equal(t, color.RGBAModel.Convert(src.At(x, y)), color.RGBAModel.Convert(img.At(x, y)))
+		}
+	}
+}
+
+func TestProcess_GIFRoundTrip(t *testing.T) {
+	animation := &gif.GIF{}
+	for _, c := range []color.Color{red, blue, red} {
+		frame := image.NewPaletted(image.Rect(0, 0, 10, 10), palette.Plan9)
+		for i := range frame.Pix {
+			frame.Pix[i] = uint8(frame.Palette.Index(c))
+		}
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, animation))
+	assert.Equal(t, 3, gifFrameCount(buf.Bytes()))
+
+	result, err := Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "image/gif", result.ContentType)
+	assert.Equal(t, ".gif", result.Extension)
+	assert.Equal(t, ".png", result.ThumbnailExtension)
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(result.Data))
+	require.NoError(t, err)
+	assert.Len(t, decoded.Image, 3)
+	assert.Equal(t, []int{10, 10, 10}, decoded.Delay)
+
+	// The thumbnail is made of the first frame.
+	thumbnail, err := png.Decode(bytes.NewReader(result.Thumbnail))
+	require.NoError(t, err)
+	assertColor(t, thumbnail, 5, 5, red)
+}
+
+func TestProcess_ThumbnailFits(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		thumbW        int
+		thumbH        int
+	}{
+		{name: "landscape", width: 800, height: 400, thumbW: 320, thumbH: 160},
+		{name: "portrait", width: 300, height: 900, thumbW: 106, thumbH: 320},
+		{name: "small", width: 100, height: 50, thumbW: 100, thumbH: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, png.Encode(&buf, halves(tt.width, tt.height)))
+
+			result, err := Process(buf.Bytes())
+			require.NoError(t, err)
+
+			config, err := png.DecodeConfig(bytes.NewReader(result.Thumbnail))
+			require.NoError(t, err)
+			assert.Equal(t, tt.thumbW, config.Width)
+			assert.Equal(t, tt.thumbH, config.Height)
+			assert.LessOrEqual(t, config.Width, ThumbnailSize)
+			assert.LessOrEqual(t, config.Height, ThumbnailSize)
+		})
+	}
+}
+
+func TestProcess_Errors(t *testing.T) {
+	encodePNG := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, img))
+		return buf.Bytes()
+	}
+	valid := encodePNG(halves(20, 10))
+
+	// A GIF whose frames are tiny but whose screen is so large that all of
+	// its frames together go over MaxPixels.
+	frames := &gif.GIF{Config: image.Config{Width: 5000, Height: 5000, ColorModel: color.Palette(palette.Plan9)}}
+	for range 2 {
+		frames.Image = append(frames.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9))
+		frames.Delay = append(frames.Delay, 0)
+	}
+	var manyFrames bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&manyFrames, frames))
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "empty", data: nil, err: ErrInvalidImage},
+		{name: "text", data: []byte("hello, world"), err: ErrInvalidImage},
+		{name: "unsupported type", data: []byte("BM\x00\x00\x00\x00"), err: ErrInvalidImage},
+		{name: "truncated", data: valid[:len(valid)/2], err: ErrInvalidImage},
+		{name: "too wide", data: encodePNG(image.NewGray(image.Rect(0, 0, MaxDimension+1, 1))), err: ErrImageTooLarge},
+		{name: "too many GIF frames", data: manyFrames.Bytes(), err: ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import "fmt"
+
+func NewVerificationMessage(to, name, link string) Message {
+	return Message{
+		To:      to,
+		Subject: "Verify your Go Social email address",
+		Body: fmt.Sprintf(`Hi %s,
+
+Please confirm your email address by opening the link below:
+
+%s
+
+The link expires in 24 hours. If you did not sign up for Go Social you can ignore this email.
+`, name, link),
+	}
+}
+
+func NewPasswordResetMessage(to, name, token string) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your Go Social password",
+		Body: fmt.Sprintf(`Hi %s,
+
+Someone asked to reset the password of your Go Social account. Use the token below to choose a new password:
+
+%s
+
+The token expires in 1 hour and can only be used once. If you did not ask for a password reset you can ignore this email.
+`, name, token),
+	}
+}
@@ -2,13 +2,17 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
 	"github.com/fatihesergg/go_social/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(storage *database.Storage, keyRing *util.KeyRing) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" || len(token) < 7 {
@@ -17,15 +21,91 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 		token = token[7:] // Remove "Bearer " prefix
-		claims, err := util.ParseJWT(token)
+		if strings.HasPrefix(token, model.PersonalAccessTokenPrefix) {
+			authenticatePersonalAccessToken(c, storage, token)
+			return
+		}
+
+		claims, err := keyRing.ParseJWT(token)
 		if err != nil {
 
 			c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
 			c.Abort()
 			return
 		}
+
+		tokenID, err := uuid.Parse(claims.ID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
+			c.Abort()
+			return
+		}
+		revoked, err := storage.TokenStore.IsAccessTokenRevoked(tokenID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: util.InternalServerError})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
+			c.Abort()
+			return
+		}
+
 		userID, _ := uuid.Parse(claims.Subject)
+		sessionID, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
+			c.Abort()
+			return
+		}
+		session, err := storage.SessionStore.GetSessionByID(sessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: util.InternalServerError})
+			c.Abort()
+			return
+		}
+		if session == nil || session.RevokedAt != nil || session.UserID != userID {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
+			c.Abort()
+			return
+		}
+		if err := storage.SessionStore.TouchSession(sessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: util.InternalServerError})
+			c.Abort()
+			return
+		}
+
 		c.Set("userID", userID)
+		c.Set("sessionID", sessionID)
+		c.Set("tokenID", tokenID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
 		c.Next()
 	}
 }
+
+// authenticatePersonalAccessToken authenticates a request made with a personal
+// access token. The scopes of the token are checked by ScopeMiddleware.
+func authenticatePersonalAccessToken(c *gin.Context, storage *database.Storage, token string) {
+	accessToken, err := storage.PersonalAccessTokenStore.GetPersonalAccessTokenByHash(util.HashToken(token))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: util.InternalServerError})
+		c.Abort()
+		return
+	}
+	if accessToken == nil || accessToken.RevokedAt != nil || (accessToken.ExpiresAt != nil && accessToken.ExpiresAt.Before(time.Now())) {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
+		c.Abort()
+		return
+	}
+	if err := storage.PersonalAccessTokenStore.TouchPersonalAccessToken(accessToken.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: util.InternalServerError})
+		c.Abort()
+		return
+	}
+
+	c.Set("userID", accessToken.UserID)
+	c.Set("personalAccessTokenID", accessToken.ID)
+	c.Set("tokenScopes", accessToken.Scopes)
+	c.Next()
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequireRole only lets users with the given role or a higher one through. It
+// must run after AuthMiddleware.
+func RequireRole(storage *database.Storage, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uuid.UUID)
+
+		user, err := storage.UserStore.GetUserByID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: util.InternalServerError})
+			c.Abort()
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
+			c.Abort()
+			return
+		}
+		if !user.HasRole(role) {
+			c.JSON(http.StatusForbidden, util.ErrorResponse{Error: util.InvalidPermissionError})
+			c.Abort()
+			return
+		}
+
+		c.Set("userRole", user.Role)
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ScopeMiddleware checks that a personal access token has a scope for the
+// resource of a route group. Read requests need "<resource>:read" or
+// "<resource>:write", all other requests need "<resource>:write". Requests
+// authenticated with a session are not limited. It must run after
+// AuthMiddleware.
+func ScopeMiddleware(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("tokenScopes")
+		if !ok {
+			c.Next()
+			return
+		}
+		scopes := value.([]string)
+
+		allowed := slices.Contains(scopes, resource+":write")
+		if !allowed && (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) {
+			allowed = slices.Contains(scopes, resource+":read")
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, util.ErrorResponse{Error: util.InsufficientScopeError})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SessionOnlyMiddleware rejects requests authenticated with a personal access
+// token, for routes that manage the account itself like passwords, sessions
+// and tokens. It must run after AuthMiddleware.
+func SessionOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("sessionID"); !ok {
+			c.JSON(http.StatusForbidden, util.ErrorResponse{Error: util.SessionRequiredError})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// VerifiedEmailMiddleware only lets users with a verified email address
+// through. It must run after AuthMiddleware.
+func VerifiedEmailMiddleware(storage *database.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uuid.UUID)
+
+		user, err := storage.UserStore.GetUserByID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse{Error: util.InternalServerError})
+			c.Abort()
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse{Error: "unauthorized"})
+			c.Abort()
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, util.ErrorResponse{Error: util.EmailNotVerifiedError})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
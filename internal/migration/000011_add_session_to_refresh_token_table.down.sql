@@ -0,0 +1,3 @@
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_session_id_fkey;
+ALTER INDEX IF EXISTS idx_refresh_tokens_session_id RENAME TO idx_refresh_tokens_family_id;
+ALTER TABLE refresh_tokens RENAME COLUMN session_id TO family_id;
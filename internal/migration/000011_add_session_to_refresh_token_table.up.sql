@@ -0,0 +1,6 @@
+-- Refresh tokens issued before sessions existed can not be tied to a session.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens RENAME COLUMN family_id TO session_id;
+ALTER INDEX IF EXISTS idx_refresh_tokens_family_id RENAME TO idx_refresh_tokens_session_id;
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_session_id_fkey FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE;
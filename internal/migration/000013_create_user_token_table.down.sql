@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_tokens CASCADE;
\ No newline at end of file
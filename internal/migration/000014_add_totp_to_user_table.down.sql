@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_used_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
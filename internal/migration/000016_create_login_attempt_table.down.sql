@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS login_lockouts CASCADE;
+DROP TABLE IF EXISTS login_attempts CASCADE;
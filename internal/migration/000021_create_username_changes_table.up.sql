@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS username_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_username VARCHAR(50) NOT NULL,
+    new_username VARCHAR(50) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reserved_until TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_changes_old_username ON username_changes (old_username, reserved_until);
+CREATE INDEX IF NOT EXISTS idx_username_changes_user_id ON username_changes (user_id, changed_at);
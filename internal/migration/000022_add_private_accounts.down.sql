@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_follows_user_id_follow_id;
+DROP TABLE IF EXISTS follow_requests;
+ALTER TABLE users DROP COLUMN IF EXISTS is_private;
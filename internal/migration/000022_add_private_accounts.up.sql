@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    follow_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, follow_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_requests_follow_id ON follow_requests(follow_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_follows_user_id_follow_id ON follows(user_id, follow_id);
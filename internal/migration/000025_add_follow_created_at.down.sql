@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_follows_follow_id;
+
+ALTER TABLE follows DROP COLUMN IF EXISTS created_at;
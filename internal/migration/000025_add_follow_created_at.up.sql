@@ -0,0 +1,3 @@
+ALTER TABLE follows ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_follows_follow_id ON follows(follow_id);
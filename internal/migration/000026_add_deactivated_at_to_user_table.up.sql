@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_deactivated_at ON users(deactivated_at) WHERE deactivated_at IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS thumbnail;
+ALTER TABLE posts DROP COLUMN IF EXISTS thumbnail;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS media (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    blob_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    content_type VARCHAR(32) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size INT NOT NULL,
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    comment_id UUID REFERENCES comments(id) ON DELETE SET NULL,
+    attached_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_unattached ON media(created_at) WHERE post_id IS NULL AND comment_id IS NULL;
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS thumbnail TEXT;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS thumbnail TEXT;
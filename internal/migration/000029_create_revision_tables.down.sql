@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS reply_revisions;
+DROP TABLE IF EXISTS comment_revisions;
+DROP TABLE IF EXISTS post_revisions;
+
+ALTER TABLE replies DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE replies DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_posts_drafts;
+DROP INDEX IF EXISTS idx_posts_scheduled;
+
+DELETE FROM posts WHERE status <> 'published';
+ALTER TABLE posts DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS status;
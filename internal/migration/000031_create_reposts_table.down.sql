@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_quote_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS quote_id;
+
+DROP TABLE IF EXISTS reposts;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS reposts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reposts_post_id ON reposts(post_id);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS quote_id UUID REFERENCES posts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_quote_id ON posts(quote_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_comments_created_at;
+DROP INDEX IF EXISTS idx_posts_created_at;
+
+DROP TABLE IF EXISTS comment_tags;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
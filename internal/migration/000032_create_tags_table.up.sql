@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS comment_tags (
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag_id ON post_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_comment_tags_tag_id ON comment_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments(created_at);
+
+-- Tag the existing posts and comments with the same rules as the hashtag
+-- package, as far as POSIX classes allow.
+CREATE TEMPORARY TABLE existing_tags AS
+SELECT 'post' AS kind, posts.id AS content_id, lower(match[2]) AS name
+FROM posts, regexp_matches(posts.content, '(^|[^[:alnum:]_&/])#([[:alnum:]_]+)', 'g') AS match
+UNION
+SELECT 'comment', comments.id, lower(match[2])
+FROM comments, regexp_matches(comments.content, '(^|[^[:alnum:]_&/])#([[:alnum:]_]+)', 'g') AS match;
+
+DELETE FROM existing_tags WHERE name !~ '[[:alpha:]]' OR char_length(name) > 100;
+
+INSERT INTO tags (name) SELECT DISTINCT name FROM existing_tags ON CONFLICT (name) DO NOTHING;
+INSERT INTO post_tags (post_id, tag_id)
+SELECT existing_tags.content_id, tags.id FROM existing_tags JOIN tags ON tags.name = existing_tags.name
+WHERE existing_tags.kind = 'post' ON CONFLICT DO NOTHING;
+INSERT INTO comment_tags (comment_id, tag_id)
+SELECT existing_tags.content_id, tags.id FROM existing_tags JOIN tags ON tags.name = existing_tags.name
+WHERE existing_tags.kind = 'comment' ON CONFLICT DO NOTHING;
+
+DROP TABLE existing_tags;
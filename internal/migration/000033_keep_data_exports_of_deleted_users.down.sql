@@ -0,0 +1,4 @@
+DELETE FROM data_exports WHERE user_id IS NULL;
+ALTER TABLE data_exports DROP CONSTRAINT IF EXISTS data_exports_user_id_fkey;
+ALTER TABLE data_exports ADD CONSTRAINT data_exports_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE data_exports ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,4 @@
+-- Exports outlive their user so the worker can still delete their archives.
+ALTER TABLE data_exports ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE data_exports DROP CONSTRAINT IF EXISTS data_exports_user_id_fkey;
+ALTER TABLE data_exports ADD CONSTRAINT data_exports_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
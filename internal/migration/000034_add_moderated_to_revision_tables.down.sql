@@ -0,0 +1,3 @@
+ALTER TABLE reply_revisions DROP COLUMN IF EXISTS moderated;
+ALTER TABLE comment_revisions DROP COLUMN IF EXISTS moderated;
+ALTER TABLE post_revisions DROP COLUMN IF EXISTS moderated;
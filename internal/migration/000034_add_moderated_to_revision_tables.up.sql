@@ -0,0 +1,15 @@
+-- Revisions replaced by a moderator edit hold the text the moderator removed,
+-- which is kept in moderation_actions instead of being listed.
+ALTER TABLE post_revisions ADD COLUMN IF NOT EXISTS moderated BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE comment_revisions ADD COLUMN IF NOT EXISTS moderated BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE reply_revisions ADD COLUMN IF NOT EXISTS moderated BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE post_revisions SET moderated = true FROM moderation_actions
+WHERE moderation_actions.action = 'update' AND moderation_actions.target_type = 'post'
+AND moderation_actions.target_id = post_revisions.post_id AND moderation_actions.previous_content = post_revisions.content;
+UPDATE comment_revisions SET moderated = true FROM moderation_actions
+WHERE moderation_actions.action = 'update' AND moderation_actions.target_type = 'comment'
+AND moderation_actions.target_id = comment_revisions.comment_id AND moderation_actions.previous_content = comment_revisions.content;
+UPDATE reply_revisions SET moderated = true FROM moderation_actions
+WHERE moderation_actions.action = 'update' AND moderation_actions.target_type = 'reply'
+AND moderation_actions.target_id = reply_revisions.reply_id AND moderation_actions.previous_content = reply_revisions.content;
@@ -0,0 +1,2 @@
+-- The original case of emails isn't kept, there is nothing to undo.
+SELECT 1;
@@ -0,0 +1,6 @@
+-- Emails are stored in lower case so logins and lockouts key them the same
+-- way. Accounts whose email only differs in case from another one are left
+-- as they are, since they can't share an address.
+UPDATE users SET email = lower(trim(email))
+WHERE email <> lower(trim(email))
+AND NOT EXISTS (SELECT 1 FROM users AS other WHERE other.id <> users.id AND lower(trim(other.email)) = lower(trim(users.email)));
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failed_at ON login_attempts(scope, last_failed_at);
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Block means UserID blocked BlockedID. Blocks work in both directions, the
+// two users can't follow or interact with each other or see each other's
+// content.
+type Block struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `json:"user"`
+}
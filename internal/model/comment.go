@@ -5,16 +5,20 @@ import (
 )
 
 type Comment struct {
-	ID          uuid.UUID `json:"id"`
-	PostID      uuid.UUID `json:"-"`
-	UserID      uuid.UUID `json:"-"`
-	Content     string    `json:"content"`
-	CreatedAt   string    `json:"created_at"`
-	UpdatedAt   string    `json:"updated_at"`
-	User        User      `json:"user"`
-	Replies     []Reply   `json:"replies"`
-	LikeCount   int       `json:"total_likes"`
-	ReplyCount  int       `json:"total_reply"`
-	IsLiked     bool      `json:"is_liked"`
-	IsFollowing bool      `json:"is_followed"`
+	ID          uuid.UUID  `json:"id"`
+	PostID      uuid.UUID  `json:"-"`
+	UserID      uuid.UUID  `json:"-"`
+	Content     string     `json:"content"`
+	Image       *string    `json:"image"`
+	Thumbnail   *string    `json:"thumbnail"`
+	MediaID     *uuid.UUID `json:"-"`
+	CreatedAt   string     `json:"created_at"`
+	UpdatedAt   string     `json:"updated_at"`
+	Edited      bool       `json:"edited"`
+	User        User       `json:"user"`
+	Replies     []Reply    `json:"replies"`
+	LikeCount   int        `json:"total_likes"`
+	ReplyCount  int        `json:"total_reply"`
+	IsLiked     bool       `json:"is_liked"`
+	IsFollowing bool       `json:"is_followed"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DataExportStatusPending   = "pending"
+	DataExportStatusRunning   = "running"
+	DataExportStatusCompleted = "completed"
+	DataExportStatusFailed    = "failed"
+	DataExportStatusExpired   = "expired"
+)
+
+// DataExport is an archive of everything a user stored. It is built in the
+// background and can be downloaded until ExpiresAt.
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Status      string     `json:"status"`
+	BlobKey     *string    `json:"-"`
+	StartedAt   *time.Time `json:"-"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
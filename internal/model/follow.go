@@ -1,9 +1,29 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// Follow means UserID follows FollowID. When follows are listed, User is the
+// other side of the follow and IsFollowing tells whether the viewer follows
+// them.
 type Follow struct {
-	ID       uuid.UUID `json:"-"`
-	UserID   uuid.UUID `json:"user_id"`
-	FollowID uuid.UUID `json:"follow_id"`
+	ID          uuid.UUID `json:"-"`
+	UserID      uuid.UUID `json:"user_id"`
+	FollowID    uuid.UUID `json:"follow_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	User        User      `json:"user"`
+	IsFollowing bool      `json:"is_following"`
+}
+
+// FollowRequest is a pending follow of a private account. UserID asked to
+// follow FollowID.
+type FollowRequest struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	FollowID  uuid.UUID `json:"follow_id"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `json:"user"`
 }
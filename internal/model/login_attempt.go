@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LoginAttemptScopeAccount = "account"
+	LoginAttemptScopeIP      = "ip"
+)
+
+// LoginAttempt counts the recent failed logins of an account or an IP address.
+type LoginAttempt struct {
+	Scope        string     `json:"scope"`
+	Subject      string     `json:"subject"`
+	FailedCount  int        `json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+// LoginLockout is the audit record of an account or IP address being locked
+// out after too many failed logins.
+type LoginLockout struct {
+	ID          uuid.UUID  `json:"id"`
+	Scope       string     `json:"scope"`
+	Subject     string     `json:"subject"`
+	UserID      *uuid.UUID `json:"user_id"`
+	IPAddress   string     `json:"ip_address"`
+	FailedCount int        `json:"failed_count"`
+	LockedUntil time.Time  `json:"locked_until"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Media is an image uploaded to be attached to a post or a comment. Uploads
+// that are never attached, or whose post or comment was deleted, are removed
+// by worker.MediaCleaner.
+type Media struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       *uuid.UUID `json:"-"`
+	BlobKey      string     `json:"-"`
+	ThumbnailKey string     `json:"-"`
+	ContentType  string     `json:"content_type"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	Size         int        `json:"size"`
+	PostID       *uuid.UUID `json:"-"`
+	CommentID    *uuid.UUID `json:"-"`
+	AttachedAt   *time.Time `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	URL          string     `json:"url"`
+	ThumbnailURL string     `json:"thumbnail_url"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ModerationActionUpdate = "update"
+	ModerationActionDelete = "delete"
+)
+
+const (
+	ModerationTargetPost    = "post"
+	ModerationTargetComment = "comment"
+	ModerationTargetReply   = "reply"
+)
+
+// ModerationAction records a moderator changing or removing content of
+// another user.
+type ModerationAction struct {
+	ID              uuid.UUID  `json:"id"`
+	ModeratorID     *uuid.UUID `json:"moderator_id"`
+	Action          string     `json:"action"`
+	TargetType      string     `json:"target_type"`
+	TargetID        uuid.UUID  `json:"target_id"`
+	TargetUserID    *uuid.UUID `json:"target_user_id"`
+	PreviousContent string     `json:"previous_content"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
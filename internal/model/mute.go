@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mute hides the posts of MutedID from the feed and post listings of UserID
+// until ExpiresAt, or forever if it is nil. Unlike a block, the muted user
+// doesn't know about it and nothing else changes.
+type Mute struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	MutedID   uuid.UUID  `json:"muted_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      User       `json:"user"`
+}
+
+// MutedKeyword hides posts containing Keyword, ignoring case.
+type MutedKeyword struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Keyword   string     `json:"keyword"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
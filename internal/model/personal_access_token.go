@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PersonalAccessTokenPrefix makes personal access tokens easy to tell apart
+// from JWTs and easy to find by secret scanners.
+const PersonalAccessTokenPrefix = "gsp_"
+
+// Scopes a personal access token can be granted. A write scope includes the
+// read scope of the same resource.
+const (
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+	ScopeFollowsRead   = "follows:read"
+	ScopeFollowsWrite  = "follows:write"
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsRead  = "comments:read"
+	ScopeCommentsWrite = "comments:write"
+	ScopeFeedRead      = "feed:read"
+	ScopeMediaWrite    = "media:write"
+)
+
+var PersonalAccessTokenScopes = []string{
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeFollowsRead,
+	ScopeFollowsWrite,
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopeCommentsRead,
+	ScopeCommentsWrite,
+	ScopeFeedRead,
+	ScopeMediaWrite,
+}
+
+// PersonalAccessToken lets scripts and bots use the API on behalf of a user
+// without the user's password.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -1,19 +1,46 @@
 package model
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// A post is a draft until it is published or scheduled. Scheduled posts are
+// published at PublishAt by worker.PostScheduler. Only published posts are
+// shown to anyone but their author.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
 type Post struct {
-	ID           uuid.UUID `json:"id"`
-	Content      string    `json:"content"`
-	UserID       uuid.UUID `json:"-"`
-	CreatedAt    string    `json:"created_at"`
-	UpdatedAt    string    `json:"updated_at"`
-	User         User      `json:"user"`
-	LikeCount    int       `json:"total_likes"`
-	CommentCount int       `json:"total_comment"`
-	IsLiked      bool      `json:"is_liked"`
-	IsFollowing  bool      `json:"is_followed"`
-	Comments     []Comment `json:"comments"`
+	ID           uuid.UUID  `json:"id"`
+	Content      string     `json:"content"`
+	Image        *string    `json:"image"`
+	Thumbnail    *string    `json:"thumbnail"`
+	MediaID      *uuid.UUID `json:"-"`
+	QuoteID      *uuid.UUID `json:"-"`
+	Quote        *Post      `json:"quote"`
+	UserID       uuid.UUID  `json:"-"`
+	Status       string     `json:"-"`
+	PublishAt    *time.Time `json:"-"`
+	CreatedAt    string     `json:"created_at"`
+	UpdatedAt    string     `json:"updated_at"`
+	Edited       bool       `json:"edited"`
+	User         User       `json:"user"`
+	LikeCount    int        `json:"total_likes"`
+	CommentCount int        `json:"total_comment"`
+	IsLiked      bool       `json:"is_liked"`
+	IsFollowing  bool       `json:"is_followed"`
+	RepostCount  int        `json:"total_reposts"`
+	IsReposted   bool       `json:"is_reposted"`
+	RepostedBy   *User      `json:"reposted_by"`
+	RepostedAt   *string    `json:"reposted_at"`
+	Comments     []Comment  `json:"comments"`
+}
+
+func (p *Post) IsPublished() bool {
+	return p.Status == PostStatusPublished
 }
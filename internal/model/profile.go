@@ -0,0 +1,13 @@
+package model
+
+// Profile is a user together with their counts and their relationship to the
+// user viewing the profile.
+type Profile struct {
+	User
+	FollowerCount     int  `json:"follower_count"`
+	FollowingCount    int  `json:"following_count"`
+	PostCount         int  `json:"post_count"`
+	IsFollowing       bool `json:"is_following"`
+	FollowsYou        bool `json:"follows_you"`
+	IsFollowRequested bool `json:"is_follow_requested"`
+}
@@ -0,0 +1,10 @@
+package model
+
+// Relationship describes how the viewer and another user are connected.
+// Mutual is true if they follow each other.
+type Relationship struct {
+	Following       bool `json:"following"`
+	FollowedBy      bool `json:"followed_by"`
+	Mutual          bool `json:"mutual"`
+	FollowRequested bool `json:"follow_requested"`
+}
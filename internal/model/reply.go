@@ -7,5 +7,8 @@ type Reply struct {
 	CommentID uuid.UUID `json:"comment_id"`
 	UserID    uuid.UUID `json:"-"`
 	Message   string    `json:"message"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+	Edited    bool      `json:"edited"`
 	User      User      `json:"user"`
 }
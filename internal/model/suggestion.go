@@ -0,0 +1,11 @@
+package model
+
+// Suggestion is an account suggested to follow. MutualCount is the number of
+// accounts the viewer follows that follow it, FollowerCount its total number
+// of followers. Reason explains the suggestion to the viewer.
+type Suggestion struct {
+	User          User   `json:"user"`
+	MutualCount   int    `json:"mutual_count"`
+	FollowerCount int    `json:"follower_count"`
+	Reason        string `json:"reason"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// TrendingTag is a hashtag with the number of users that used it and the
+// number of posts and comments it was used in during a time window.
+type TrendingTag struct {
+	Name      string `json:"tag"`
+	UserCount int    `json:"total_users"`
+	UseCount  int    `json:"total_uses"`
+}
@@ -1,19 +1,45 @@
 package model
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Roles are ordered, every role has the permissions of the roles before it.
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	LastName  string    `json:"last_name"`
-	Username  string    `json:"username"`
-	Email     string    `json:"-"`
-	Password  string    `json:"-"`
-	Avatar    *string   `json:"avatar"`
-	CreatedAt time.Time `json:"-"`
-	UpdatedAt time.Time `json:"-"`
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	LastName        string     `json:"last_name"`
+	Username        string     `json:"username"`
+	Email           string     `json:"-"`
+	Password        string     `json:"-"`
+	Avatar          *string    `json:"avatar"`
+	DisplayName     string     `json:"display_name"`
+	Bio             string     `json:"bio"`
+	Website         string     `json:"website"`
+	Location        string     `json:"location"`
+	IsPrivate       bool       `json:"is_private"`
+	CreatedAt       time.Time  `json:"-"`
+	UpdatedAt       time.Time  `json:"-"`
+	EmailVerifiedAt *time.Time `json:"-"`
+	TOTPSecret      *string    `json:"-"`
+	TOTPEnabledAt   *time.Time `json:"-"`
+	Role            string     `json:"role,omitempty"`
+	DeactivatedAt   *time.Time `json:"-"`
+}
+
+// HasRole reports whether the user has the given role or a higher one.
+func (u *User) HasRole(role string) bool {
+	rank := slices.Index(Roles, role)
+	return rank >= 0 && slices.Index(Roles, u.Role) >= rank
 }
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsernameChange records a username change. Until ReservedUntil the old
+// username points to the account and can't be taken by anyone else.
+type UsernameChange struct {
+	ID            uuid.UUID `json:"id"`
+	UserID        uuid.UUID `json:"user_id"`
+	OldUsername   string    `json:"old_username"`
+	NewUsername   string    `json:"new_username"`
+	ChangedAt     time.Time `json:"changed_at"`
+	ReservedUntil time.Time `json:"reserved_until"`
+}
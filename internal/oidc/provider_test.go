@@ -0,0 +1,366 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testClientID = "test_client"
+
+type authorizationGrant struct {
+	codeChallenge string
+	nonce         string
+}
+
+// mockProvider is a minimal OpenID Connect provider serving discovery, a key
+// set and a token endpoint that checks PKCE.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu             sync.Mutex
+	issuer         string
+	keys           map[string]*rsa.PrivateKey
+	signingKeyID   string
+	grants         map[string]authorizationGrant
+	discoveryCalls int
+	jwksCalls      int
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	m := &mockProvider{
+		t:      t,
+		keys:   make(map[string]*rsa.PrivateKey),
+		grants: make(map[string]authorizationGrant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.handleDiscovery)
+	mux.HandleFunc("/jwks", m.handleJWKS)
+	mux.HandleFunc("/token", m.handleToken)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	m.issuer = m.server.URL
+	m.rotateKey("key1")
+	return m
+}
+
+// rotateKey adds a new signing key and signs new ID tokens with it.
+func (m *mockProvider) rotateKey(keyID string) *rsa.PrivateKey {
+	m.t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(m.t, err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[keyID] = key
+	m.signingKeyID = keyID
+	return key
+}
+
+// authorize stands in for the user signing in at the provider and returns
+// the authorization code the provider redirects back with.
+func (m *mockProvider) authorize(codeChallenge, nonce string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code := fmt.Sprintf("code_%d", len(m.grants)+1)
+	m.grants[code] = authorizationGrant{codeChallenge: codeChallenge, nonce: nonce}
+	return code
+}
+
+func (m *mockProvider) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   m.issuer,
+		"sub":   "provider_user",
+		"aud":   testClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+		"email": "user@example.com",
+	}
+}
+
+func (m *mockProvider) sign(claims jwt.MapClaims) string {
+	m.t.Helper()
+	m.mu.Lock()
+	keyID := m.signingKeyID
+	key := m.keys[keyID]
+	m.mu.Unlock()
+	return signIDToken(m.t, key, keyID, claims)
+}
+
+func signIDToken(t *testing.T, key *rsa.PrivateKey, keyID string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func (m *mockProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.discoveryCalls++
+	issuer := m.issuer
+	m.mu.Unlock()
+
+	json.NewEncoder(w).Encode(Metadata{
+		Issuer:                issuer,
+		AuthorizationEndpoint: m.server.URL + "/authorize",
+		TokenEndpoint:         m.server.URL + "/token",
+		JWKSURI:               m.server.URL + "/jwks",
+	})
+}
+
+func (m *mockProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jwksCalls++
+
+	set := jwkSet{}
+	for keyID, key := range m.keys {
+		set.Keys = append(set.Keys, jwk{
+			KeyType: "RSA",
+			KeyID:   keyID,
+			Use:     "sig",
+			N:       base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func (m *mockProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	grant, ok := m.grants[r.PostForm.Get("code")]
+	delete(m.grants, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("client_id") != testClientID {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+	if CodeChallenge(r.PostForm.Get("code_verifier")) != grant.codeChallenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken: "access_token",
+		TokenType:   "Bearer",
+		IDToken:     m.sign(m.claims(grant.nonce)),
+		ExpiresIn:   3600,
+	})
+}
+
+func newTestProvider(m *mockProvider) *Provider {
+	return NewProvider(Config{
+		Name:        "mock",
+		Issuer:      m.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:3000/api/v1/auth/mock/callback",
+	})
+}
+
+func TestProvider_Discovery(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+	ctx := context.Background()
+
+	metadata, err := provider.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, m.server.URL, metadata.Issuer)
+	assert.Equal(t, m.server.URL+"/token", metadata.TokenEndpoint)
+
+	// The discovery document is cached.
+	_, err = provider.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.discoveryCalls)
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "challenge")
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", parsed.Path)
+	query := parsed.Query()
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, testClientID, query.Get("client_id"))
+	assert.Equal(t, "state", query.Get("state"))
+	assert.Equal(t, "nonce", query.Get("nonce"))
+	assert.Equal(t, "challenge", query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	m.issuer = "https://attacker.example.com"
+	provider := newTestProvider(m)
+
+	_, err := provider.Metadata(context.Background())
+	assert.Error(t, err)
+}
+
+func TestProvider_CodeFlow(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+	ctx := context.Background()
+
+	verifier, err := GenerateCodeVerifier()
+	require.NoError(t, err)
+	code := m.authorize(CodeChallenge(verifier), "nonce")
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, "provider_user", claims.Subject)
+	assert.Equal(t, "user@example.com", claims.Email)
+
+	// Codes can only be redeemed once.
+	_, err = provider.Exchange(ctx, code, verifier)
+	assert.Error(t, err)
+}
+
+func TestProvider_CodeFlowWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+
+	verifier, err := GenerateCodeVerifier()
+	require.NoError(t, err)
+	otherVerifier, err := GenerateCodeVerifier()
+	require.NoError(t, err)
+	code := m.authorize(CodeChallenge(verifier), "nonce")
+
+	_, err = provider.Exchange(context.Background(), code, otherVerifier)
+	assert.Error(t, err)
+}
+
+func TestProvider_VerifyIDTokenRejects(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+	ctx := context.Background()
+
+	forgedKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{
+			name: "signature from another key",
+			token: func() string {
+				return signIDToken(t, forgedKey, "key1", m.claims("nonce"))
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := m.claims("nonce")
+				claims["iss"] = "https://attacker.example.com"
+				return m.sign(claims)
+			},
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := m.claims("nonce")
+				claims["aud"] = "other_client"
+				return m.sign(claims)
+			},
+		},
+		{
+			name: "wrong nonce",
+			token: func() string {
+				return m.sign(m.claims("other_nonce"))
+			},
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := m.claims("nonce")
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return m.sign(claims)
+			},
+		},
+		{
+			name: "no subject",
+			token: func() string {
+				claims := m.claims("nonce")
+				delete(claims, "sub")
+				return m.sign(claims)
+			},
+		},
+		{
+			name: "unsigned",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, m.claims("nonce"))
+				token.Header["kid"] = "key1"
+				signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				require.NoError(t, err)
+				return signed
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(ctx, tt.token(), "nonce")
+			assert.Error(t, err)
+		})
+	}
+
+	_, err = provider.VerifyIDToken(ctx, m.sign(m.claims("other_nonce")), "nonce")
+	assert.ErrorIs(t, err, ErrNonceMismatch)
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(m)
+	ctx := context.Background()
+
+	_, err := provider.VerifyIDToken(ctx, m.sign(m.claims("nonce")), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.jwksCalls)
+
+	// Known keys are served from the cache.
+	_, err = provider.VerifyIDToken(ctx, m.sign(m.claims("nonce")), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.jwksCalls)
+
+	// A token signed with a new key makes the provider fetch the key set
+	// again.
+	m.rotateKey("key2")
+	_, err = provider.VerifyIDToken(ctx, m.sign(m.claims("nonce")), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.jwksCalls)
+
+	// A key ID the provider doesn't publish is rejected.
+	unknownKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(ctx, signIDToken(t, unknownKey, "key3", m.claims("nonce")), "nonce")
+	assert.Error(t, err)
+}
@@ -12,7 +12,60 @@ var UnauthorizedError = "You are not authorized to perform this action"
 var BadRequestError = "Bad request"
 var NoPostsFoundError = "No posts found"
 var IDRequiredError = "ID is required"
-var NotFollowingError = "You are not allowed to view this user's posts"
 var NoCommentsFoundError = "No comments found"
 var InvalidIDFormatError = "Invalid ID format"
 var InvalidPermissionError = "You don't have enough permission to do this operation"
+var InvalidRefreshTokenError = "Invalid or expired refresh token"
+var SessionNotFoundError = "Session not found"
+var InvalidVerificationTokenError = "Invalid or expired verification token"
+var EmailNotVerifiedError = "Please verify your email address first"
+var InvalidPasswordResetTokenError = "Invalid or expired password reset token"
+var InvalidChallengeTokenError = "Invalid or expired two factor challenge"
+var InvalidTwoFactorCodeError = "Invalid two factor authentication code"
+var TwoFactorAlreadyEnabledError = "Two factor authentication is already enabled"
+var TwoFactorNotEnabledError = "Two factor authentication is not enabled"
+var TwoFactorNotEnrolledError = "Start two factor authentication enrollment first"
+var TooManyLoginAttemptsError = "Too many failed login attempts. Please try again later"
+var UnknownLoginProviderError = "Unknown login provider"
+var InvalidLoginStateError = "Invalid or expired login request. Please try again"
+var ExternalLoginFailedError = "Login with the external provider failed"
+var ExternalEmailRequiredError = "The external account has no email address"
+var ExternalAccountExistsError = "An account with this email already exists and can't be linked until both emails are verified"
+var InsufficientScopeError = "The access token doesn't have the scope required for this action"
+var SessionRequiredError = "This action can't be done with a personal access token"
+var InvalidScopeError = "Invalid scope"
+var PersonalAccessTokenNotFoundError = "Personal access token not found"
+var ChangeOwnRoleError = "You can't change your own role"
+var InvalidWebsiteError = "Website should be a valid http or https URL"
+var InvalidImageError = "File should be a JPEG, PNG or GIF image"
+var FileTooLargeError = "File is too large"
+var FileRequiredError = "File is required"
+var MediaNotFoundError = "Media not found"
+var UsernameTakenError = "Username already exists"
+var SameUsernameError = "New username is the same as the current one"
+var UsernameChangeTooSoonError = "Username was changed recently, try again later"
+var FollowYourselfError = "You can't follow yourself"
+var FollowRequestAlreadySentError = "Follow request already sent"
+var FollowRequestNotFoundError = "Follow request not found"
+var PrivateAccountError = "This account is private"
+var BlockYourselfError = "You can't block yourself"
+var BlockNotFoundError = "User is not blocked"
+var BlockedUserError = "You can't interact with this user"
+var MuteYourselfError = "You can't mute yourself"
+var MuteNotFoundError = "User is not muted"
+var MutedKeywordNotFoundError = "Muted keyword not found"
+var InvalidKeywordError = "Keyword can't be empty"
+var FollowPathNotFoundError = "No follow path found"
+var InvalidFollowPathDepthError = "max_depth must be between 1 and 4"
+var DataExportNotFoundError = "Data export not found"
+var InvalidDownloadTokenError = "Invalid or expired download link"
+var ImageDimensionsTooLargeError = "Image dimensions are too large"
+var MediaAlreadyAttachedError = "Media is already attached to a post or comment"
+var ReplyNotFoundError = "Reply not found"
+var PostAlreadyPublishedError = "Post is already published"
+var InvalidPublishTimeError = "Publish time must be in the future"
+var PostAlreadyRepostedError = "Post already reposted"
+var PostNotRepostedError = "Post not reposted yet"
+var PrivatePostShareError = "Posts of private accounts can't be reposted or quoted"
+var InvalidTagError = "Invalid hashtag"
+var InvalidTrendingWindowError = "hours must be between 1 and 168"
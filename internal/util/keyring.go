@@ -0,0 +1,282 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const tokenIssuer = "go_social"
+const userAudience = "go_social_user"
+const challengeAudience = "go_social_2fa"
+const dataExportAudience = "go_social_export"
+
+const AccessTokenTTL = time.Minute * 15
+const RefreshTokenTTL = time.Hour * 24 * 30
+const ChallengeTokenTTL = time.Minute * 5
+const DataExportTokenTTL = time.Hour
+
+// Claims are the claims of the access tokens issued by go_social.
+type Claims struct {
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// VerificationKey is a public key that tokens can be verified with. Keys
+// loaded from a private key can sign tokens as well.
+type VerificationKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PublicKey  crypto.PublicKey
+	PrivateKey crypto.Signer
+}
+
+// KeyRing signs tokens with a single active key and verifies tokens with any
+// of the keys it holds, so keys can be rotated without signing everyone out.
+type KeyRing struct {
+	signingKey *VerificationKey
+	keys       map[string]*VerificationKey
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeyRing loads every PEM file in dir as a key. The file name without the
+// extension is used as the key ID. Private keys (PKCS#8 or PKCS#1) can sign
+// and verify tokens, public keys (PKIX) only verify them, which is how retired
+// keys are kept around until the tokens they signed have expired.
+func LoadKeyRing(dir, signingKeyID string) (*KeyRing, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keyRing := &KeyRing{keys: make(map[string]*VerificationKey)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		keyID := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := parseKey(keyID, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		keyRing.keys[keyID] = key
+	}
+
+	signingKey, ok := keyRing.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingKeyID, dir)
+	}
+	if signingKey.PrivateKey == nil {
+		return nil, fmt.Errorf("signing key %q is not a private key", signingKeyID)
+	}
+	keyRing.signingKey = signingKey
+
+	return keyRing, nil
+}
+
+func parseKey(keyID string, data []byte) (*VerificationKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &VerificationKey{ID: keyID}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if rsaKey, ok := key.PublicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return key, nil
+}
+
+// Sign signs the given claims with the active signing key.
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.signingKey.Method, claims)
+	token.Header["kid"] = kr.signingKey.ID
+	return token.SignedString(kr.signingKey.PrivateKey)
+}
+
+// Parse verifies a token signed by any key of the key ring and fills claims.
+func (kr *KeyRing) Parse(token string, claims jwt.Claims, audience string) error {
+	jwtToken, err := jwt.ParseWithClaims(token, claims, func(jwtToken *jwt.Token) (interface{}, error) {
+		keyID, _ := jwtToken.Header["kid"].(string)
+		key, ok := kr.keys[keyID]
+		if !ok {
+			return nil, jwt.ErrTokenUnverifiable
+		}
+		if jwtToken.Method.Alg() != key.Method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.PublicKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if !jwtToken.Valid {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (kr *KeyRing) CreateJsonWebToken(userID, sessionID uuid.UUID) (string, error) {
+	claims := Claims{
+		SessionID: sessionID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    tokenIssuer,
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{userAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
+		},
+	}
+	return kr.Sign(claims)
+}
+
+func (kr *KeyRing) ParseJWT(token string) (*Claims, error) {
+	claims := &Claims{}
+	if err := kr.Parse(token, claims, userAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CreateChallengeToken creates the short lived token returned by the password
+// step of a two factor login. It can only be exchanged for real tokens
+// together with a second factor.
+func (kr *KeyRing) CreateChallengeToken(userID uuid.UUID) (string, error) {
+	claims := jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Issuer:    tokenIssuer,
+		Subject:   userID.String(),
+		Audience:  jwt.ClaimStrings{challengeAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ChallengeTokenTTL)),
+	}
+	return kr.Sign(claims)
+}
+
+func (kr *KeyRing) ParseChallengeToken(token string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	if err := kr.Parse(token, claims, challengeAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CreateDataExportToken creates the token of a download link for a data
+// export. Anyone with the link can download the export until it expires.
+func (kr *KeyRing) CreateDataExportToken(exportID uuid.UUID) (string, error) {
+	claims := jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Issuer:    tokenIssuer,
+		Subject:   exportID.String(),
+		Audience:  jwt.ClaimStrings{dataExportAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(DataExportTokenTTL)),
+	}
+	return kr.Sign(claims)
+}
+
+func (kr *KeyRing) ParseDataExportToken(token string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	if err := kr.Parse(token, claims, dataExportAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys of the key ring so other services can verify
+// tokens without sharing a secret.
+func (kr *KeyRing) JWKS() JWKSet {
+	keyIDs := make([]string, 0, len(kr.keys))
+	for keyID := range kr.keys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, keyID := range keyIDs {
+		key := kr.keys[keyID]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch k := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
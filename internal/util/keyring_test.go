@@ -0,0 +1,228 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKey(t *testing.T, dir, keyID string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyID+".pem"), data, 0600))
+}
+
+func writePublicKey(t *testing.T, dir, keyID string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyID+".pem"), data, 0600))
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "old", oldKey)
+
+	oldRing, err := LoadKeyRing(dir, "old")
+	require.NoError(t, err)
+
+	userID, sessionID := uuid.New(), uuid.New()
+	oldToken, err := oldRing.CreateJsonWebToken(userID, sessionID)
+	require.NoError(t, err)
+
+	// The old key is retired to its public half and a new key signs tokens.
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "new", newKey)
+	writePublicKey(t, dir, "old", oldKey.Public())
+
+	newRing, err := LoadKeyRing(dir, "new")
+	require.NoError(t, err)
+
+	claims, err := newRing.ParseJWT(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, userID.String(), claims.Subject)
+	assert.Equal(t, sessionID.String(), claims.SessionID)
+
+	newToken, err := newRing.CreateJsonWebToken(userID, sessionID)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "new", parsed.Header["kid"])
+	assert.Equal(t, jwt.SigningMethodRS256.Alg(), parsed.Method.Alg())
+
+	_, err = newRing.ParseJWT(newToken)
+	assert.NoError(t, err)
+
+	// The old ring doesn't know the new key.
+	_, err = oldRing.ParseJWT(newToken)
+	assert.Error(t, err)
+
+	// A retired public key can't be used to sign.
+	_, err = LoadKeyRing(dir, "old")
+	assert.Error(t, err)
+	_, err = LoadKeyRing(dir, "missing")
+	assert.Error(t, err)
+}
+
+func TestKeyRing_Parse(t *testing.T) {
+	dir := t.TempDir()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "main", key)
+	keyRing, err := LoadKeyRing(dir, "main")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		claims jwt.Claims
+	}{
+		{
+			name: "expired",
+			claims: jwt.RegisteredClaims{
+				Issuer:    tokenIssuer,
+				Audience:  jwt.ClaimStrings{userAudience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			},
+		},
+		{
+			name: "without expiry",
+			claims: jwt.RegisteredClaims{
+				Issuer:   tokenIssuer,
+				Audience: jwt.ClaimStrings{userAudience},
+			},
+		},
+		{
+			name: "wrong issuer",
+			claims: jwt.RegisteredClaims{
+				Issuer:    "someone_else",
+				Audience:  jwt.ClaimStrings{userAudience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := keyRing.Sign(tt.claims)
+			require.NoError(t, err)
+			_, err = keyRing.ParseJWT(token)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("unknown kid", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{userAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		})
+		token.Header["kid"] = "other"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		_, err = keyRing.ParseJWT(signed)
+		assert.Error(t, err)
+	})
+}
+
+func TestKeyRing_Audiences(t *testing.T) {
+	dir := t.TempDir()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "main", key)
+	keyRing, err := LoadKeyRing(dir, "main")
+	require.NoError(t, err)
+
+	accessToken, err := keyRing.CreateJsonWebToken(uuid.New(), uuid.New())
+	require.NoError(t, err)
+	challengeToken, err := keyRing.CreateChallengeToken(uuid.New())
+	require.NoError(t, err)
+	exportToken, err := keyRing.CreateDataExportToken(uuid.New())
+	require.NoError(t, err)
+
+	_, err = keyRing.ParseJWT(accessToken)
+	assert.NoError(t, err)
+	_, err = keyRing.ParseJWT(challengeToken)
+	assert.Error(t, err)
+	_, err = keyRing.ParseJWT(exportToken)
+	assert.Error(t, err)
+
+	_, err = keyRing.ParseChallengeToken(challengeToken)
+	assert.NoError(t, err)
+	_, err = keyRing.ParseChallengeToken(accessToken)
+	assert.Error(t, err)
+	_, err = keyRing.ParseChallengeToken(exportToken)
+	assert.Error(t, err)
+
+	_, err = keyRing.ParseDataExportToken(exportToken)
+	assert.NoError(t, err)
+	_, err = keyRing.ParseDataExportToken(accessToken)
+	assert.Error(t, err)
+	_, err = keyRing.ParseDataExportToken(challengeToken)
+	assert.Error(t, err)
+}
+
+func TestKeyRing_JWKS(t *testing.T) {
+	dir := t.TempDir()
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "b_ed", edKey)
+	writePublicKey(t, dir, "a_rsa", &rsaKey.PublicKey)
+
+	keyRing, err := LoadKeyRing(dir, "b_ed")
+	require.NoError(t, err)
+
+	set := keyRing.JWKS()
+	require.Len(t, set.Keys, 2)
+
+	rsaJWK := set.Keys[0]
+	assert.Equal(t, "a_rsa", rsaJWK.KeyID)
+	assert.Equal(t, "RSA", rsaJWK.KeyType)
+	assert.Equal(t, "RS256", rsaJWK.Algorithm)
+	assert.Equal(t, "sig", rsaJWK.Use)
+	n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	require.NoError(t, err)
+	assert.Equal(t, rsaKey.N, new(big.Int).SetBytes(n))
+	e, err := base64.RawURLEncoding.DecodeString(rsaJWK.E)
+	require.NoError(t, err)
+	assert.Equal(t, int64(rsaKey.E), new(big.Int).SetBytes(e).Int64())
+
+	edJWK := set.Keys[1]
+	assert.Equal(t, "b_ed", edJWK.KeyID)
+	assert.Equal(t, "OKP", edJWK.KeyType)
+	assert.Equal(t, "Ed25519", edJWK.Curve)
+	assert.Equal(t, "EdDSA", edJWK.Algorithm)
+	x, err := base64.RawURLEncoding.DecodeString(edJWK.X)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(edPublic), x)
+	assert.Empty(t, edJWK.N)
+}
+
+func TestLoadKeyRing_RejectsShortRSAKeys(t *testing.T) {
+	dir := t.TempDir()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "short", key)
+
+	_, err = LoadKeyRing(dir, "short")
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package util
+
+import "time"
+
+// LockoutPolicy decides how long logins are blocked after failed attempts.
+// The first FreeAttempts failures are not delayed, after that the delay doubles
+// with every failure up to MaxBackoff and once LockoutThreshold is reached the
+// login is locked for LockoutDuration. Failures older than Window are
+// forgotten.
+type LockoutPolicy struct {
+	FreeAttempts     int
+	MaxBackoff       time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+// AccountLockoutPolicy applies to the failed logins of a single email address.
+var AccountLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     3,
+	MaxBackoff:       time.Minute,
+	LockoutThreshold: 10,
+	LockoutDuration:  time.Minute * 15,
+	Window:           time.Hour,
+}
+
+// IPLockoutPolicy applies to the failed logins from a single IP address. It is
+// looser than the account policy since many users can share an address.
+var IPLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     20,
+	MaxBackoff:       time.Minute,
+	LockoutThreshold: 100,
+	LockoutDuration:  time.Minute * 15,
+	Window:           time.Hour,
+}
+
+// Backoff returns how long to block logins after failedCount failures and
+// whether this is a full lockout.
+func (p LockoutPolicy) Backoff(failedCount int) (time.Duration, bool) {
+	if failedCount >= p.LockoutThreshold {
+		return p.LockoutDuration, true
+	}
+	if failedCount <= p.FreeAttempts {
+		return 0, false
+	}
+
+	exponent := failedCount - p.FreeAttempts - 1
+	if exponent > 30 {
+		return p.MaxBackoff, false
+	}
+	backoff := time.Second << exponent
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff, false
+}
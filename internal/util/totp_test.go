@@ -0,0 +1,104 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret is the SHA1 seed of the RFC 6238 Appendix B test vectors,
+// "12345678901234567890" in base32.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes, go_social uses their last 6 digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, code)
+
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+			assert.True(t, ok)
+			assert.Equal(t, TOTPStep(time.Unix(tt.unix, 0)), step)
+		})
+	}
+}
+
+func TestTOTPCode_LowercaseSecret(t *testing.T) {
+	upper, err := TOTPCode(rfc6238Secret, 1)
+	require.NoError(t, err)
+	lower, err := TOTPCode(strings.ToLower(rfc6238Secret), 1)
+	require.NoError(t, err)
+	assert.Equal(t, upper, lower)
+
+	_, err = TOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidateTOTP_Window(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{name: "two steps behind", offset: -2, valid: false},
+		{name: "one step behind", offset: -1, valid: true},
+		{name: "current step", offset: 0, valid: true},
+		{name: "one step ahead", offset: 1, valid: true},
+		{name: "two steps ahead", offset: 2, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, current+tt.offset)
+			require.NoError(t, err)
+
+			step, ok := ValidateTOTP(rfc6238Secret, code, now)
+			assert.Equal(t, tt.valid, ok)
+			if tt.valid {
+				assert.Equal(t, current+tt.offset, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP_InvalidCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{name: "wrong code", code: "000000"},
+		{name: "too short", code: "28708"},
+		{name: "too long", code: "94287082"},
+		{name: "empty", code: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+			assert.False(t, ok)
+		})
+	}
+
+	// Surrounding whitespace is ignored.
+	_, ok := ValidateTOTP(rfc6238Secret, " 287082 ", now)
+	assert.True(t, ok)
+}
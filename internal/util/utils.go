@@ -1,14 +1,16 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 )
 
 type ErrorResponse struct {
@@ -24,42 +26,50 @@ type SuccessResultResponse struct {
 	Message string `json:"message"`
 }
 
-func CreateJsonWebToken(userID uuid.UUID) (string, error) {
+const EmailVerificationTokenTTL = time.Hour * 24
+const PasswordResetTokenTTL = time.Hour
 
-	claims := jwt.RegisteredClaims{
-		Issuer:    "go_social",
-		Subject:   userID.String(),
-		Audience:  jwt.ClaimStrings{"go_social_user"},
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		NotBefore: jwt.NewNumericDate(time.Now()),
+// UsernameChangeCooldown is how long a user has to wait between username
+// changes.
+const UsernameChangeCooldown = time.Hour * 24 * 14
 
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret := os.Getenv("JWT_SECRET")
-	tokenString, err := token.SignedString([]byte(secret))
+// UsernameGracePeriod is how long an old username keeps pointing to the
+// account and is reserved from reuse.
+const UsernameGracePeriod = time.Hour * 24 * 30
 
-	if err != nil {
-		return "", err
-	}
+// DefaultFollowPathDepth and MaxFollowPathDepth bound the number of follows
+// searched when looking for a follow path between two users.
+const DefaultFollowPathDepth = 3
+const MaxFollowPathDepth = 4
 
-	return tokenString, nil
-}
+// DataExportRetention is how long a finished data export can be downloaded.
+const DataExportRetention = time.Hour * 24 * 7
 
-func ParseJWT(token string) (*jwt.RegisteredClaims, error) {
-	claims := &jwt.RegisteredClaims{}
-	secret := os.Getenv("JWT_SECRET")
+// DefaultTrendingWindow and MaxTrendingWindow bound the time window trending
+// hashtags are computed over.
+const DefaultTrendingWindow = time.Hour * 24
+const MaxTrendingWindow = time.Hour * 24 * 7
 
-	jwtToken, err := jwt.ParseWithClaims(token, claims, func(jwtToken *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	if !jwtToken.Valid {
-		return nil, jwt.ErrSignatureInvalid
+// NormalizeEmail returns email the way it is stored and looked up: without
+// surrounding whitespace and in lower case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// GenerateRandomToken returns a URL safe random string suitable for opaque
+// tokens such as refresh tokens.
+func GenerateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return claims, nil
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken hashes an opaque token so that only the digest is stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func HandleBindError(c *gin.Context, err error) {
@@ -0,0 +1,13 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "user@example.com", NormalizeEmail("user@example.com"))
+	assert.Equal(t, "user@example.com", NormalizeEmail("User@Example.COM"))
+	assert.Equal(t, "user@example.com", NormalizeEmail("  user@example.com\n"))
+}
@@ -0,0 +1,107 @@
+// Package worker contains the background jobs that run next to the API.
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/blob"
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
+)
+
+const (
+	// PurgePolicyAnonymize keeps the content of purged accounts under an
+	// anonymous user so the threads they took part in stay intact.
+	PurgePolicyAnonymize = "anonymize"
+	// PurgePolicyDelete deletes purged accounts together with everything
+	// they created.
+	PurgePolicyDelete = "delete"
+)
+
+const purgeBatchSize = 100
+
+// AccountPurger purges accounts that were deactivated longer than
+// GracePeriod ago according to Policy.
+type AccountPurger struct {
+	Storage     *database.Storage
+	Blob        blob.Store
+	GracePeriod time.Duration
+	Policy      string
+	Interval    time.Duration
+}
+
+func NewAccountPurger(storage *database.Storage, blobStore blob.Store, gracePeriod time.Duration, policy string) *AccountPurger {
+	return &AccountPurger{
+		Storage:     storage,
+		Blob:        blobStore,
+		GracePeriod: gracePeriod,
+		Policy:      policy,
+		Interval:    time.Hour,
+	}
+}
+
+// Run purges accounts every Interval until ctx is cancelled.
+func (p *AccountPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Purge(ctx); err != nil {
+			log.Printf("error purging deactivated accounts: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge purges every account whose grace period has passed.
+func (p *AccountPurger) Purge(ctx context.Context) error {
+	for {
+		deactivatedBefore := time.Now().Add(-p.GracePeriod)
+		users, err := p.Storage.UserStore.GetDeactivatedUsers(deactivatedBefore, purgeBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := p.purgeUser(ctx, user, deactivatedBefore); err != nil {
+				return err
+			}
+		}
+		if len(users) < purgeBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// purgeUser purges a user unless they logged in and reactivated their
+// account after it was listed for purging.
+func (p *AccountPurger) purgeUser(ctx context.Context, user model.User, deactivatedBefore time.Time) error {
+	var purged bool
+	var err error
+	if p.Policy == PurgePolicyDelete {
+		purged, err = p.Storage.UserStore.DeleteDeactivatedUser(user.ID, deactivatedBefore)
+	} else {
+		purged, err = p.Storage.UserStore.AnonymizeUser(user.ID, deactivatedBefore)
+	}
+	if err != nil {
+		return err
+	}
+	if !purged {
+		log.Printf("skipped purging account %s, it was reactivated", user.ID)
+		return nil
+	}
+
+	if user.Avatar != nil {
+		if key, ok := p.Blob.Key(*user.Avatar); ok {
+			if err := p.Blob.Delete(ctx, key); err != nil {
+				log.Printf("error deleting avatar %s: %v", key, err)
+			}
+		}
+	}
+	log.Printf("purged account %s with policy %s", user.ID, p.Policy)
+	return nil
+}
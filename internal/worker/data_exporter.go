@@ -0,0 +1,347 @@
+package worker
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/blob"
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+	"github.com/google/uuid"
+)
+
+// exportStaleAfter is how long an export can run before another worker takes
+// it over.
+const exportStaleAfter = time.Minute * 30
+
+const exportPageSize = 100
+
+// DataExporter builds the archives of requested data exports and deletes
+// them when they expire. Archives are kept in Blob, which must not be served
+// publicly.
+type DataExporter struct {
+	Storage  *database.Storage
+	Blob     blob.Store
+	Interval time.Duration
+}
+
+func NewDataExporter(storage *database.Storage, blobStore blob.Store) *DataExporter {
+	return &DataExporter{
+		Storage:  storage,
+		Blob:     blobStore,
+		Interval: time.Second * 10,
+	}
+}
+
+type exportProfile struct {
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	LastName         string     `json:"last_name"`
+	Username         string     `json:"username"`
+	Email            string     `json:"email"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	Avatar           *string    `json:"avatar"`
+	DisplayName      string     `json:"display_name"`
+	Bio              string     `json:"bio"`
+	Website          string     `json:"website"`
+	Location         string     `json:"location"`
+	IsPrivate        bool       `json:"is_private"`
+	Role             string     `json:"role"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type exportPost struct {
+	ID        uuid.UUID `json:"id"`
+	Content   string    `json:"content"`
+	Image     *string   `json:"image"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+}
+
+type exportDraft struct {
+	ID        uuid.UUID  `json:"id"`
+	Content   string     `json:"content"`
+	Image     *string    `json:"image"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	CreatedAt string     `json:"created_at"`
+	UpdatedAt string     `json:"updated_at"`
+}
+
+type exportComment struct {
+	ID        uuid.UUID `json:"id"`
+	PostID    uuid.UUID `json:"post_id"`
+	Content   string    `json:"content"`
+	Image     *string   `json:"image"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+}
+
+type exportReply struct {
+	ID        uuid.UUID `json:"id"`
+	CommentID uuid.UUID `json:"comment_id"`
+	Message   string    `json:"message"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+}
+
+type exportLikes struct {
+	Posts    []model.PostLike    `json:"posts"`
+	Comments []model.CommentLike `json:"comments"`
+}
+
+type exportFollows struct {
+	Following []model.Follow `json:"following"`
+	Followers []model.Follow `json:"followers"`
+}
+
+type exportSession struct {
+	ID         uuid.UUID  `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Run processes exports every Interval until ctx is cancelled.
+func (e *DataExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+	for {
+		if err := e.ExpireExports(ctx); err != nil {
+			log.Printf("error expiring data exports: %v", err)
+		}
+		if err := e.ProcessExports(ctx); err != nil {
+			log.Printf("error processing data exports: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessExports builds the archives of all pending exports.
+func (e *DataExporter) ProcessExports(ctx context.Context) error {
+	for ctx.Err() == nil {
+		export, err := e.Storage.DataExportStore.ClaimDataExport(time.Now().Add(-exportStaleAfter))
+		if err != nil {
+			return err
+		}
+		if export == nil {
+			return nil
+		}
+
+		key, err := e.buildArchive(ctx, export)
+		if err != nil {
+			log.Printf("error building data export %s: %v", export.ID, err)
+			if err := e.Storage.DataExportStore.FailDataExport(export.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.Storage.DataExportStore.CompleteDataExport(export.ID, key, time.Now().Add(util.DataExportRetention)); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// ExpireExports deletes the archives of expired exports and of exports whose
+// user was deleted.
+func (e *DataExporter) ExpireExports(ctx context.Context) error {
+	exports, err := e.Storage.DataExportStore.GetExpiredDataExports(exportPageSize)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if export.BlobKey != nil {
+			if err := e.Blob.Delete(ctx, *export.BlobKey); err != nil {
+				return err
+			}
+		}
+		if err := e.Storage.DataExportStore.ExpireDataExport(export.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildArchive writes a zip with a JSON file for every kind of data of the
+// user and returns its key.
+func (e *DataExporter) buildArchive(ctx context.Context, export *model.DataExport) (string, error) {
+	files, err := e.collect(export.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return "", err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return "", err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("exports/%s/%s.zip", export.UserID, export.ID)
+	if err := e.Blob.Put(ctx, key, &buf); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+type exportFile struct {
+	name string
+	data any
+}
+
+func (e *DataExporter) collect(userID uuid.UUID) ([]exportFile, error) {
+	storage := e.Storage
+
+	user, err := storage.UserStore.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+	profile := exportProfile{
+		ID:               user.ID,
+		Name:             user.Name,
+		LastName:         user.LastName,
+		Username:         user.Username,
+		Email:            user.Email,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		Avatar:           user.Avatar,
+		DisplayName:      user.DisplayName,
+		Bio:              user.Bio,
+		Website:          user.Website,
+		Location:         user.Location,
+		IsPrivate:        user.IsPrivate,
+		Role:             user.Role,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+	}
+
+	posts := []exportPost{}
+	for offset := 0; ; offset += exportPageSize {
+		page, err := storage.PostStore.GetPostsByUserID(userID, database.Pagination{Limit: exportPageSize, Offset: offset}, database.Search{})
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range page {
+			posts = append(posts, exportPost{ID: post.ID, Content: post.Content, Image: post.Image, CreatedAt: post.CreatedAt, UpdatedAt: post.UpdatedAt})
+		}
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	drafts := []exportDraft{}
+	for offset := 0; ; offset += exportPageSize {
+		page, err := storage.PostStore.GetDraftsByUserID(userID, database.Pagination{Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range page {
+			drafts = append(drafts, exportDraft{ID: post.ID, Content: post.Content, Image: post.Image, Status: post.Status, PublishAt: post.PublishAt, CreatedAt: post.CreatedAt, UpdatedAt: post.UpdatedAt})
+		}
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	userComments, err := storage.CommentStore.GetCommentsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	comments := make([]exportComment, 0, len(userComments))
+	for _, comment := range userComments {
+		comments = append(comments, exportComment{ID: comment.ID, PostID: comment.PostID, Content: comment.Content, Image: comment.Image, CreatedAt: comment.CreatedAt, UpdatedAt: comment.UpdatedAt})
+	}
+
+	userReplies, err := storage.ReplyStore.GetRepliesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	replies := make([]exportReply, 0, len(userReplies))
+	for _, reply := range userReplies {
+		replies = append(replies, exportReply{ID: reply.ID, CommentID: reply.CommentID, Message: reply.Message, CreatedAt: reply.CreatedAt, UpdatedAt: reply.UpdatedAt})
+	}
+
+	likes := exportLikes{}
+	if likes.Posts, err = storage.LikeStore.GetPostLikesByUserID(userID); err != nil {
+		return nil, err
+	}
+	if likes.Comments, err = storage.LikeStore.GetCommentLikesByUserID(userID); err != nil {
+		return nil, err
+	}
+
+	reposts, err := storage.RepostStore.GetRepostsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	follows := exportFollows{Following: []model.Follow{}, Followers: []model.Follow{}}
+	for offset := 0; ; offset += exportPageSize {
+		page, err := storage.FollowStore.GetFollowingByUserID(userID, userID, database.Pagination{Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		follows.Following = append(follows.Following, page...)
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+	for offset := 0; ; offset += exportPageSize {
+		page, err := storage.FollowStore.GetFollowerByUserID(userID, userID, database.Pagination{Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		follows.Followers = append(follows.Followers, page...)
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	userSessions, err := storage.SessionStore.GetSessionsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]exportSession, 0, len(userSessions))
+	for _, session := range userSessions {
+		sessions = append(sessions, exportSession{ID: session.ID, UserAgent: session.UserAgent, IPAddress: session.IPAddress, CreatedAt: session.CreatedAt, LastSeenAt: session.LastSeenAt, RevokedAt: session.RevokedAt})
+	}
+
+	return []exportFile{
+		{name: "profile.json", data: profile},
+		{name: "posts.json", data: posts},
+		{name: "drafts.json", data: drafts},
+		{name: "comments.json", data: comments},
+		{name: "replies.json", data: replies},
+		{name: "likes.json", data: likes},
+		{name: "reposts.json", data: reposts},
+		{name: "follows.json", data: follows},
+		{name: "sessions.json", data: sessions},
+	}, nil
+}
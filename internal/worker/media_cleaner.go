@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/blob"
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/model"
+)
+
+const mediaCleanupBatchSize = 100
+
+// MediaCleaner deletes uploaded media that isn't attached to a post or a
+// comment: uploads that weren't attached within UnattachedTTL and media whose
+// post or comment was deleted.
+type MediaCleaner struct {
+	Storage       *database.Storage
+	Blob          blob.Store
+	UnattachedTTL time.Duration
+	Interval      time.Duration
+}
+
+func NewMediaCleaner(storage *database.Storage, blobStore blob.Store) *MediaCleaner {
+	return &MediaCleaner{
+		Storage:       storage,
+		Blob:          blobStore,
+		UnattachedTTL: time.Hour * 24,
+		Interval:      time.Hour,
+	}
+}
+
+// Run cleans up media every Interval until ctx is cancelled.
+func (m *MediaCleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Clean(ctx); err != nil {
+			log.Printf("error cleaning up media: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Clean deletes every orphaned media.
+func (m *MediaCleaner) Clean(ctx context.Context) error {
+	for {
+		media, err := m.Storage.MediaStore.GetOrphanedMedia(time.Now().Add(-m.UnattachedTTL), mediaCleanupBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range media {
+			if err := m.deleteMedia(ctx, item); err != nil {
+				return err
+			}
+		}
+		if len(media) < mediaCleanupBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// deleteMedia deletes the blobs of media before its row, so a failure leaves
+// the row to be retried on the next run. Media attached since it was listed
+// is left alone.
+func (m *MediaCleaner) deleteMedia(ctx context.Context, media model.Media) error {
+	_, err := m.Storage.MediaStore.DeleteOrphanedMedia(media.ID, func(locked model.Media) error {
+		for _, key := range []string{locked.BlobKey, locked.ThumbnailKey} {
+			if err := m.Blob.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/database"
+	"github.com/fatihesergg/go_social/internal/mailer"
+	"github.com/fatihesergg/go_social/internal/model"
+	"github.com/fatihesergg/go_social/internal/util"
+)
+
+const passwordResetQueueSize = 100
+
+// PasswordResetMailer emails password reset tokens in the background, so
+// requesting a reset takes as long whether the email belongs to an account or
+// not. Requests wait in a bounded queue and are sent one at a time, each
+// within Timeout. Requests that don't fit in the queue are dropped.
+type PasswordResetMailer struct {
+	Storage *database.Storage
+	Mailer  mailer.Mailer
+	Timeout time.Duration
+	queue   chan string
+}
+
+func NewPasswordResetMailer(storage *database.Storage, mail mailer.Mailer) *PasswordResetMailer {
+	return &PasswordResetMailer{
+		Storage: storage,
+		Mailer:  mail,
+		Timeout: time.Second * 30,
+		queue:   make(chan string, passwordResetQueueSize),
+	}
+}
+
+// Enqueue queues a password reset email for the account with the given
+// email, if there is one. It returns false if the queue is full.
+func (m *PasswordResetMailer) Enqueue(email string) bool {
+	select {
+	case m.queue <- email:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run sends queued password reset emails until ctx is cancelled.
+func (m *PasswordResetMailer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case email := <-m.queue:
+			if err := m.send(ctx, email); err != nil {
+				log.Printf("error sending password reset email: %v", err)
+			}
+		}
+	}
+}
+
+// send replaces any pending password reset token of the account with email
+// with a new one and emails it.
+func (m *PasswordResetMailer) send(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	user, err := m.Storage.UserStore.GetUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, err := util.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+
+	if err := m.Storage.UserTokenStore.DeleteUserTokens(user.ID, model.UserTokenPurposePasswordReset); err != nil {
+		return err
+	}
+	err = m.Storage.UserTokenStore.CreateUserToken(&model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.UserTokenPurposePasswordReset,
+		TokenHash: util.HashToken(token),
+		ExpiresAt: time.Now().Add(util.PasswordResetTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.Mailer.Send(ctx, mailer.NewPasswordResetMessage(user.Email, user.Name, token))
+}
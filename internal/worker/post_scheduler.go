@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fatihesergg/go_social/internal/database"
+)
+
+const publishBatchSize = 100
+
+// PostScheduler publishes scheduled posts when their publish time comes.
+// Several servers can run it at the same time, each post is published by
+// exactly one of them.
+type PostScheduler struct {
+	Storage  *database.Storage
+	Interval time.Duration
+}
+
+func NewPostScheduler(storage *database.Storage) *PostScheduler {
+	return &PostScheduler{
+		Storage:  storage,
+		Interval: time.Second * 10,
+	}
+}
+
+// Run publishes due posts every Interval until ctx is cancelled. Posts that
+// came due while the server was down are published on the first run.
+func (s *PostScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Publish(ctx); err != nil {
+			log.Printf("error publishing scheduled posts: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publish publishes every post whose publish time has passed.
+func (s *PostScheduler) Publish(ctx context.Context) error {
+	for {
+		ids, err := s.Storage.PostStore.PublishDuePosts(time.Now(), publishBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			log.Printf("published scheduled post %s", id)
+		}
+		if len(ids) < publishBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}